@@ -3,9 +3,13 @@ package account
 import (
 	"goplugins/core/account/handler"
 	"goplugins/core/account/models"
+	"goplugins/core/account/seed"
 	"goplugins/core/account/store"
+	"goplugins/core/framework"
 	"goplugins/core/framework/database"
 	"goplugins/core/routing"
+	"goplugins/core/routing/middleware"
+	"time"
 )
 
 // service holds the Service
@@ -27,6 +31,11 @@ func NewService(
 		models.User{},
 	)
 
+	framework.RegisterSeeder("account.permissions", seed.Permissions)
+	framework.RegisterSeeder("account.superuser", seed.Superuser(store.New))
+
 	mux.GET("/users", handler.ListAccounts(userStore))
-	mux.POST("/user", handler.CreateHandler(userStore))
+	// Public sign up, 10 accounts per IP and hour.
+	signUpLimit := middleware.NewRateLimiterMemoryStore(10, time.Hour)
+	mux.POST("/user", handler.CreateHandler(userStore), middleware.RateLimiter(signUpLimit))
 }
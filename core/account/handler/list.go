@@ -2,12 +2,23 @@ package handler
 
 import (
 	"goplugins/core/account/models"
+	"goplugins/core/framework"
 	"goplugins/core/routing"
+	"net/http"
 )
 
 // ListAccounts lists all users
 func ListAccounts(userStore models.UserStore) routing.HandlerFunc {
 	return func(c routing.Context) error {
-		return c.String(200, "listUsers")
+		params, err := framework.ParseListParams(c)
+		if err != nil {
+			return err
+		}
+
+		page, err := userStore.List(params)
+		if err != nil {
+			return err
+		}
+		return c.JSON(http.StatusOK, page)
 	}
 }
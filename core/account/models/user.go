@@ -14,6 +14,7 @@ type (
 	// User reflects a user
 	User struct {
 		framework.Model
+		framework.SoftDelete
 		ConfirmedAt        *time.Time    `json:"confirmedAt,omitempty" gorm:"column:confirmed_at"`
 		ConfirmationToken  string        `json:"-" gorm:"column:confirmation_token"`
 		ConfirmationSentAt *time.Time    `json:"confirmationSentAt,omitempty" gorm:"column:confirmation_sent_at"`
@@ -44,6 +45,7 @@ type (
 		FindByConfirmationToken(token string) (*User, error)
 		FindByEmail(email string) (*User, error)
 		FindByRecoveryToken(token string) (*User, error)
+		List(params framework.ListParams) (*framework.Page, error)
 	}
 )
 
@@ -54,9 +56,6 @@ func (User) TableName() string {
 
 // BeforeSave gets executed before the model is saved.
 func (u *User) BeforeSave(tx *gorm.DB) error {
-	if u.ID == uuid.Nil {
-		u.ID = uuid.New()
-	}
 	if u.ConfirmedAt != nil && u.ConfirmedAt.IsZero() {
 		u.ConfirmedAt = nil
 	}
@@ -0,0 +1,86 @@
+package seed
+
+import (
+	"errors"
+	"fmt"
+	"goplugins/core/account/models"
+	"goplugins/core/framework"
+	"goplugins/core/framework/crypto"
+	"goplugins/core/framework/database"
+	"os"
+
+	"github.com/kelseyhightower/envconfig"
+	"github.com/sirupsen/logrus"
+)
+
+// defaultPermissions are the groups and permissions every installation starts with.
+const defaultPermissions = `
+- model: permission
+  key: user.list
+  fields: {name: Can list users, contentType: user, codeName: list_user}
+- model: permission
+  key: user.create
+  fields: {name: Can create users, contentType: user, codeName: create_user}
+- model: permission
+  key: user.update
+  fields: {name: Can update users, contentType: user, codeName: update_user}
+- model: permission
+  key: user.delete
+  fields: {name: Can delete users, contentType: user, codeName: delete_user}
+- model: group
+  key: administrators
+  fields:
+    name: Administrators
+    permissions: ["@user.list", "@user.create", "@user.update", "@user.delete"]
+- model: group
+  key: staff
+  fields:
+    name: Staff
+    permissions: ["@user.list"]
+`
+
+// superuser holds the credentials of the superuser created by Superuser.
+type superuser struct {
+	Email    string `envconfig:"SUPERUSER_EMAIL" default:"admin@localhost"`
+	Password string `envconfig:"SUPERUSER_PASSWORD"`
+	Env      string `envconfig:"APP_ENV" default:"production"`
+}
+
+// Permissions creates the default groups and permissions.
+func Permissions(db *database.DB) error {
+	return framework.NewFixtures(db).
+		Register("group", &models.Group{}).
+		Register("permission", &models.Permission{}).
+		Load([]byte(defaultPermissions))
+}
+
+// Superuser returns a seeder creating the superuser configured by
+// SUPERUSER_EMAIL and SUPERUSER_PASSWORD using the store returned by
+// newStore for the seeder transaction. SUPERUSER_PASSWORD is required unless
+// APP_ENV is "development", where a random password is generated and printed
+// once to stdout, never logged.
+func Superuser(newStore func(db *database.DB) models.UserStore) framework.SeedFunc {
+	return func(db *database.DB) error {
+		cfg := superuser{}
+		if err := envconfig.Process("", &cfg); err != nil {
+			return err
+		}
+		if cfg.Password == "" {
+			if cfg.Env != "development" {
+				return errors.New("account: SUPERUSER_PASSWORD is required")
+			}
+			cfg.Password = crypto.SecureToken()
+			logrus.WithField("email", cfg.Email).
+				Warnln("account: generated superuser password, see stdout")
+			fmt.Fprintf(os.Stdout, "Superuser %s, password: %s\n", cfg.Email, cfg.Password)
+		}
+
+		user := &models.User{
+			Email:       cfg.Email,
+			IsActive:    true,
+			IsStaff:     true,
+			IsSuperUser: true,
+		}
+		return newStore(db).Create(user, cfg.Password)
+	}
+}
@@ -3,6 +3,8 @@ package store
 import (
 	"goplugins/core/account/errs"
 	"goplugins/core/account/models"
+	"goplugins/core/framework"
+	"goplugins/core/framework/crypto"
 	"goplugins/core/framework/database"
 
 	"errors"
@@ -12,14 +14,27 @@ import (
 
 // New returns a new UserStore.
 func New(db *database.DB) models.UserStore {
-	return &userStore{db}
+	return &userStore{
+		db: db,
+		repo: framework.NewRepository(db, &models.User{},
+			framework.WithSortable("email", "firstName", "lastName", "createdAt", "lastSignInAt"),
+			framework.WithFilterable("email", "isActive", "isStaff", "isSuperUser", "createdAt"),
+		),
+	}
 }
 
 type userStore struct {
-	db *database.DB
+	db   *database.DB
+	repo *framework.Repository
 }
 
+// Create stores the given user with the hash of password
 func (u *userStore) Create(user *models.User, password string) error {
+	hash, err := crypto.HashPassword(password)
+	if err != nil {
+		return err
+	}
+	user.Password = hash
 	return u.db.Create(user).Error
 }
 
@@ -55,3 +70,9 @@ func (u *userStore) FindByEmail(email string) (*models.User, error) {
 func (u *userStore) FindByRecoveryToken(token string) (*models.User, error) {
 	return u.findUser("recovery_token = ?", token)
 }
+
+// List returns a page of users
+func (u *userStore) List(params framework.ListParams) (*framework.Page, error) {
+	users := []*models.User{}
+	return u.repo.List(params, &users)
+}
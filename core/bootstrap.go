@@ -5,6 +5,7 @@ import (
 	"goplugins/core/account"
 	"goplugins/core/framework"
 	"goplugins/core/framework/config"
+	"strings"
 
 	"github.com/joho/godotenv"
 	"github.com/sirupsen/logrus"
@@ -12,8 +13,9 @@ import (
 
 // Bootstrap starts our framework
 func Bootstrap() {
-	var envfile string
+	var envfile, seed string
 	flag.StringVar(&envfile, "env-file", ".env", "Read in a file of environment variables")
+	flag.StringVar(&seed, "seed", "", "Run the given comma separated seeders, or \"all\", and exit")
 	flag.Parse()
 	godotenv.Load(envfile)
 
@@ -27,5 +29,17 @@ func Bootstrap() {
 
 	fw.AddService(account.NewService)
 
+	if seed != "" {
+		var names []string
+		if seed != "all" {
+			names = strings.Split(seed, ",")
+		}
+		if err := fw.Seed(names...); err != nil {
+			logger := logrus.WithError(err)
+			logger.Fatalln("main: seeding failed")
+		}
+		return
+	}
+
 	fw.Start()
 }
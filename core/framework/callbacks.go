@@ -0,0 +1,145 @@
+package framework
+
+import (
+	"errors"
+	"goplugins/core/framework/database"
+	"reflect"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	versionField   = "Version"
+	createdByField = "CreatedBy"
+	updatedByField = "UpdatedBy"
+
+	// versionCheckKey marks a statement whose update is guarded by a version.
+	versionCheckKey = "framework:version_check"
+)
+
+var (
+	versionerType = reflect.TypeOf((*versioner)(nil)).Elem()
+	auditorType   = reflect.TypeOf((*auditor)(nil)).Elem()
+)
+
+// callbacks is the gorm plugin maintaining Versioned and Audited models.
+type callbacks struct{}
+
+// RegisterCallbacks installs the callbacks maintaining the Versioned and
+// Audited columns on db. New calls it for the framework database; it is safe
+// to call it more than once.
+func RegisterCallbacks(db *database.DB) error {
+	if err := db.Use(callbacks{}); err != nil && !errors.Is(err, gorm.ErrRegistered) {
+		return err
+	}
+	return nil
+}
+
+func (callbacks) Name() string {
+	return "framework:callbacks"
+}
+
+func (callbacks) Initialize(db *gorm.DB) error {
+	create := db.Callback().Create()
+	if err := create.Before("gorm:create").Register("framework:before_create", beforeCreate); err != nil {
+		return err
+	}
+	update := db.Callback().Update()
+	if err := update.Before("gorm:update").Register("framework:before_update", beforeUpdate); err != nil {
+		return err
+	}
+	return update.After("gorm:update").Register("framework:after_update", afterUpdate)
+}
+
+func implements(stmt *gorm.Statement, t reflect.Type) bool {
+	return stmt.Schema != nil && reflect.PtrTo(stmt.Schema.ModelType).Implements(t)
+}
+
+func beforeCreate(db *gorm.DB) {
+	stmt := db.Statement
+	if db.Error != nil {
+		return
+	}
+
+	if implements(stmt, versionerType) {
+		setEach(stmt, versionField, func(current interface{}, zero bool) interface{} {
+			if zero {
+				return uint(1)
+			}
+			return current
+		})
+	}
+
+	if implements(stmt, auditorType) {
+		if id, ok := UserFromContext(stmt.Context); ok {
+			user := func(current interface{}, zero bool) interface{} {
+				if zero {
+					return &id
+				}
+				return current
+			}
+			setEach(stmt, createdByField, user)
+			setEach(stmt, updatedByField, user)
+		}
+	}
+}
+
+func beforeUpdate(db *gorm.DB) {
+	stmt := db.Statement
+	if db.Error != nil || stmt.UpdatingColumn {
+		return
+	}
+
+	if implements(stmt, auditorType) {
+		if id, ok := UserFromContext(stmt.Context); ok {
+			stmt.SetColumn(updatedByField, &id)
+		}
+	}
+
+	if implements(stmt, versionerType) && stmt.ReflectValue.Kind() == reflect.Struct {
+		field := stmt.Schema.LookUpField(versionField)
+		current, zero := field.ValueOf(stmt.ReflectValue)
+		if zero {
+			return
+		}
+		version := current.(uint)
+		stmt.AddClause(clause.Where{Exprs: []clause.Expression{
+			clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: field.DBName}, Value: version},
+		}})
+		stmt.SetColumn(versionField, version+1)
+		db.InstanceSet(versionCheckKey, version)
+	}
+}
+
+func afterUpdate(db *gorm.DB) {
+	version, ok := db.InstanceGet(versionCheckKey)
+	if !ok || db.Error != nil || db.DryRun || db.RowsAffected > 0 {
+		return
+	}
+	// Nothing has been written, restore the version the caller loaded.
+	db.Statement.SetColumn(versionField, version)
+	db.AddError(ErrConflict)
+}
+
+// setEach sets the named field of every record of the statement to the value
+// returned by fn for its current value.
+func setEach(stmt *gorm.Statement, name string, fn func(current interface{}, zero bool) interface{}) {
+	field := stmt.Schema.LookUpField(name)
+	if field == nil {
+		return
+	}
+	set := func(rv reflect.Value) {
+		current, zero := field.ValueOf(rv)
+		stmt.AddError(field.Set(rv, fn(current, zero)))
+	}
+
+	switch stmt.ReflectValue.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < stmt.ReflectValue.Len(); i++ {
+			set(reflect.Indirect(stmt.ReflectValue.Index(i)))
+		}
+	case reflect.Struct:
+		set(stmt.ReflectValue)
+	}
+}
@@ -0,0 +1,115 @@
+package framework
+
+import (
+	"context"
+	"errors"
+	"goplugins/core/framework/database"
+	"goplugins/core/routing"
+	"net/http"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type document struct {
+	Model
+	SoftDelete
+	Versioned
+	Audited
+	Title string `json:"title"`
+}
+
+func newDocumentRepository(t *testing.T) *Repository {
+	db := newTestDB(t)
+	require.NoError(t, db.AutoMigrate(&document{}))
+	return NewRepository(db, &document{})
+}
+
+func TestVersioned(t *testing.T) {
+	repo := newDocumentRepository(t)
+
+	doc := &document{Title: "draft"}
+	require.NoError(t, repo.Create(doc))
+	assert.Equal(t, uint(1), doc.Version)
+
+	stale := &document{}
+	require.NoError(t, repo.Find(doc.ID, stale))
+
+	doc.Title = "first"
+	require.NoError(t, repo.Update(doc))
+	assert.Equal(t, uint(2), doc.Version)
+
+	stale.Title = "second"
+	err := repo.Update(stale)
+	assert.True(t, errors.Is(err, ErrConflict))
+	he, ok := err.(*routing.HTTPError)
+	require.True(t, ok)
+	assert.Equal(t, http.StatusConflict, he.Code)
+	assert.Equal(t, uint(1), stale.Version)
+
+	found := &document{}
+	require.NoError(t, repo.Find(doc.ID, found))
+	assert.Equal(t, "first", found.Title)
+	assert.Equal(t, uint(2), found.Version)
+
+	// updating through a map is guarded as well
+	require.NoError(t, repo.DB().Where("id = ?", found.ID).Model(found).Updates(map[string]interface{}{"title": "third"}).Error)
+	assert.Equal(t, uint(3), found.Version)
+}
+
+func TestSoftDelete(t *testing.T) {
+	repo := newDocumentRepository(t)
+
+	doc := &document{Title: "deleted"}
+	require.NoError(t, repo.Create(doc))
+	require.NoError(t, repo.Delete(doc))
+
+	assert.True(t, errors.Is(repo.Find(doc.ID, &document{}), database.ErrRecordNotFound))
+	var items []*document
+	page, err := repo.List(ListParams{}, &items)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), page.Total)
+
+	require.NoError(t, repo.Restore(doc.ID))
+	found := &document{}
+	require.NoError(t, repo.Find(doc.ID, found))
+	assert.False(t, found.IsDeleted())
+
+	require.NoError(t, repo.ForceDelete(found))
+	assert.True(t, errors.Is(repo.Restore(doc.ID), database.ErrRecordNotFound))
+}
+
+func TestAudited(t *testing.T) {
+	repo := newDocumentRepository(t)
+	creator, editor := uuid.New(), uuid.New()
+
+	doc := &document{Title: "audited"}
+	require.NoError(t, repo.WithContext(WithUser(context.Background(), creator)).Create(doc))
+	require.NotNil(t, doc.CreatedBy)
+	assert.Equal(t, creator, *doc.CreatedBy)
+	assert.Equal(t, creator, *doc.UpdatedBy)
+
+	require.NoError(t, repo.WithContext(WithUser(context.Background(), editor)).Update(doc))
+	found := &document{}
+	require.NoError(t, repo.Find(doc.ID, found))
+	assert.Equal(t, creator, *found.CreatedBy)
+	assert.Equal(t, editor, *found.UpdatedBy)
+
+	// without a user the columns are left alone
+	require.NoError(t, repo.Update(found))
+	require.NoError(t, repo.Find(doc.ID, found))
+	assert.Equal(t, editor, *found.UpdatedBy)
+}
+
+func TestSetUser(t *testing.T) {
+	c := newContext(http.MethodGet, "/")
+	id := uuid.New()
+	SetUser(c, id)
+
+	assert.Equal(t, id, c.Get(ContextKeyUser))
+	user, ok := UserFromContext(c.Request().Context())
+	assert.True(t, ok)
+	assert.Equal(t, id, user)
+}
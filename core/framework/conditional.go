@@ -0,0 +1,45 @@
+package framework
+
+import (
+	"goplugins/core/routing"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Cacheable is implemented by all models embedding Model.
+type Cacheable interface {
+	GetID() uuid.UUID
+	GetUpdatedAt() time.Time
+}
+
+// ETag returns the entity tag of record, derived from its ID, UpdatedAt and
+// Version if the model is Versioned. The tag is weak, as it identifies the
+// state of the record rather than the bytes of a representation.
+func ETag(record Cacheable) string {
+	tag := record.GetID().String() + "-" + strconv.FormatInt(record.GetUpdatedAt().UnixNano(), 36)
+	if v, ok := record.(versioner); ok {
+		tag += "-" + strconv.FormatUint(uint64(v.GetVersion()), 10)
+	}
+	return `W/"` + tag + `"`
+}
+
+// NotModified sets the Last-Modified and ETag headers of the response from
+// record and reports whether the client's copy is still fresh.
+//
+//	if framework.NotModified(c, &product) {
+//		return c.NoContent(http.StatusNotModified)
+//	}
+//	return c.JSON(http.StatusOK, product)
+func NotModified(c routing.Context, record Cacheable) bool {
+	return c.NotModified(record.GetUpdatedAt(), ETag(record))
+}
+
+// CheckPreconditions returns routing.ErrPreconditionFailed if the request
+// has an If-Match or If-Unmodified-Since header which does not match the
+// current state of record. Call it after loading and before updating or
+// deleting a record to prevent lost updates.
+func CheckPreconditions(c routing.Context, record Cacheable) error {
+	return c.CheckPreconditions(record.GetUpdatedAt(), ETag(record))
+}
@@ -0,0 +1,53 @@
+package framework
+
+import (
+	"goplugins/core/routing"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestETag(t *testing.T) {
+	updated := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+	w := &widget{Model: Model{ID: uuid.New(), UpdatedAt: updated}}
+	etag := ETag(w)
+	assert.Regexp(t, `^W/"[0-9a-f-]{36}-\w+"$`, etag)
+
+	w.UpdatedAt = updated.Add(time.Nanosecond)
+	assert.NotEqual(t, etag, ETag(w))
+
+	d := &document{Model: w.Model, Versioned: Versioned{Version: 2}}
+	assert.Equal(t, ETag(w)[:len(ETag(w))-1]+`-2"`, ETag(d))
+}
+
+func TestNotModified(t *testing.T) {
+	m := routing.New()
+	w := &widget{Model: Model{ID: uuid.New(), UpdatedAt: time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)}}
+
+	rec := httptest.NewRecorder()
+	c := m.NewContext(httptest.NewRequest(http.MethodGet, "/", nil), rec)
+	assert.False(t, NotModified(c, w))
+	assert.Equal(t, ETag(w), rec.Header().Get(routing.HeaderETag))
+	assert.Equal(t, "Wed, 01 Jan 2020 12:00:00 GMT", rec.Header().Get(routing.HeaderLastModified))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(routing.HeaderIfNoneMatch, ETag(w))
+	c = m.NewContext(req, httptest.NewRecorder())
+	assert.True(t, NotModified(c, w))
+}
+
+func TestCheckPreconditions(t *testing.T) {
+	m := routing.New()
+	w := &widget{Model: Model{ID: uuid.New(), UpdatedAt: time.Now()}}
+
+	req := httptest.NewRequest(http.MethodPut, "/", nil)
+	req.Header.Set(routing.HeaderIfMatch, ETag(w))
+	assert.NoError(t, CheckPreconditions(m.NewContext(req, httptest.NewRecorder()), w))
+
+	w.UpdatedAt = w.UpdatedAt.Add(time.Second)
+	assert.Equal(t, routing.ErrPreconditionFailed, CheckPreconditions(m.NewContext(req, httptest.NewRecorder()), w))
+}
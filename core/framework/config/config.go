@@ -7,6 +7,9 @@ type (
 	Config struct {
 		App      App
 		Database Database
+		Tenancy  Tenancy
+		CORS     CORS
+		OpenAPI  OpenAPI
 	}
 
 	// App the basic Application configuration
@@ -27,6 +30,45 @@ type (
 		Datasource     string `envconfig:"DATABASE_DATASOURCE" default:"core.sqlite"`
 		MaxConnections int    `envconfig:"DATABASE_CONNECTIONS" default:"11"`
 	}
+
+	// Tenancy provides the multi-tenancy configuration. Tenancy is disabled
+	// unless a mode is set.
+	Tenancy struct {
+		// Mode is either "shared" or "schema" (postgres only).
+		Mode string `envconfig:"TENANCY_MODE"`
+		// Source is where the tenant is taken from: "host", "header" or "path".
+		Source string `envconfig:"TENANCY_SOURCE" default:"header"`
+		Header string `envconfig:"TENANCY_HEADER" default:"X-Tenant-ID"`
+		Param  string `envconfig:"TENANCY_PARAM"  default:"tenant"`
+		// Domain is stripped from the host to get the tenant, e.g. "example.com".
+		Domain   string `envconfig:"TENANCY_DOMAIN"`
+		Required bool   `envconfig:"TENANCY_REQUIRED" default:"true"`
+	}
+
+	// CORS provides the Cross-Origin Resource Sharing configuration. CORS is
+	// disabled unless at least one origin is allowed. Lists are comma separated.
+	CORS struct {
+		// AllowOrigins are exact origins, wildcard subdomains like
+		// "https://*.example.com" or "*", which cannot be combined with
+		// AllowCredentials.
+		AllowOrigins []string `envconfig:"CORS_ALLOW_ORIGINS"`
+		// AllowOriginPatterns are regular expressions matching allowed origins.
+		AllowOriginPatterns []string `envconfig:"CORS_ALLOW_ORIGIN_PATTERNS"`
+		AllowMethods        []string `envconfig:"CORS_ALLOW_METHODS"`
+		AllowHeaders        []string `envconfig:"CORS_ALLOW_HEADERS"`
+		ExposeHeaders       []string `envconfig:"CORS_EXPOSE_HEADERS"`
+		AllowCredentials    bool     `envconfig:"CORS_ALLOW_CREDENTIALS" default:"false"`
+		// MaxAge is the number of seconds browsers may cache preflight results.
+		MaxAge int `envconfig:"CORS_MAX_AGE" default:"0"`
+	}
+
+	// OpenAPI provides the configuration of the generated API documentation.
+	// The document is not served unless a path is set.
+	OpenAPI struct {
+		// Path the OpenAPI document is served at, e.g. "/openapi.json".
+		Path    string `envconfig:"OPENAPI_PATH"`
+		Version string `envconfig:"OPENAPI_VERSION" default:"1.0.0"`
+	}
 )
 
 // Environ returns the settings from the environment.
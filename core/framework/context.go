@@ -0,0 +1,51 @@
+package framework
+
+import (
+	"context"
+	"goplugins/core/framework/database"
+	"goplugins/core/routing"
+
+	"github.com/google/uuid"
+)
+
+type contextKey string
+
+const (
+	// ContextKeyUser is the key the ID of the authenticated user is stored
+	// under in the routing.Context.
+	ContextKeyUser = "user"
+	// ContextKeyTenant is the key the ID of the tenant of the request is
+	// stored under in the routing.Context.
+	ContextKeyTenant = "tenant"
+
+	userContextKey contextKey = "user"
+)
+
+// WithUser returns a copy of ctx carrying the ID of the authenticated user.
+func WithUser(ctx context.Context, id uuid.UUID) context.Context {
+	return context.WithValue(ctx, userContextKey, id)
+}
+
+// UserFromContext returns the ID of the authenticated user carried by ctx.
+func UserFromContext(ctx context.Context) (uuid.UUID, bool) {
+	id, ok := ctx.Value(userContextKey).(uuid.UUID)
+	return id, ok && id != uuid.Nil
+}
+
+// SetUser marks id as the authenticated user of the request. It is meant to
+// be called by authentication middleware, so that records written using the
+// request context are audited.
+func SetUser(c routing.Context, id uuid.UUID) {
+	c.Set(ContextKeyUser, id)
+	req := c.Request()
+	c.SetRequest(req.WithContext(WithUser(req.Context(), id)))
+}
+
+// SetTenant marks tenant as the tenant of the request. Statements run with
+// the request context are restricted to the data of the tenant, see
+// database.EnableTenancy.
+func SetTenant(c routing.Context, tenant string) {
+	c.Set(ContextKeyTenant, tenant)
+	req := c.Request()
+	c.SetRequest(req.WithContext(database.WithTenant(req.Context(), tenant)))
+}
@@ -10,3 +10,11 @@ func TestCrypto(t *testing.T) {
 	token := SecureToken()
 	require.NotNil(t, token)
 }
+
+func TestHashPassword(t *testing.T) {
+	hash, err := HashPassword("secret")
+	require.NoError(t, err)
+	require.NotEqual(t, "secret", hash)
+	require.True(t, CheckPassword(hash, "secret"))
+	require.False(t, CheckPassword(hash, "wrong"))
+}
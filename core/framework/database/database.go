@@ -1,6 +1,11 @@
 package database
 
-import "gorm.io/gorm"
+import (
+	"context"
+	"database/sql"
+
+	"gorm.io/gorm"
+)
 
 // Database driver enums.
 const (
@@ -24,3 +29,16 @@ type (
 func (db *DB) Driver() Driver {
 	return db.driver
 }
+
+// WithContext returns a copy of db running all statements with ctx.
+func (db *DB) WithContext(ctx context.Context) *DB {
+	return &DB{db.DB.WithContext(ctx), db.driver}
+}
+
+// Transaction runs fn within a transaction. The transaction is committed if
+// fn returns nil and rolled back otherwise.
+func (db *DB) Transaction(fn func(tx *DB) error, opts ...*sql.TxOptions) error {
+	return db.DB.Transaction(func(tx *gorm.DB) error {
+		return fn(&DB{tx, db.driver})
+	}, opts...)
+}
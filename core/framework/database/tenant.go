@@ -0,0 +1,334 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"regexp"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Tenant isolation modes.
+const (
+	// SharedSchema keeps the records of all tenants in the same tables. The
+	// records of TenantScoped models are told apart by their tenant_id column.
+	SharedSchema TenantMode = iota + 1
+	// SchemaPerTenant keeps the tables of every tenant in a postgres schema
+	// of its own, see TenantSchema. Records of TenantScoped models are
+	// additionally filtered like in SharedSchema mode.
+	SchemaPerTenant
+)
+
+const (
+	tenancyPlugin = "database:tenancy"
+	tenantField   = "TenantID"
+	schemaPrefix  = "tenant_"
+)
+
+type (
+	// TenantMode selects how the data of the tenants is isolated.
+	TenantMode int
+
+	// TenantScoped is implemented by models whose records belong to a
+	// tenant. Their records are stamped with the tenant of the statement
+	// context when created, and all queries, updates and deletes are limited
+	// to the records of that tenant. Statements without tenant fail with
+	// ErrNoTenant unless their context is marked by AllTenants.
+	TenantScoped interface {
+		GetTenantID() string
+	}
+
+	// Shared is implemented by models whose table is shared by all tenants in
+	// SchemaPerTenant mode, e.g. the table of the tenants themselves.
+	Shared interface {
+		Shared()
+	}
+
+	// tenancy is the gorm plugin isolating the data of the tenants.
+	tenancy struct {
+		mode TenantMode
+	}
+
+	tenantKey     struct{}
+	allTenantsKey struct{}
+)
+
+var (
+	// ErrInvalidTenant is returned for tenant IDs which are not made of
+	// lower case letters, digits, "_" and "-".
+	ErrInvalidTenant = errors.New("invalid tenant")
+	// ErrTenantMismatch is returned when a record of another tenant is
+	// created or saved, or when an update assigns the tenant of records.
+	ErrTenantMismatch = errors.New("record belongs to another tenant")
+	// ErrNoTenant is returned for statements on TenantScoped models whose
+	// context carries no tenant.
+	ErrNoTenant = errors.New("no tenant")
+
+	tenantPattern    = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,55}$`)
+	tenantScopedType = reflect.TypeOf((*TenantScoped)(nil)).Elem()
+	sharedType       = reflect.TypeOf((*Shared)(nil)).Elem()
+)
+
+// ValidTenant reports whether id may be used as tenant ID. IDs are limited to
+// lower case letters, digits, "_" and "-" so they can be used within schema
+// names.
+func ValidTenant(id string) bool {
+	return tenantPattern.MatchString(id)
+}
+
+// TenantSchema returns the name of the postgres schema holding the tables of
+// tenant in SchemaPerTenant mode.
+func TenantSchema(tenant string) string {
+	return schemaPrefix + tenant
+}
+
+// WithTenant returns a copy of ctx carrying the ID of the current tenant.
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+// TenantFromContext returns the ID of the tenant carried by ctx.
+func TenantFromContext(ctx context.Context) (string, bool) {
+	if ctx == nil {
+		return "", false
+	}
+	tenant, ok := ctx.Value(tenantKey{}).(string)
+	return tenant, ok && tenant != ""
+}
+
+// AllTenants returns a copy of ctx whose statements are not restricted to a
+// tenant, e.g. for maintenance tasks working across all tenants.
+func AllTenants(ctx context.Context) context.Context {
+	return context.WithValue(ctx, allTenantsKey{}, true)
+}
+
+// EnableTenancy isolates the data of the tenants using mode. Statements are
+// run on behalf of the tenant carried by their context, see WithContext and
+// ForTenant. Statements on TenantScoped models without a tenant fail with
+// ErrNoTenant; use AllTenants to work across all tenants. Raw SQL is never
+// rewritten. SchemaPerTenant mode is only supported on postgres.
+func (db *DB) EnableTenancy(mode TenantMode) error {
+	switch mode {
+	case SharedSchema:
+	case SchemaPerTenant:
+		if db.driver != Postgres {
+			return ErrUnsupportedDriver
+		}
+	default:
+		return ErrInvalidData
+	}
+	return db.Use(tenancy{mode: mode})
+}
+
+// TenantMode returns the isolation mode enabled by EnableTenancy, or zero if
+// tenancy is not enabled.
+func (db *DB) TenantMode() TenantMode {
+	if t, ok := db.Config.Plugins[tenancyPlugin].(tenancy); ok {
+		return t.mode
+	}
+	return 0
+}
+
+// ForTenant returns a copy of db running all statements on behalf of tenant.
+func (db *DB) ForTenant(tenant string) *DB {
+	ctx := db.Statement.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return db.WithContext(WithTenant(ctx, tenant))
+}
+
+// MigrateTenant creates the schema of tenant if needed and migrates the
+// tables of models within it. It is only supported on postgres.
+func (db *DB) MigrateTenant(tenant string, models ...interface{}) error {
+	if db.driver != Postgres {
+		return ErrUnsupportedDriver
+	}
+	if !ValidTenant(tenant) {
+		return ErrInvalidTenant
+	}
+	// The search path is set for the transaction only, so the migrator finds
+	// and creates the tables within the schema of the tenant.
+	return db.WithContext(context.Background()).Transaction(func(tx *DB) error {
+		schema := tx.Statement.Quote(TenantSchema(tenant))
+		if err := tx.Exec("CREATE SCHEMA IF NOT EXISTS " + schema).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("SET LOCAL search_path TO " + schema).Error; err != nil {
+			return err
+		}
+		return tx.AutoMigrate(models...)
+	})
+}
+
+func (tenancy) Name() string {
+	return tenancyPlugin
+}
+
+func (t tenancy) Initialize(db *gorm.DB) error {
+	callbacks := db.Callback()
+	if err := callbacks.Create().Before("gorm:create").Register("database:tenant_create", t.create); err != nil {
+		return err
+	}
+	if err := callbacks.Query().Before("gorm:query").Register("database:tenant_query", t.scope); err != nil {
+		return err
+	}
+	if err := callbacks.Update().Before("gorm:update").Register("database:tenant_update", t.update); err != nil {
+		return err
+	}
+	if err := callbacks.Delete().Before("gorm:delete").Register("database:tenant_delete", t.scope); err != nil {
+		return err
+	}
+	return callbacks.Row().Before("gorm:row").Register("database:tenant_row", t.scope)
+}
+
+// create stamps new records of TenantScoped models with the tenant and
+// inserts them into the schema of the tenant in SchemaPerTenant mode.
+func (t tenancy) create(db *gorm.DB) {
+	tenant, table, ok := t.prepare(db)
+	if !ok {
+		return
+	}
+	stmt := db.Statement
+	if table != "" {
+		// Not every dialect builds the INSERT clause from the table
+		// expression, so name the table explicitly.
+		stmt.AddClause(clause.Insert{Table: clause.Table{Name: table}})
+	}
+	if !implements(stmt, tenantScopedType) {
+		return
+	}
+	field := stmt.Schema.LookUpField(tenantField)
+	if field == nil {
+		return
+	}
+
+	stamp := func(rv reflect.Value) {
+		current, zero := field.ValueOf(rv)
+		if zero {
+			db.AddError(field.Set(rv, tenant))
+		} else if current != tenant {
+			db.AddError(ErrTenantMismatch)
+		}
+	}
+	switch stmt.ReflectValue.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < stmt.ReflectValue.Len(); i++ {
+			stamp(reflect.Indirect(stmt.ReflectValue.Index(i)))
+		}
+	case reflect.Struct:
+		stamp(stmt.ReflectValue)
+	}
+}
+
+// update limits updates of TenantScoped models to the records of the tenant
+// and keeps the records there. Saved records are stamped like in create;
+// updates selecting, omitting or assigning the tenant column fail with
+// ErrTenantMismatch.
+func (t tenancy) update(db *gorm.DB) {
+	t.scope(db)
+	tenant, ok := TenantFromContext(db.Statement.Context)
+	if db.Error != nil || !ok || !implements(db.Statement, tenantScopedType) {
+		return
+	}
+	stmt := db.Statement
+	field := stmt.Schema.LookUpField(tenantField)
+	if field == nil {
+		return
+	}
+	isTenant := func(name string) bool {
+		return name == field.Name || name == field.DBName
+	}
+	for _, columns := range [][]string{stmt.Selects, stmt.Omits} {
+		for _, column := range columns {
+			if isTenant(column) {
+				db.AddError(ErrTenantMismatch)
+				return
+			}
+		}
+	}
+
+	dest := reflect.ValueOf(stmt.Dest)
+	for dest.Kind() == reflect.Ptr {
+		dest = dest.Elem()
+	}
+	switch value := dest.Interface().(type) {
+	case map[string]interface{}:
+		for column := range value {
+			if isTenant(column) {
+				db.AddError(ErrTenantMismatch)
+				return
+			}
+		}
+	default:
+		if dest.Kind() != reflect.Struct || dest.Type() != stmt.Schema.ModelType {
+			return
+		}
+		current, zero := field.ValueOf(dest)
+		if !zero {
+			if current != tenant {
+				db.AddError(ErrTenantMismatch)
+			}
+			return
+		}
+		if !dest.CanAddr() {
+			// Stamp a copy of records passed by value, gorm treats them
+			// like any Dest differing from the Model.
+			ptr := reflect.New(dest.Type())
+			ptr.Elem().Set(dest)
+			stmt.Dest, dest = ptr.Interface(), ptr.Elem()
+		}
+		db.AddError(field.Set(dest, tenant))
+	}
+}
+
+// scope limits statements on TenantScoped models to the records of the
+// tenant.
+func (t tenancy) scope(db *gorm.DB) {
+	tenant, _, ok := t.prepare(db)
+	if !ok || !implements(db.Statement, tenantScopedType) {
+		return
+	}
+	stmt := db.Statement
+	field := stmt.Schema.LookUpField(tenantField)
+	if field == nil {
+		return
+	}
+	stmt.AddClause(clause.Where{Exprs: []clause.Expression{
+		clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: field.DBName}, Value: tenant},
+	}})
+}
+
+// prepare returns the tenant of the statement. In SchemaPerTenant mode it
+// moves the statement to the schema of the tenant and returns the qualified
+// table name.
+func (t tenancy) prepare(db *gorm.DB) (tenant, table string, ok bool) {
+	stmt := db.Statement
+	if db.Error != nil {
+		return "", "", false
+	}
+	if tenant, ok = TenantFromContext(stmt.Context); !ok {
+		if all, _ := stmt.Context.Value(allTenantsKey{}).(bool); !all && implements(stmt, tenantScopedType) {
+			db.AddError(ErrNoTenant)
+		}
+		return "", "", false
+	}
+	if !ValidTenant(tenant) {
+		db.AddError(ErrInvalidTenant)
+		return "", "", false
+	}
+
+	// Statements with a custom table expression, e.g. Table("public.users"),
+	// are left untouched.
+	if t.mode == SchemaPerTenant && stmt.TableExpr == nil && stmt.Table != "" && !implements(stmt, sharedType) {
+		table = TenantSchema(tenant) + "." + stmt.Table
+		stmt.TableExpr = &clause.Expr{SQL: stmt.Quote(table)}
+	}
+	return tenant, table, true
+}
+
+func implements(stmt *gorm.Statement, t reflect.Type) bool {
+	return stmt.Schema != nil && reflect.PtrTo(stmt.Schema.ModelType).Implements(t)
+}
@@ -0,0 +1,161 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type note struct {
+	ID       uint
+	TenantID string
+	Text     string
+}
+
+func (n *note) GetTenantID() string {
+	return n.TenantID
+}
+
+type plan struct {
+	ID   uint
+	Name string
+}
+
+func (plan) Shared() {}
+
+func newTenantDB(t *testing.T, mode TenantMode) *DB {
+	db, err := Connect("sqlite3", fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name()), 1)
+	require.NoError(t, err)
+	require.NoError(t, db.Use(tenancy{mode: mode}))
+	return db
+}
+
+func TestSharedSchema(t *testing.T) {
+	db := newTenantDB(t, SharedSchema)
+	require.NoError(t, db.AutoMigrate(&note{}))
+	assert.Equal(t, SharedSchema, db.TenantMode())
+
+	acme, globex := db.ForTenant("acme"), db.ForTenant("globex")
+	require.NoError(t, acme.Create(&note{Text: "a1"}).Error)
+	require.NoError(t, acme.Create(&[]*note{{Text: "a2"}, {Text: "a3"}}).Error)
+	g := &note{Text: "g1"}
+	require.NoError(t, globex.Create(g).Error)
+	assert.Equal(t, "globex", g.TenantID)
+
+	var notes []note
+	require.NoError(t, acme.Order("id").Find(&notes).Error)
+	require.Len(t, notes, 3)
+	for _, n := range notes {
+		assert.Equal(t, "acme", n.TenantID)
+	}
+
+	var count int64
+	require.NoError(t, acme.Model(&note{}).Count(&count).Error)
+	assert.Equal(t, int64(3), count)
+
+	// records of other tenants can neither be read, updated nor deleted
+	assert.Error(t, acme.First(&note{}, g.ID).Error)
+	res := acme.Model(&note{}).Where("id = ?", g.ID).Update("text", "hijacked")
+	require.NoError(t, res.Error)
+	assert.Equal(t, int64(0), res.RowsAffected)
+	res = acme.Delete(&note{}, g.ID)
+	require.NoError(t, res.Error)
+	assert.Equal(t, int64(0), res.RowsAffected)
+
+	// nor be created
+	assert.True(t, errors.Is(acme.Create(&note{TenantID: "globex"}).Error, ErrTenantMismatch))
+
+	// saved records keep their tenant
+	a := notes[0]
+	require.NoError(t, acme.Save(&note{ID: a.ID, Text: "changed"}).Error)
+	require.NoError(t, acme.First(&a, a.ID).Error)
+	assert.Equal(t, "acme", a.TenantID)
+	assert.Equal(t, "changed", a.Text)
+	require.NoError(t, acme.Save(note{ID: a.ID, Text: "by value"}).Error)
+	require.NoError(t, acme.First(&a, a.ID).Error)
+	assert.Equal(t, "acme", a.TenantID)
+	assert.True(t, errors.Is(acme.Save(&note{ID: a.ID, TenantID: "globex"}).Error, ErrTenantMismatch))
+
+	// and cannot be moved to another tenant
+	assert.True(t, errors.Is(acme.Model(&a).Updates(map[string]interface{}{"tenant_id": "globex"}).Error, ErrTenantMismatch))
+	assert.True(t, errors.Is(acme.Model(&a).Updates(map[string]interface{}{"TenantID": ""}).Error, ErrTenantMismatch))
+	assert.True(t, errors.Is(acme.Model(&a).Update("tenant_id", "globex").Error, ErrTenantMismatch))
+	assert.True(t, errors.Is(acme.Model(&a).Updates(&note{TenantID: "globex"}).Error, ErrTenantMismatch))
+	assert.True(t, errors.Is(acme.Model(&a).Select("tenant_id", "text").Updates(&note{Text: "x"}).Error, ErrTenantMismatch))
+	assert.True(t, errors.Is(acme.Model(&a).Omit("tenant_id").Updates(&note{Text: "x"}).Error, ErrTenantMismatch))
+	require.NoError(t, acme.Model(&a).Updates(map[string]interface{}{"text": "updated"}).Error)
+	require.NoError(t, db.WithContext(AllTenants(context.Background())).First(&a, a.ID).Error)
+	assert.Equal(t, "acme", a.TenantID)
+	assert.Equal(t, "updated", a.Text)
+	assert.True(t, errors.Is(db.ForTenant("../x").Create(&note{}).Error, ErrInvalidTenant))
+
+	// statements without tenant fail, unless they are meant for all tenants
+	assert.True(t, errors.Is(db.Model(&note{}).Count(&count).Error, ErrNoTenant))
+	assert.True(t, errors.Is(db.Create(&note{Text: "x"}).Error, ErrNoTenant))
+	assert.True(t, errors.Is(db.Where("1 = 1").Delete(&note{}).Error, ErrNoTenant))
+	all := db.WithContext(AllTenants(context.Background()))
+	require.NoError(t, all.Model(&note{}).Count(&count).Error)
+	assert.Equal(t, int64(4), count)
+}
+
+func TestSchemaPerTenant(t *testing.T) {
+	db := newTenantDB(t, SchemaPerTenant)
+	sqlDB, err := db.DB.DB()
+	require.NoError(t, err)
+	// attached databases are bound to the connection
+	sqlDB.SetMaxOpenConns(1)
+
+	require.NoError(t, db.AutoMigrate(&note{}, &plan{}))
+	for _, tenant := range []string{"acme", "globex"} {
+		require.NoError(t, db.Exec(fmt.Sprintf("ATTACH DATABASE 'file:%s_%s?mode=memory&cache=shared' AS %s", t.Name(), tenant, TenantSchema(tenant))).Error)
+		require.NoError(t, db.Exec(fmt.Sprintf("CREATE TABLE %s.notes (id integer primary key, tenant_id text, text text)", TenantSchema(tenant))).Error)
+	}
+
+	acme := db.ForTenant("acme")
+	require.NoError(t, acme.Create(&note{Text: "a1"}).Error)
+	require.NoError(t, db.ForTenant("globex").Create(&note{Text: "g1"}).Error)
+	require.NoError(t, acme.Create(&plan{Name: "basic"}).Error)
+
+	var notes []note
+	require.NoError(t, acme.Find(&notes).Error)
+	require.Len(t, notes, 1)
+	assert.Equal(t, "a1", notes[0].Text)
+
+	var count int64
+	require.NoError(t, db.Table(TenantSchema("globex")+".notes").Count(&count).Error)
+	assert.Equal(t, int64(1), count)
+	// the default schema holds no notes, but the shared plans
+	require.NoError(t, db.WithContext(AllTenants(context.Background())).Model(&note{}).Count(&count).Error)
+	assert.Equal(t, int64(0), count)
+	require.NoError(t, db.Model(&plan{}).Count(&count).Error)
+	assert.Equal(t, int64(1), count)
+}
+
+func TestEnableTenancy(t *testing.T) {
+	db, err := Connect("sqlite3", fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name()), 1)
+	require.NoError(t, err)
+	assert.Equal(t, TenantMode(0), db.TenantMode())
+	assert.True(t, errors.Is(db.EnableTenancy(SchemaPerTenant), ErrUnsupportedDriver))
+	assert.True(t, errors.Is(db.MigrateTenant("acme", &note{}), ErrUnsupportedDriver))
+	require.NoError(t, db.EnableTenancy(SharedSchema))
+	assert.Equal(t, SharedSchema, db.TenantMode())
+
+	tenant, ok := TenantFromContext(WithTenant(context.Background(), "acme"))
+	assert.True(t, ok)
+	assert.Equal(t, "acme", tenant)
+	_, ok = TenantFromContext(context.Background())
+	assert.False(t, ok)
+}
+
+func TestValidTenant(t *testing.T) {
+	for _, id := range []string{"acme", "a", "acme-1", "acme_corp"} {
+		assert.True(t, ValidTenant(id), id)
+	}
+	for _, id := range []string{"", "Acme", "-acme", "ac.me", "ac me", `a"b`} {
+		assert.False(t, ValidTenant(id), id)
+	}
+}
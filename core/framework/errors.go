@@ -0,0 +1,17 @@
+package framework
+
+import "errors"
+
+var (
+	// ErrInvalidCursor is returned for cursors which were not issued by the
+	// same listing.
+	ErrInvalidCursor = errors.New("invalid cursor")
+	// ErrInvalidSort is returned when sorting by a field which is not whitelisted.
+	ErrInvalidSort = errors.New("invalid sort field")
+	// ErrInvalidFilter is returned for filters on fields which are not
+	// whitelisted, unknown operators or values of the wrong type.
+	ErrInvalidFilter = errors.New("invalid filter")
+	// ErrConflict is returned when updating a Versioned record which has been
+	// updated by someone else since it was loaded.
+	ErrConflict = errors.New("record has been modified by someone else")
+)
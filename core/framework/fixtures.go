@@ -0,0 +1,260 @@
+package framework
+
+import (
+	"encoding/json"
+	"fmt"
+	"goplugins/core/framework/database"
+	"io/ioutil"
+	"reflect"
+	"strings"
+
+	"github.com/google/uuid"
+	"gopkg.in/yaml.v3"
+)
+
+// refPrefix marks a value referencing another fixture by its key. A value
+// starting with "@@" is an escaped "@".
+const refPrefix = "@"
+
+var uuidType = reflect.TypeOf(uuid.UUID{})
+
+type (
+	// Fixtures inserts records described in YAML or JSON documents.
+	//
+	//	- model: group
+	//	  key: staff
+	//	  fields:
+	//	    name: Staff
+	//	- model: user
+	//	  key: jane
+	//	  fields:
+	//	    email: jane@example.com
+	//	    groups: ["@staff"]
+	//
+	// A value "@key" references another fixture. On fields of type uuid.UUID
+	// it is replaced by the ID of the referenced record, on all other fields
+	// by the record itself, so associations can be set up. References may
+	// point to fixtures of the same document in any order, or to fixtures
+	// loaded before.
+	Fixtures struct {
+		db      *database.DB
+		models  map[string]reflect.Type
+		records map[string]interface{}
+	}
+
+	fixture struct {
+		Model  string                 `yaml:"model"`
+		Key    string                 `yaml:"key"`
+		Fields map[string]interface{} `yaml:"fields"`
+	}
+
+	identifier interface {
+		GetID() uuid.UUID
+	}
+)
+
+// NewFixtures returns a fixture loader writing to db.
+func NewFixtures(db *database.DB) *Fixtures {
+	return &Fixtures{
+		db:      db,
+		models:  map[string]reflect.Type{},
+		records: map[string]interface{}{},
+	}
+}
+
+// Register makes the type of model available to fixtures under name. model
+// must be a pointer to a struct embedding Model.
+func (f *Fixtures) Register(name string, model interface{}) *Fixtures {
+	t := reflect.TypeOf(model)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	f.models[name] = t
+	return f
+}
+
+// Get returns the record loaded for key.
+func (f *Fixtures) Get(key string) interface{} {
+	return f.records[key]
+}
+
+// LoadFile loads the fixtures of a YAML or JSON file.
+func (f *Fixtures) LoadFile(path string) error {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err := f.Load(b); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// Load loads the fixtures of a YAML or JSON document.
+func (f *Fixtures) Load(data []byte) error {
+	var list []*fixture
+	if err := yaml.Unmarshal(data, &list); err != nil {
+		return err
+	}
+
+	pending := map[string]*fixture{}
+	for i, fx := range list {
+		if _, ok := f.models[fx.Model]; !ok {
+			return fmt.Errorf("fixture %d: unknown model %q", i, fx.Model)
+		}
+		if fx.Key == "" {
+			fx.Key = fmt.Sprintf("%s#%d", fx.Model, i)
+		}
+		if _, ok := pending[fx.Key]; ok {
+			return fmt.Errorf("fixture %q defined twice", fx.Key)
+		}
+		if _, ok := f.records[fx.Key]; ok {
+			return fmt.Errorf("fixture %q already loaded", fx.Key)
+		}
+		pending[fx.Key] = fx
+	}
+
+	visiting := map[string]bool{}
+	var insert func(fx *fixture) error
+	insert = func(fx *fixture) error {
+		if _, ok := f.records[fx.Key]; ok {
+			return nil
+		}
+		if visiting[fx.Key] {
+			return fmt.Errorf("fixture %q references itself", fx.Key)
+		}
+		visiting[fx.Key] = true
+
+		for _, ref := range references(fx.Fields) {
+			if _, ok := f.records[ref]; ok {
+				continue
+			}
+			dep, ok := pending[ref]
+			if !ok {
+				return fmt.Errorf("fixture %q references unknown fixture %q", fx.Key, ref)
+			}
+			if err := insert(dep); err != nil {
+				return err
+			}
+		}
+		return f.insert(fx)
+	}
+
+	for _, fx := range list {
+		if err := insert(fx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *Fixtures) insert(fx *fixture) error {
+	t := f.models[fx.Model]
+	fields := make(map[string]interface{}, len(fx.Fields))
+	for name, v := range fx.Fields {
+		fields[name] = f.resolve(v, fieldType(t, name))
+	}
+
+	b, err := json.Marshal(fields)
+	if err != nil {
+		return fmt.Errorf("fixture %q: %w", fx.Key, err)
+	}
+	record := reflect.New(t).Interface()
+	if err := json.Unmarshal(b, record); err != nil {
+		return fmt.Errorf("fixture %q: %w", fx.Key, err)
+	}
+	if err := f.db.Create(record).Error; err != nil {
+		return fmt.Errorf("fixture %q: %w", fx.Key, err)
+	}
+	f.records[fx.Key] = record
+	return nil
+}
+
+// resolve replaces references within v. t is the type of the field v is
+// assigned to.
+func (f *Fixtures) resolve(v interface{}, t reflect.Type) interface{} {
+	switch val := v.(type) {
+	case string:
+		if strings.HasPrefix(val, refPrefix+refPrefix) {
+			return val[len(refPrefix):]
+		}
+		if !strings.HasPrefix(val, refPrefix) {
+			return val
+		}
+		record := f.records[val[len(refPrefix):]]
+		for t != nil && t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		if t == uuidType {
+			if id, ok := record.(identifier); ok {
+				return id.GetID()
+			}
+		}
+		return record
+	case []interface{}:
+		var elem reflect.Type
+		if t != nil && (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) && t != uuidType {
+			elem = t.Elem()
+		}
+		out := make([]interface{}, len(val))
+		for i, e := range val {
+			out[i] = f.resolve(e, elem)
+		}
+		return out
+	case map[string]interface{}:
+		for t != nil && t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		out := make(map[string]interface{}, len(val))
+		for k, e := range val {
+			var ft reflect.Type
+			if t != nil && t.Kind() == reflect.Struct {
+				ft = fieldType(t, k)
+			}
+			out[k] = f.resolve(e, ft)
+		}
+		return out
+	}
+	return v
+}
+
+// references returns the keys referenced within fields.
+func references(v interface{}) []string {
+	var refs []string
+	switch val := v.(type) {
+	case string:
+		if strings.HasPrefix(val, refPrefix) && !strings.HasPrefix(val, refPrefix+refPrefix) {
+			refs = append(refs, val[len(refPrefix):])
+		}
+	case []interface{}:
+		for _, e := range val {
+			refs = append(refs, references(e)...)
+		}
+	case map[string]interface{}:
+		for _, e := range val {
+			refs = append(refs, references(e)...)
+		}
+	}
+	return refs
+}
+
+// fieldType returns the type of the field encoded as name by encoding/json.
+func fieldType(t reflect.Type, name string) reflect.Type {
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag := strings.Split(sf.Tag.Get("json"), ",")[0]
+		if tag == "-" {
+			continue
+		}
+		if sf.Anonymous && tag == "" && sf.Type.Kind() == reflect.Struct {
+			if ft := fieldType(sf.Type, name); ft != nil {
+				return ft
+			}
+			continue
+		}
+		if tag == name || (tag == "" && strings.EqualFold(sf.Name, name)) {
+			return sf.Type
+		}
+	}
+	return nil
+}
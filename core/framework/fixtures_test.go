@@ -0,0 +1,84 @@
+package framework
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type (
+	Author struct {
+		Model
+		Name string `json:"name"`
+	}
+	Tag struct {
+		Model
+		Name string `json:"name"`
+	}
+	Post struct {
+		Model
+		Title    string    `json:"title"`
+		AuthorID uuid.UUID `json:"authorId"`
+		Tags     []*Tag    `json:"tags" gorm:"many2many:post_tags;"`
+	}
+)
+
+const testFixtures = `
+- model: post
+  key: hello
+  fields:
+    title: "@@hello"
+    authorId: "@jane"
+    tags: ["@go", "@news"]
+- model: author
+  key: jane
+  fields: {name: Jane}
+- model: tag
+  key: go
+  fields: {name: go}
+- model: tag
+  key: news
+  fields: {name: news}
+`
+
+func newFixtures(t *testing.T) *Fixtures {
+	db := newTestDB(t)
+	require.NoError(t, db.AutoMigrate(&Author{}, &Tag{}, &Post{}))
+	return NewFixtures(db).
+		Register("author", &Author{}).
+		Register("tag", &Tag{}).
+		Register("post", &Post{})
+}
+
+func TestFixtures(t *testing.T) {
+	f := newFixtures(t)
+	require.NoError(t, f.Load([]byte(testFixtures)))
+
+	jane := f.Get("jane").(*Author)
+	hello := f.Get("hello").(*Post)
+	assert.NotEqual(t, uuid.Nil, jane.ID)
+	assert.Equal(t, jane.ID, hello.AuthorID)
+	assert.Equal(t, "@hello", hello.Title)
+
+	loaded := &Post{}
+	require.NoError(t, f.db.Preload("Tags").First(loaded, "id = ?", hello.ID).Error)
+	require.Len(t, loaded.Tags, 2)
+	assert.ElementsMatch(t, []string{"go", "news"}, []string{loaded.Tags[0].Name, loaded.Tags[1].Name})
+
+	// JSON documents and references to fixtures loaded before
+	require.NoError(t, f.Load([]byte(`[{"model": "post", "key": "bye", "fields": {"title": "Bye", "authorId": "@jane"}}]`)))
+	assert.Equal(t, jane.ID, f.Get("bye").(*Post).AuthorID)
+}
+
+func TestFixturesErrors(t *testing.T) {
+	f := newFixtures(t)
+
+	assert.Error(t, f.Load([]byte(`[{"model": "comment"}]`)))
+	assert.Error(t, f.Load([]byte(`[{"model": "post", "fields": {"authorId": "@nobody"}}]`)))
+	assert.Error(t, f.Load([]byte(`
+- {model: tag, key: a, fields: {name: "@b"}}
+- {model: tag, key: b, fields: {name: "@a"}}
+`)))
+}
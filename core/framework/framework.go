@@ -4,6 +4,8 @@ import (
 	"goplugins/core/framework/config"
 	"goplugins/core/framework/database"
 	"goplugins/core/routing"
+	"goplugins/core/routing/middleware"
+	"goplugins/core/routing/openapi"
 
 	"github.com/sirupsen/logrus"
 )
@@ -26,8 +28,45 @@ func New(config config.Config) *Framework {
 		logger := logrus.WithError(err)
 		logger.Fatalln("framework: could not connect to database")
 	}
+	if err := RegisterCallbacks(db); err != nil {
+		logger := logrus.WithError(err)
+		logger.Fatalln("framework: could not register database callbacks")
+	}
 
 	mux := routing.New()
+	mux.Debug = config.App.Debug
+	mux.Use(middleware.RequestID(), middleware.Logger(), middleware.Recover(), middleware.Secure())
+	if len(config.CORS.AllowOrigins) > 0 || len(config.CORS.AllowOriginPatterns) > 0 {
+		cors, err := middleware.CORSFromConfig(config.CORS)
+		if err != nil {
+			logger := logrus.WithError(err)
+			logger.Fatalln("framework: invalid CORS settings")
+		}
+		mux.Pre(cors)
+	}
+
+	if config.OpenAPI.Path != "" {
+		openapi.Register(mux, openapi.Config{
+			Path: config.OpenAPI.Path,
+			Info: openapi.Info{
+				Title:   config.App.Name,
+				Version: config.OpenAPI.Version,
+			},
+			Servers: []openapi.Server{{URL: config.App.URL}},
+		})
+	}
+
+	if config.Tenancy.Mode != "" {
+		mode, tenantConfig, err := tenancyFromConfig(config.Tenancy)
+		if err == nil {
+			err = db.EnableTenancy(mode)
+		}
+		if err != nil {
+			logger := logrus.WithError(err)
+			logger.Fatalln("framework: could not enable tenancy")
+		}
+		mux.Use(ResolveTenant(tenantConfig))
+	}
 
 	files, err := ListAvailablePlugins()
 	if err != nil {
@@ -57,7 +96,19 @@ func (f *Framework) AddService(fn func(*database.DB, *routing.Mux)) {
 	fn(f.db, f.mux)
 }
 
+// Seed runs the named seeders, or all registered seeders if no names are
+// given. Every seeder runs once per database.
+func (f *Framework) Seed(names ...string) error {
+	return seeders.Run(f.db, names...)
+}
+
 // Start starts the framework service
 func (f *Framework) Start() {
+	if f.config.App.Env == "development" {
+		if err := f.Seed(); err != nil {
+			logger := logrus.WithError(err)
+			logger.Fatalln("framework: could not seed database")
+		}
+	}
 	f.mux.Logger.Fatal(f.mux.Start(":3000"))
 }
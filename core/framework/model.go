@@ -1,9 +1,12 @@
 package framework
 
 import (
+	"encoding/binary"
+	"reflect"
 	"time"
 
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 type (
@@ -13,14 +16,136 @@ type (
 		CreatedAt time.Time `json:"createdAt"`
 		UpdatedAt time.Time `json:"updatedAt"`
 	}
+
+	// SoftDelete can be embedded next to Model to mark records as deleted
+	// instead of removing them. Deleted records are excluded from all queries
+	// unless `Unscoped()` is used.
+	SoftDelete struct {
+		DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+	}
+
+	// Versioned can be embedded next to Model to enable optimistic locking.
+	// Every update increments Version and fails with ErrConflict if the
+	// record has been updated since it was loaded.
+	Versioned struct {
+		Version uint `json:"version" gorm:"not null;default:1"`
+	}
+
+	// Audited can be embedded next to Model to record which user created and
+	// last updated a record. The user is taken from the context of the
+	// statement, see WithUser.
+	Audited struct {
+		CreatedBy *uuid.UUID `json:"createdBy,omitempty"`
+		UpdatedBy *uuid.UUID `json:"updatedBy,omitempty"`
+	}
+
+	// Tenanted can be embedded next to Model to make records belong to a
+	// tenant. Records are stamped with and filtered by the tenant of the
+	// statement context once tenancy is enabled, see database.EnableTenancy.
+	// The tenant is not part of the JSON representation, so clients cannot
+	// move records to another tenant.
+	Tenanted struct {
+		TenantID string `json:"-" gorm:"index;not null;default:''"`
+	}
+
+	// IDStrategy selects how IDs of new records are generated.
+	IDStrategy int
+
+	// IDStrategist can be implemented by models to select the IDStrategy
+	// used for their records. Models not implementing it use UUIDv4.
+	//
+	//  func (Order) IDStrategy() framework.IDStrategy {
+	//      return framework.TimeOrderedUUID
+	//  }
+	IDStrategist interface {
+		IDStrategy() IDStrategy
+	}
+
+	versioner interface {
+		GetVersion() uint
+	}
+
+	auditor interface {
+		GetCreatedBy() *uuid.UUID
+	}
 )
 
+// ID strategies
+const (
+	// UUIDv4 generates random UUIDs.
+	UUIDv4 IDStrategy = iota
+	// TimeOrderedUUID generates UUIDs starting with the creation time in
+	// milliseconds (UUIDv7 layout). New records are appended to the end of
+	// the primary key index, which gives a far better index locality on
+	// postgres and mysql than random UUIDs.
+	TimeOrderedUUID
+)
+
+var idStrategistType = reflect.TypeOf((*IDStrategist)(nil)).Elem()
+
+// NewID returns a new ID generated using the given strategy.
+func NewID(strategy IDStrategy) uuid.UUID {
+	if strategy == TimeOrderedUUID {
+		return newTimeOrderedUUID(time.Now())
+	}
+	return uuid.New()
+}
+
+func newTimeOrderedUUID(t time.Time) uuid.UUID {
+	id := uuid.New()
+	var ts [8]byte
+	binary.BigEndian.PutUint64(ts[:], uint64(t.UnixNano()/int64(time.Millisecond)))
+	copy(id[:6], ts[2:])
+	id[6] = id[6]&0x0f | 0x70 // version 7
+	return id
+}
+
+// BeforeCreate assigns a new ID to records without one. Models defining
+// their own BeforeCreate hook have to call this one as well.
+func (m *Model) BeforeCreate(tx *gorm.DB) error {
+	if m.ID != uuid.Nil {
+		return nil
+	}
+
+	strategy := UUIDv4
+	if s := tx.Statement.Schema; s != nil && reflect.PtrTo(s.ModelType).Implements(idStrategistType) {
+		strategy = reflect.New(s.ModelType).Interface().(IDStrategist).IDStrategy()
+	}
+	m.ID = NewID(strategy)
+	return nil
+}
+
 // GetID returns the ID of the model
 func (m *Model) GetID() uuid.UUID {
 	return m.ID
 }
 
+// GetUpdatedAt returns the time the record was last updated
+func (m *Model) GetUpdatedAt() time.Time {
+	return m.UpdatedAt
+}
+
 // Validate validates the struct tags against the input
 func (m *Model) Validate() bool {
 	return true
 }
+
+// IsDeleted reports whether the record has been soft deleted.
+func (s *SoftDelete) IsDeleted() bool {
+	return s.DeletedAt.Valid
+}
+
+// GetVersion returns the version of the record
+func (v *Versioned) GetVersion() uint {
+	return v.Version
+}
+
+// GetCreatedBy returns the ID of the user who created the record
+func (a *Audited) GetCreatedBy() *uuid.UUID {
+	return a.CreatedBy
+}
+
+// GetTenantID returns the ID of the tenant the record belongs to
+func (t *Tenanted) GetTenantID() string {
+	return t.TenantID
+}
@@ -0,0 +1,55 @@
+package framework
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type event struct {
+	Model
+	Name string
+}
+
+func (event) IDStrategy() IDStrategy {
+	return TimeOrderedUUID
+}
+
+func TestNewID(t *testing.T) {
+	id := NewID(UUIDv4)
+	assert.Equal(t, uuid.Version(4), id.Version())
+	assert.Equal(t, uuid.RFC4122, id.Variant())
+
+	id = NewID(TimeOrderedUUID)
+	assert.Equal(t, uuid.Version(7), id.Version())
+	assert.Equal(t, uuid.RFC4122, id.Variant())
+
+	now := time.Now()
+	first := newTimeOrderedUUID(now)
+	second := newTimeOrderedUUID(now.Add(time.Millisecond))
+	assert.True(t, first.String() < second.String())
+}
+
+func TestModelBeforeCreate(t *testing.T) {
+	db := newTestDB(t)
+	require.NoError(t, db.AutoMigrate(&widget{}, &event{}))
+
+	w := &widget{Name: "random"}
+	require.NoError(t, db.Create(w).Error)
+	assert.Equal(t, uuid.Version(4), w.ID.Version())
+
+	events := []*event{{Name: "first"}, {Name: "second"}}
+	require.NoError(t, db.Create(&events).Error)
+	for _, e := range events {
+		assert.Equal(t, uuid.Version(7), e.ID.Version())
+	}
+	assert.NotEqual(t, events[0].ID, events[1].ID)
+
+	id := uuid.New()
+	w = &widget{Model: Model{ID: id}}
+	require.NoError(t, db.Create(w).Error)
+	assert.Equal(t, id, w.ID)
+}
@@ -0,0 +1,226 @@
+package framework
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"goplugins/core/routing"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Filter operators
+const (
+	OpEq   = "eq"
+	OpNe   = "ne"
+	OpGt   = "gt"
+	OpGte  = "gte"
+	OpLt   = "lt"
+	OpLte  = "lte"
+	OpLike = "like"
+	OpIn   = "in"
+)
+
+const (
+	queryLimit  = "limit"
+	queryOffset = "offset"
+	queryCursor = "cursor"
+	querySort   = "sort"
+	queryFilter = "filter"
+)
+
+type (
+	// ListParams describes which page of a collection should be loaded.
+	ListParams struct {
+		// Limit is the maximum number of items on the page. Zero selects the
+		// repository default.
+		Limit int
+		// Offset skips the given amount of items. It is ignored as soon as a
+		// Cursor is set.
+		Offset int
+		// Cursor continues a listing after the last item of a previous page.
+		Cursor string
+		// Sort holds the requested ordering, e.g. parsed from `sort=-createdAt,email`.
+		Sort []SortField
+		// Filters restricts the result, e.g. parsed from `filter[email]=a@b.c`
+		// or `filter[createdAt][gte]=2020-01-01T00:00:00Z`.
+		Filters []Filter
+		// URL is the request URL the page links are built from.
+		URL *url.URL
+	}
+
+	// SortField is a single field of an ordering.
+	SortField struct {
+		Field string
+		Desc  bool
+	}
+
+	// Filter is a single condition on a field.
+	Filter struct {
+		Field    string
+		Operator string
+		Value    string
+	}
+
+	// Page is a single page of a collection. It is meant to be sent as is
+	// using `Context#JSON()`.
+	Page struct {
+		Items      interface{} `json:"items"`
+		Total      int64       `json:"total"`
+		Limit      int         `json:"limit"`
+		Offset     int         `json:"offset"`
+		NextCursor string      `json:"nextCursor,omitempty"`
+		Links      PageLinks   `json:"links"`
+	}
+
+	// PageLinks contains the links to the current and the surrounding pages.
+	PageLinks struct {
+		Self string `json:"self,omitempty"`
+		Next string `json:"next,omitempty"`
+		Prev string `json:"prev,omitempty"`
+	}
+
+	// cursor is the decoded form of ListParams.Cursor. It carries the sort
+	// values of the last item of the previous page.
+	cursor struct {
+		Sort   string            `json:"s"`
+		Values []json.RawMessage `json:"v"`
+	}
+)
+
+// ParseListParams reads the pagination, sorting and filtering parameters
+// from the query string of the request.
+//
+//	?limit=20&offset=40
+//	?limit=20&cursor=eyJzIjoi...
+//	?sort=-createdAt,email
+//	?filter[isActive]=true&filter[createdAt][gte]=2020-01-01T00:00:00Z
+//
+// Which fields may be sorted and filtered by is decided by the Repository.
+func ParseListParams(c routing.Context) (ListParams, error) {
+	query := c.QueryParams()
+	u := *c.Request().URL
+	params := ListParams{
+		Cursor: query.Get(queryCursor),
+		URL:    &u,
+	}
+
+	var err error
+	if v := query.Get(queryLimit); v != "" {
+		if params.Limit, err = strconv.Atoi(v); err != nil || params.Limit < 0 {
+			return params, routing.NewHTTPError(http.StatusBadRequest, "invalid limit").SetInternal(err)
+		}
+	}
+	if v := query.Get(queryOffset); v != "" {
+		if params.Offset, err = strconv.Atoi(v); err != nil || params.Offset < 0 {
+			return params, routing.NewHTTPError(http.StatusBadRequest, "invalid offset").SetInternal(err)
+		}
+	}
+
+	params.Sort = ParseSort(query.Get(querySort))
+
+	for key, values := range query {
+		if !strings.HasPrefix(key, queryFilter+"[") {
+			continue
+		}
+		field, op, ok := parseFilterKey(key[len(queryFilter):])
+		if !ok {
+			return params, routing.NewHTTPError(http.StatusBadRequest, "invalid filter "+key).SetInternal(ErrInvalidFilter)
+		}
+		for _, v := range values {
+			params.Filters = append(params.Filters, Filter{Field: field, Operator: op, Value: v})
+		}
+	}
+
+	return params, nil
+}
+
+// ParseSort parses a comma separated list of fields. A leading "-" sorts
+// the field in descending order.
+func ParseSort(s string) []SortField {
+	var fields []SortField
+	for _, f := range strings.Split(s, ",") {
+		f = strings.TrimSpace(f)
+		if f == "" {
+			continue
+		}
+		sf := SortField{Field: f}
+		switch f[0] {
+		case '-':
+			sf.Field, sf.Desc = f[1:], true
+		case '+':
+			sf.Field = f[1:]
+		}
+		fields = append(fields, sf)
+	}
+	return fields
+}
+
+// sortString formats the sort fields the way ParseSort reads them.
+func sortString(fields []SortField) string {
+	parts := make([]string, len(fields))
+	for i, f := range fields {
+		if f.Desc {
+			parts[i] = "-" + f.Field
+		} else {
+			parts[i] = f.Field
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// parseFilterKey splits `[field]` or `[field][op]` into its parts.
+func parseFilterKey(key string) (field, op string, ok bool) {
+	var parts []string
+	for key != "" {
+		if key[0] != '[' {
+			return "", "", false
+		}
+		end := strings.IndexByte(key, ']')
+		if end < 2 {
+			return "", "", false
+		}
+		parts = append(parts, key[1:end])
+		key = key[end+1:]
+	}
+
+	switch len(parts) {
+	case 1:
+		return parts[0], OpEq, true
+	case 2:
+		return parts[0], parts[1], true
+	}
+	return "", "", false
+}
+
+func encodeCursor(c cursor) string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeCursor(s string) (c cursor, err error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, err
+	}
+	err = json.Unmarshal(b, &c)
+	return
+}
+
+// pageURL returns the link to another page of the same listing.
+func pageURL(u *url.URL, set map[string]string) string {
+	if u == nil {
+		return ""
+	}
+	query := u.Query()
+	for k, v := range set {
+		if v == "" {
+			query.Del(k)
+		} else {
+			query.Set(k, v)
+		}
+	}
+	link := url.URL{Path: u.Path, RawQuery: query.Encode()}
+	return link.String()
+}
@@ -0,0 +1,592 @@
+package framework
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"goplugins/core/framework/database"
+	"goplugins/core/routing"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+const (
+	defaultPageLimit = 20
+	maxPageLimit     = 100
+)
+
+var valuerType = reflect.TypeOf((*driver.Valuer)(nil)).Elem()
+
+type (
+	// Repository provides CRUD operations and paginated listings for a model
+	// embedding Model.
+	//
+	//  repo := framework.NewRepository(db, &models.User{},
+	//      framework.WithSortable("email", "createdAt"),
+	//      framework.WithFilterable("email", "isActive"),
+	//  )
+	Repository struct {
+		db           *database.DB
+		typ          reflect.Type
+		schema       *schema.Schema
+		sortable     map[string]*schema.Field
+		filterable   map[string]*schema.Field
+		defaultSort  []SortField
+		defaultLimit int
+		maxLimit     int
+	}
+
+	// RepositoryOption configures a Repository.
+	RepositoryOption func(*repositoryConfig)
+
+	repositoryConfig struct {
+		sortable     []string
+		filterable   []string
+		defaultSort  string
+		defaultLimit int
+		maxLimit     int
+	}
+)
+
+// WithSortable whitelists the fields a listing may be sorted by. Fields are
+// referenced by their json name, Go name or column name.
+func WithSortable(fields ...string) RepositoryOption {
+	return func(c *repositoryConfig) {
+		c.sortable = append(c.sortable, fields...)
+	}
+}
+
+// WithFilterable whitelists the fields a listing may be filtered by.
+func WithFilterable(fields ...string) RepositoryOption {
+	return func(c *repositoryConfig) {
+		c.filterable = append(c.filterable, fields...)
+	}
+}
+
+// WithDefaultSort sets the ordering used when a listing does not request
+// one, e.g. "-createdAt". The default is "createdAt".
+func WithDefaultSort(sort string) RepositoryOption {
+	return func(c *repositoryConfig) {
+		c.defaultSort = sort
+	}
+}
+
+// WithLimits sets the default and the maximum page size.
+// The defaults are 20 and 100.
+func WithLimits(defaultLimit, maxLimit int) RepositoryOption {
+	return func(c *repositoryConfig) {
+		c.defaultLimit = defaultLimit
+		c.maxLimit = maxLimit
+	}
+}
+
+// NewRepository returns a Repository for the type of model, which must be a
+// pointer to a struct embedding Model. It panics if the model can't be parsed
+// or a whitelisted field does not exist, as both are programming errors.
+func NewRepository(db *database.DB, model interface{}, options ...RepositoryOption) *Repository {
+	cfg := &repositoryConfig{
+		defaultSort:  "createdAt",
+		defaultLimit: defaultPageLimit,
+		maxLimit:     maxPageLimit,
+	}
+	for _, option := range options {
+		option(cfg)
+	}
+
+	stmt := &gorm.Statement{DB: db.DB}
+	if err := stmt.Parse(model); err != nil {
+		panic(fmt.Sprintf("framework: invalid repository model: %v", err))
+	}
+
+	r := &Repository{
+		db:           db,
+		typ:          stmt.Schema.ModelType,
+		schema:       stmt.Schema,
+		sortable:     map[string]*schema.Field{},
+		filterable:   map[string]*schema.Field{},
+		defaultSort:  ParseSort(cfg.defaultSort),
+		defaultLimit: cfg.defaultLimit,
+		maxLimit:     cfg.maxLimit,
+	}
+	if r.primaryField() == nil {
+		panic("framework: repository model has no ID field")
+	}
+	for _, name := range cfg.sortable {
+		r.sortable[name] = r.mustLookUp(name)
+	}
+	for _, name := range cfg.filterable {
+		r.filterable[name] = r.mustLookUp(name)
+	}
+	for _, sf := range r.defaultSort {
+		if _, ok := r.sortable[sf.Field]; !ok {
+			r.sortable[sf.Field] = r.mustLookUp(sf.Field)
+		}
+	}
+
+	return r
+}
+
+// DB returns a session scoped to the model of the repository.
+func (r *Repository) DB() *gorm.DB {
+	return r.db.Model(reflect.New(r.typ).Interface())
+}
+
+// WithContext returns a copy of the repository running all statements with
+// ctx. Pass the request context to have Audited records stamped with the
+// authenticated user.
+func (r *Repository) WithContext(ctx context.Context) *Repository {
+	clone := *r
+	clone.db = r.db.WithContext(ctx)
+	return &clone
+}
+
+// Create inserts value.
+func (r *Repository) Create(value interface{}) error {
+	return r.db.Create(value).Error
+}
+
+// Find loads the record with the given id into dest. It returns
+// database.ErrRecordNotFound if no such record exists.
+func (r *Repository) Find(id uuid.UUID, dest interface{}) error {
+	err := r.db.Where(clause.Eq{
+		Column: clause.Column{Table: clause.CurrentTable, Name: r.primaryField().DBName},
+		Value:  id,
+	}).First(dest).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return database.ErrRecordNotFound
+	}
+	return err
+}
+
+// Update saves all fields of value. Stale updates of Versioned records are
+// reported as `*routing.HTTPError` with status 409 wrapping ErrConflict.
+func (r *Repository) Update(value interface{}) error {
+	err := r.db.Save(value).Error
+	if errors.Is(err, ErrConflict) {
+		return routing.NewHTTPError(http.StatusConflict, err.Error()).SetInternal(err)
+	}
+	return err
+}
+
+// Delete removes value. Records embedding SoftDelete are only marked as
+// deleted.
+func (r *Repository) Delete(value interface{}) error {
+	return r.db.Delete(value).Error
+}
+
+// ForceDelete removes value, even if it embeds SoftDelete.
+func (r *Repository) ForceDelete(value interface{}) error {
+	return r.db.Unscoped().Delete(value).Error
+}
+
+// Restore undeletes the soft deleted record with the given id. It returns
+// database.ErrRecordNotFound if no such record exists.
+func (r *Repository) Restore(id uuid.UUID) error {
+	field := r.schema.LookUpField("DeletedAt")
+	if field == nil {
+		return database.ErrRecordNotFound
+	}
+	res := r.db.Unscoped().Model(reflect.New(r.typ).Interface()).Where(clause.Eq{
+		Column: clause.Column{Table: clause.CurrentTable, Name: r.primaryField().DBName},
+		Value:  id,
+	}).UpdateColumn(field.DBName, nil)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return database.ErrRecordNotFound
+	}
+	return nil
+}
+
+// DeleteByID removes the record with the given id. It returns
+// database.ErrRecordNotFound if no such record exists.
+func (r *Repository) DeleteByID(id uuid.UUID) error {
+	res := r.db.Delete(reflect.New(r.typ).Interface(), clause.Eq{
+		Column: clause.Column{Table: clause.CurrentTable, Name: r.primaryField().DBName},
+		Value:  id,
+	})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return database.ErrRecordNotFound
+	}
+	return nil
+}
+
+// List loads a page of records into dest, which must be a pointer to a slice
+// of the model type. The returned Page references dest as its items.
+//
+// Invalid sort fields, filters and cursors are reported as `*routing.HTTPError`
+// with status 400, so handlers can return them as is.
+func (r *Repository) List(params ListParams, dest interface{}) (*Page, error) {
+	rv := reflect.ValueOf(dest)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Slice {
+		return nil, fmt.Errorf("framework: list destination must be a pointer to a slice, got %T", dest)
+	}
+
+	limit := params.Limit
+	if limit <= 0 {
+		limit = r.defaultLimit
+	}
+	if limit > r.maxLimit {
+		limit = r.maxLimit
+	}
+
+	sort, err := r.orderBy(params.Sort)
+	if err != nil {
+		return nil, badRequest(err)
+	}
+	conds, err := r.conditions(params.Filters)
+	if err != nil {
+		return nil, badRequest(err)
+	}
+
+	tx := r.DB()
+	if len(conds) > 0 {
+		tx = tx.Clauses(clause.Where{Exprs: conds})
+	}
+	// Keep the conditions for both, the count and the page query.
+	tx = tx.Session(&gorm.Session{WithConditions: true})
+
+	page := &Page{Limit: limit}
+	if err := tx.Count(&page.Total).Error; err != nil {
+		return nil, err
+	}
+
+	if params.Cursor != "" {
+		after, err := r.after(params.Cursor, sort)
+		if err != nil {
+			return nil, badRequest(err)
+		}
+		tx = tx.Clauses(clause.Where{Exprs: []clause.Expression{after}})
+	} else {
+		page.Offset = params.Offset
+		tx = tx.Offset(params.Offset)
+	}
+
+	columns := make([]clause.OrderByColumn, 0, len(sort))
+	for _, s := range sort {
+		if s.nullable {
+			// Dialects disagree on where NULLs go, sort them explicitly as
+			// if they were greater than all values.
+			columns = append(columns, clause.OrderByColumn{
+				Column: clause.Column{Name: tx.Statement.Quote(r.schema.Table+"."+s.field.DBName) + " IS NULL", Raw: true},
+				Desc:   s.desc,
+			})
+		}
+		columns = append(columns, clause.OrderByColumn{
+			Column: clause.Column{Table: clause.CurrentTable, Name: s.field.DBName},
+			Desc:   s.desc,
+		})
+	}
+
+	// Load one more item than requested to know whether there is a next page.
+	if err := tx.Clauses(clause.OrderBy{Columns: columns}).Limit(limit + 1).Find(dest).Error; err != nil {
+		return nil, err
+	}
+
+	items := rv.Elem()
+	hasNext := items.Len() > limit
+	if hasNext {
+		items.Set(items.Slice(0, limit))
+	} else if items.IsNil() {
+		// Send an empty list instead of null.
+		items.Set(reflect.MakeSlice(items.Type(), 0, 0))
+	}
+	page.Items = items.Interface()
+
+	page.Links.Self = pageURL(params.URL, nil)
+	if hasNext {
+		page.NextCursor = r.cursorOf(items.Index(limit-1), sort)
+	}
+	if params.Cursor != "" {
+		if hasNext {
+			page.Links.Next = pageURL(params.URL, map[string]string{queryCursor: page.NextCursor, queryOffset: ""})
+		}
+		return page, nil
+	}
+	if hasNext {
+		page.Links.Next = pageURL(params.URL, map[string]string{queryOffset: strconv.Itoa(page.Offset + limit)})
+	}
+	if page.Offset > 0 {
+		prev := page.Offset - limit
+		if prev < 0 {
+			prev = 0
+		}
+		page.Links.Prev = pageURL(params.URL, map[string]string{queryOffset: strconv.Itoa(prev)})
+	}
+
+	return page, nil
+}
+
+type orderField struct {
+	name     string
+	field    *schema.Field
+	desc     bool
+	nullable bool
+}
+
+// orderBy resolves the requested ordering. The primary key is always
+// appended, so the ordering is total and cursors are stable.
+func (r *Repository) orderBy(fields []SortField) ([]orderField, error) {
+	if len(fields) == 0 {
+		fields = r.defaultSort
+	}
+
+	pk := r.primaryField()
+	var sort []orderField
+	hasPK := false
+	for _, sf := range fields {
+		f, ok := r.sortable[sf.Field]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidSort, sf.Field)
+		}
+		if f == pk {
+			hasPK = true
+		}
+		sort = append(sort, orderField{name: sf.Field, field: f, desc: sf.Desc, nullable: nullable(f)})
+	}
+	if !hasPK {
+		desc := false
+		if len(sort) > 0 {
+			desc = sort[len(sort)-1].desc
+		}
+		sort = append(sort, orderField{name: "id", field: pk, desc: desc})
+	}
+	return sort, nil
+}
+
+func (r *Repository) conditions(filters []Filter) ([]clause.Expression, error) {
+	var conds []clause.Expression
+	for _, f := range filters {
+		field, ok := r.filterable[f.Field]
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown field %s", ErrInvalidFilter, f.Field)
+		}
+		column := clause.Column{Table: clause.CurrentTable, Name: field.DBName}
+
+		if f.Operator == OpIn {
+			var values []interface{}
+			for _, s := range strings.Split(f.Value, ",") {
+				v, err := parseValue(field, s)
+				if err != nil {
+					return nil, fmt.Errorf("%w: %s: %v", ErrInvalidFilter, f.Field, err)
+				}
+				values = append(values, v)
+			}
+			conds = append(conds, clause.IN{Column: column, Values: values})
+			continue
+		}
+
+		var value interface{} = f.Value
+		if f.Operator != OpLike {
+			v, err := parseValue(field, f.Value)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %s: %v", ErrInvalidFilter, f.Field, err)
+			}
+			value = v
+		}
+
+		switch f.Operator {
+		case OpEq, "":
+			conds = append(conds, clause.Eq{Column: column, Value: value})
+		case OpNe:
+			conds = append(conds, clause.Neq{Column: column, Value: value})
+		case OpGt:
+			conds = append(conds, clause.Gt{Column: column, Value: value})
+		case OpGte:
+			conds = append(conds, clause.Gte{Column: column, Value: value})
+		case OpLt:
+			conds = append(conds, clause.Lt{Column: column, Value: value})
+		case OpLte:
+			conds = append(conds, clause.Lte{Column: column, Value: value})
+		case OpLike:
+			conds = append(conds, clause.Like{Column: column, Value: "%" + f.Value + "%"})
+		default:
+			return nil, fmt.Errorf("%w: unknown operator %s", ErrInvalidFilter, f.Operator)
+		}
+	}
+	return conds, nil
+}
+
+// after builds the keyset condition selecting all rows behind the cursor:
+//
+//	(a > ?) OR (a = ? AND b > ?) OR (a = ? AND b = ? AND id > ?)
+//
+// NULLs of nullable columns are sorted after all values, see List.
+func (r *Repository) after(s string, sort []orderField) (clause.Expression, error) {
+	c, err := decodeCursor(s)
+	if err != nil || len(c.Values) != len(sort) || c.Sort != orderString(sort) {
+		return nil, ErrInvalidCursor
+	}
+
+	values := make([]interface{}, len(sort))
+	for i, o := range sort {
+		v := reflect.New(o.field.FieldType)
+		if err := json.Unmarshal(c.Values[i], v.Interface()); err != nil {
+			return nil, ErrInvalidCursor
+		}
+		if !isNull(v.Elem()) {
+			values[i] = v.Elem().Interface()
+		}
+	}
+
+	var or []clause.Expression
+	for i, o := range sort {
+		and := make([]clause.Expression, 0, i+1)
+		for j := 0; j < i; j++ {
+			// Eq turns into IS NULL for nil values.
+			and = append(and, clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: sort[j].field.DBName}, Value: values[j]})
+		}
+		column := clause.Column{Table: clause.CurrentTable, Name: o.field.DBName}
+		switch {
+		case values[i] == nil && o.desc:
+			and = append(and, clause.Neq{Column: column, Value: nil})
+		case values[i] == nil:
+			// Nothing sorts after NULL.
+			continue
+		case o.desc:
+			and = append(and, clause.Lt{Column: column, Value: values[i]})
+		case o.nullable:
+			and = append(and, clause.Or(clause.Gt{Column: column, Value: values[i]}, clause.Eq{Column: column, Value: nil}))
+		default:
+			and = append(and, clause.Gt{Column: column, Value: values[i]})
+		}
+		or = append(or, clause.And(and...))
+	}
+	if len(or) == 0 {
+		return clause.Expr{SQL: "1 = 0"}, nil
+	}
+	return clause.Or(or...), nil
+}
+
+// nullable reports whether f may hold NULL, i.e. it is a pointer or a
+// sql.Null* like type.
+func nullable(f *schema.Field) bool {
+	if f.FieldType.Kind() == reflect.Ptr {
+		return true
+	}
+	if f.FieldType.Kind() != reflect.Struct || !f.FieldType.Implements(valuerType) {
+		return false
+	}
+	valid, ok := f.FieldType.FieldByName("Valid")
+	return ok && valid.Type.Kind() == reflect.Bool
+}
+
+// isNull reports whether v is a nil pointer or a Valuer of NULL.
+func isNull(v reflect.Value) bool {
+	if v.Kind() == reflect.Ptr {
+		return v.IsNil()
+	}
+	if valuer, ok := v.Interface().(driver.Valuer); ok {
+		value, err := valuer.Value()
+		return err == nil && value == nil
+	}
+	return false
+}
+
+func (r *Repository) cursorOf(item reflect.Value, sort []orderField) string {
+	for item.Kind() == reflect.Ptr || item.Kind() == reflect.Interface {
+		item = item.Elem()
+	}
+	c := cursor{Sort: orderString(sort)}
+	for _, o := range sort {
+		v, _ := o.field.ValueOf(item)
+		b, _ := json.Marshal(v)
+		c.Values = append(c.Values, b)
+	}
+	return encodeCursor(c)
+}
+
+func (r *Repository) primaryField() *schema.Field {
+	if r.schema.PrioritizedPrimaryField != nil {
+		return r.schema.PrioritizedPrimaryField
+	}
+	return r.schema.LookUpField("ID")
+}
+
+// mustLookUp resolves a field by its json name, Go name or column name.
+func (r *Repository) mustLookUp(name string) *schema.Field {
+	for _, f := range r.schema.Fields {
+		if f.DBName == "" {
+			continue
+		}
+		jsonName := strings.Split(f.Tag.Get("json"), ",")[0]
+		if jsonName == name || f.Name == name || f.DBName == name {
+			return f
+		}
+	}
+	panic(fmt.Sprintf("framework: unknown field %q on %s", name, r.schema.Name))
+}
+
+func orderString(sort []orderField) string {
+	fields := make([]SortField, len(sort))
+	for i, o := range sort {
+		fields[i] = SortField{Field: o.name, Desc: o.desc}
+	}
+	return sortString(fields)
+}
+
+// parseValue converts a query string value into the type of the field.
+func parseValue(field *schema.Field, s string) (interface{}, error) {
+	v := reflect.New(field.FieldType)
+	if u, ok := v.Interface().(encoding.TextUnmarshaler); ok {
+		if err := u.UnmarshalText([]byte(s)); err != nil {
+			return nil, err
+		}
+		return v.Elem().Interface(), nil
+	}
+
+	e := v.Elem()
+	for e.Kind() == reflect.Ptr {
+		e.Set(reflect.New(e.Type().Elem()))
+		e = e.Elem()
+	}
+	switch e.Kind() {
+	case reflect.String:
+		e.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return nil, err
+		}
+		e.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		e.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		i, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		e.SetUint(i)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return nil, err
+		}
+		e.SetFloat(f)
+	default:
+		return nil, fmt.Errorf("unsupported type %s", field.FieldType)
+	}
+	return v.Elem().Interface(), nil
+}
+
+func badRequest(err error) error {
+	return routing.NewHTTPError(http.StatusBadRequest, err.Error()).SetInternal(err)
+}
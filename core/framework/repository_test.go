@@ -0,0 +1,235 @@
+package framework
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"goplugins/core/framework/database"
+	"goplugins/core/routing"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type widget struct {
+	Model
+	Name   string `json:"name"`
+	Price  int    `json:"price"`
+	Active bool   `json:"active"`
+}
+
+func newTestDB(t *testing.T) *database.DB {
+	db, err := database.Connect("sqlite3", fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name()), 1)
+	require.NoError(t, err)
+	require.NoError(t, RegisterCallbacks(db))
+	return db
+}
+
+func newWidgetRepository(t *testing.T) *Repository {
+	db := newTestDB(t)
+	require.NoError(t, db.AutoMigrate(&widget{}))
+
+	created := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 5; i++ {
+		w := &widget{Name: fmt.Sprintf("widget-%d", i), Price: i * 10, Active: i%2 == 0}
+		w.CreatedAt = created.Add(time.Duration(i) * time.Hour)
+		require.NoError(t, db.Create(w).Error)
+	}
+
+	return NewRepository(db, &widget{},
+		WithSortable("name", "price"),
+		WithFilterable("name", "price", "active"),
+		WithLimits(2, 3),
+	)
+}
+
+func newContext(method, target string) routing.Context {
+	req := httptest.NewRequest(method, target, nil)
+	return routing.New().NewContext(req, httptest.NewRecorder())
+}
+
+func listParams(t *testing.T, target string) ListParams {
+	params, err := ParseListParams(newContext(http.MethodGet, target))
+	require.NoError(t, err)
+	return params
+}
+
+func widgetNames(items []*widget) []string {
+	names := make([]string, len(items))
+	for i, w := range items {
+		names[i] = w.Name
+	}
+	return names
+}
+
+func TestRepositoryCRUD(t *testing.T) {
+	repo := newWidgetRepository(t)
+
+	w := &widget{Name: "crud"}
+	require.NoError(t, repo.Create(w))
+
+	found := &widget{}
+	require.NoError(t, repo.Find(w.ID, found))
+	assert.Equal(t, "crud", found.Name)
+
+	found.Price = 42
+	require.NoError(t, repo.Update(found))
+	require.NoError(t, repo.Find(w.ID, found))
+	assert.Equal(t, 42, found.Price)
+
+	require.NoError(t, repo.DeleteByID(w.ID))
+	assert.True(t, errors.Is(repo.Find(w.ID, found), database.ErrRecordNotFound))
+	assert.True(t, errors.Is(repo.DeleteByID(w.ID), database.ErrRecordNotFound))
+}
+
+func TestRepositoryListOffset(t *testing.T) {
+	repo := newWidgetRepository(t)
+
+	var items []*widget
+	page, err := repo.List(listParams(t, "/widgets?sort=-price&offset=1"), &items)
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), page.Total)
+	assert.Equal(t, 2, page.Limit)
+	assert.Equal(t, []string{"widget-3", "widget-2"}, widgetNames(items))
+	assert.Equal(t, "/widgets?offset=3&sort=-price", page.Links.Next)
+	assert.Equal(t, "/widgets?offset=0&sort=-price", page.Links.Prev)
+	assert.NotEmpty(t, page.NextCursor)
+
+	// limit is capped by the repository maximum
+	items = nil
+	page, err = repo.List(listParams(t, "/widgets?limit=50"), &items)
+	require.NoError(t, err)
+	assert.Equal(t, 3, page.Limit)
+	assert.Equal(t, []string{"widget-0", "widget-1", "widget-2"}, widgetNames(items))
+}
+
+func TestRepositoryListCursor(t *testing.T) {
+	repo := newWidgetRepository(t)
+
+	var names []string
+	target := "/widgets?sort=-price"
+	for target != "" {
+		var items []*widget
+		page, err := repo.List(listParams(t, target), &items)
+		require.NoError(t, err)
+		names = append(names, widgetNames(items)...)
+		if page.NextCursor == "" {
+			assert.Empty(t, page.Links.Next)
+			break
+		}
+		target = "/widgets?sort=-price&cursor=" + page.NextCursor
+	}
+	assert.Equal(t, []string{"widget-4", "widget-3", "widget-2", "widget-1", "widget-0"}, names)
+
+	// a cursor is bound to the ordering it was issued for
+	var items []*widget
+	page, err := repo.List(listParams(t, "/widgets?sort=-price"), &items)
+	require.NoError(t, err)
+	_, err = repo.List(listParams(t, "/widgets?sort=name&cursor="+page.NextCursor), &items)
+	assert.True(t, errors.Is(err, ErrInvalidCursor))
+}
+
+type gadget struct {
+	Model
+	Name   string     `json:"name"`
+	SoldAt *time.Time `json:"soldAt"`
+}
+
+func TestRepositoryListCursorNulls(t *testing.T) {
+	db := newTestDB(t)
+	require.NoError(t, db.AutoMigrate(&gadget{}))
+	sold := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, name := range []string{"unsold-a", "sold-1", "unsold-b", "sold-2", "sold-3", "unsold-c"} {
+		g := &gadget{Name: name}
+		if name[0] == 's' {
+			at := sold.Add(time.Duration(i) * time.Hour)
+			g.SoldAt = &at
+		}
+		require.NoError(t, db.Create(g).Error)
+	}
+	repo := NewRepository(db, &gadget{}, WithSortable("soldAt"), WithLimits(2, 2))
+
+	list := func(sort string) []string {
+		var names []string
+		target := "/gadgets?sort=" + sort
+		for i := 0; i < 10; i++ {
+			var items []*gadget
+			page, err := repo.List(listParams(t, target), &items)
+			require.NoError(t, err)
+			for _, g := range items {
+				names = append(names, g.Name)
+			}
+			if page.NextCursor == "" {
+				return names
+			}
+			target = "/gadgets?sort=" + sort + "&cursor=" + page.NextCursor
+		}
+		t.Fatal("paging did not end")
+		return nil
+	}
+
+	// NULLs sort after all values, pages end on both sides of them.
+	names := list("soldAt")
+	require.Len(t, names, 6)
+	assert.Equal(t, []string{"sold-1", "sold-2", "sold-3"}, names[:3])
+	assert.ElementsMatch(t, []string{"unsold-a", "unsold-b", "unsold-c"}, names[3:])
+
+	names = list("-soldAt")
+	require.Len(t, names, 6)
+	assert.ElementsMatch(t, []string{"unsold-a", "unsold-b", "unsold-c"}, names[:3])
+	assert.Equal(t, []string{"sold-3", "sold-2", "sold-1"}, names[3:])
+}
+
+func TestRepositoryListFilter(t *testing.T) {
+	repo := newWidgetRepository(t)
+
+	var items []*widget
+	page, err := repo.List(listParams(t, "/widgets?filter[active]=true&filter[price][gte]=20"), &items)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), page.Total)
+	assert.Equal(t, []string{"widget-2", "widget-4"}, widgetNames(items))
+
+	items = nil
+	_, err = repo.List(listParams(t, "/widgets?filter[name][in]=widget-1,widget-3"), &items)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"widget-1", "widget-3"}, widgetNames(items))
+
+	_, err = repo.List(listParams(t, "/widgets?filter[price]=cheap"), &items)
+	assert.True(t, errors.Is(err, ErrInvalidFilter))
+	_, err = repo.List(listParams(t, "/widgets?filter[id]=x"), &items)
+	assert.True(t, errors.Is(err, ErrInvalidFilter))
+	_, err = repo.List(listParams(t, "/widgets?sort=active"), &items)
+	assert.True(t, errors.Is(err, ErrInvalidSort))
+	he, ok := err.(*routing.HTTPError)
+	require.True(t, ok)
+	assert.Equal(t, http.StatusBadRequest, he.Code)
+}
+
+func TestRepositoryListEmpty(t *testing.T) {
+	repo := newWidgetRepository(t)
+
+	var items []*widget
+	page, err := repo.List(listParams(t, "/widgets?filter[name]=none"), &items)
+	require.NoError(t, err)
+
+	b, err := json.Marshal(page)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"items":[],"total":0,"limit":2,"offset":0,"links":{"self":"/widgets?filter%5Bname%5D=none"}}`, string(b))
+}
+
+func TestParseListParams(t *testing.T) {
+	params := listParams(t, "/?limit=5&offset=10&sort=-createdAt,+name&filter[price][lt]=3")
+	assert.Equal(t, 5, params.Limit)
+	assert.Equal(t, 10, params.Offset)
+	assert.Equal(t, []SortField{{Field: "createdAt", Desc: true}, {Field: "name"}}, params.Sort)
+	assert.Equal(t, []Filter{{Field: "price", Operator: OpLt, Value: "3"}}, params.Filters)
+
+	for _, target := range []string{"/?limit=x", "/?offset=-1", "/?filter[a]b=1", "/?filter[]=1"} {
+		_, err := ParseListParams(newContext(http.MethodGet, target))
+		assert.Error(t, err, target)
+	}
+}
@@ -0,0 +1,125 @@
+package framework
+
+import (
+	"fmt"
+	"goplugins/core/framework/database"
+	"sync"
+	"time"
+)
+
+type (
+	// SeedFunc fills the database with data, e.g. default records or demo
+	// content.
+	SeedFunc func(db *database.DB) error
+
+	seeder struct {
+		name string
+		fn   SeedFunc
+	}
+
+	// seederRegistry holds the seeders in the order they are registered.
+	seederRegistry struct {
+		mu      sync.Mutex
+		seeders []seeder
+	}
+
+	// seedRun records a seeder which has been run.
+	seedRun struct {
+		Name  string `gorm:"primarykey"`
+		RanAt time.Time
+	}
+)
+
+var seeders = &seederRegistry{}
+
+// TableName returns the name of the database table
+func (seedRun) TableName() string {
+	return "seeds"
+}
+
+// RegisterSeeder registers a named seeder. Services register their seeders
+// when they are added, plugins within their Install hook. Seeders run in the
+// order they are registered. It panics if the name is already taken.
+func RegisterSeeder(name string, fn SeedFunc) {
+	seeders.Register(name, fn)
+}
+
+// Seeders returns the names of all registered seeders.
+func Seeders() []string {
+	return seeders.Names()
+}
+
+func (r *seederRegistry) Register(name string, fn SeedFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, s := range r.seeders {
+		if s.name == name {
+			panic(fmt.Sprintf("framework: seeder %q already registered", name))
+		}
+	}
+	r.seeders = append(r.seeders, seeder{name: name, fn: fn})
+}
+
+func (r *seederRegistry) Names() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	names := make([]string, len(r.seeders))
+	for i, s := range r.seeders {
+		names[i] = s.name
+	}
+	return names
+}
+
+// Run runs the named seeders, or all seeders if no names are given. Every
+// seeder is run once per database; the runs are recorded in the "seeds"
+// table. Delete a row to run a seeder again.
+func (r *seederRegistry) Run(db *database.DB, names ...string) error {
+	r.mu.Lock()
+	list := make([]seeder, len(r.seeders))
+	copy(list, r.seeders)
+	r.mu.Unlock()
+
+	if len(names) > 0 {
+		selected := make([]seeder, 0, len(names))
+		for _, name := range names {
+			found := false
+			for _, s := range list {
+				if s.name == name {
+					selected = append(selected, s)
+					found = true
+					break
+				}
+			}
+			if !found {
+				return fmt.Errorf("framework: unknown seeder %q", name)
+			}
+		}
+		list = selected
+	}
+
+	if err := db.AutoMigrate(&seedRun{}); err != nil {
+		return err
+	}
+
+	for _, s := range list {
+		var count int64
+		if err := db.Model(&seedRun{}).Where("name = ?", s.name).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			continue
+		}
+		// The seeder is recorded in the same transaction, so it's run again
+		// if either fails.
+		err := db.Transaction(func(tx *database.DB) error {
+			if err := s.fn(tx); err != nil {
+				return fmt.Errorf("framework: seeder %q failed: %w", s.name, err)
+			}
+			return tx.Create(&seedRun{Name: s.name, RanAt: time.Now()}).Error
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
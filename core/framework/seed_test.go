@@ -0,0 +1,44 @@
+package framework
+
+import (
+	"errors"
+	"goplugins/core/framework/database"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSeeders(t *testing.T) {
+	db := newTestDB(t)
+	r := &seederRegistry{}
+
+	var runs []string
+	seed := func(name string) SeedFunc {
+		return func(*database.DB) error {
+			runs = append(runs, name)
+			return nil
+		}
+	}
+	r.Register("first", seed("first"))
+	r.Register("second", seed("second"))
+	assert.Equal(t, []string{"first", "second"}, r.Names())
+	assert.Panics(t, func() { r.Register("first", seed("first")) })
+
+	require.NoError(t, r.Run(db, "second"))
+	require.NoError(t, r.Run(db))
+	require.NoError(t, r.Run(db))
+	assert.Equal(t, []string{"second", "first"}, runs)
+
+	assert.Error(t, r.Run(db, "unknown"))
+
+	// Changes of failing seeders are rolled back.
+	r.Register("failing", func(tx *database.DB) error {
+		require.NoError(t, tx.Create(&seedRun{Name: "partial"}).Error)
+		return errors.New("boom")
+	})
+	assert.Error(t, r.Run(db))
+	var count int64
+	require.NoError(t, db.Model(&seedRun{}).Where("name IN ?", []string{"failing", "partial"}).Count(&count).Error)
+	assert.Equal(t, int64(0), count)
+}
@@ -0,0 +1,124 @@
+package framework
+
+import (
+	"fmt"
+	"goplugins/core/framework/config"
+	"goplugins/core/framework/database"
+	"goplugins/core/routing"
+	"net"
+	"net/http"
+	"strings"
+)
+
+type (
+	// TenantResolver returns the tenant named by a request, or "" if the
+	// request does not name one.
+	TenantResolver func(c routing.Context) string
+
+	// TenantConfig defines the config for the ResolveTenant middleware.
+	TenantConfig struct {
+		// Resolvers are asked in order until one returns a tenant.
+		Resolvers []TenantResolver
+		// Optional lets requests without tenant pass. Their statements on
+		// tenanted models fail with database.ErrNoTenant.
+		Optional bool
+	}
+)
+
+// TenantFromHost resolves the tenant from the subdomain of the request host,
+// e.g. "acme" for "acme.example.com" with domain "example.com". If domain is
+// empty the first label of hosts with at least three labels is used.
+func TenantFromHost(domain string) TenantResolver {
+	domain = strings.ToLower(strings.Trim(domain, "."))
+	return func(c routing.Context) string {
+		host := c.Request().Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		host = strings.ToLower(strings.TrimSuffix(host, "."))
+
+		if domain != "" {
+			sub := strings.TrimSuffix(host, "."+domain)
+			if sub == host || strings.Contains(sub, ".") {
+				return ""
+			}
+			return sub
+		}
+		labels := strings.Split(host, ".")
+		if len(labels) < 3 {
+			return ""
+		}
+		return labels[0]
+	}
+}
+
+// TenantFromHeader resolves the tenant from the named request header.
+func TenantFromHeader(name string) TenantResolver {
+	return func(c routing.Context) string {
+		return c.Request().Header.Get(name)
+	}
+}
+
+// TenantFromPath resolves the tenant from the named path parameter, e.g.
+// "tenant" for routes like "/:tenant/users".
+func TenantFromPath(param string) TenantResolver {
+	return func(c routing.Context) string {
+		return c.Param(param)
+	}
+}
+
+// ResolveTenant returns a middleware which resolves the tenant of the request
+// and stores it using SetTenant. Requests naming an invalid tenant are
+// rejected with 400, as are requests without tenant unless config.Optional
+// is set.
+func ResolveTenant(config TenantConfig) routing.MiddlewareFunc {
+	return func(next routing.HandlerFunc) routing.HandlerFunc {
+		return func(c routing.Context) error {
+			tenant := ""
+			for _, resolve := range config.Resolvers {
+				if tenant = resolve(c); tenant != "" {
+					break
+				}
+			}
+
+			switch {
+			case tenant == "":
+				if !config.Optional {
+					return routing.NewHTTPError(http.StatusBadRequest, "missing tenant")
+				}
+			case !database.ValidTenant(tenant):
+				return routing.NewHTTPError(http.StatusBadRequest, "invalid tenant").SetInternal(database.ErrInvalidTenant)
+			default:
+				SetTenant(c, tenant)
+			}
+			return next(c)
+		}
+	}
+}
+
+// tenancyFromConfig returns the isolation mode and the middleware config
+// selected by cfg.
+func tenancyFromConfig(cfg config.Tenancy) (database.TenantMode, TenantConfig, error) {
+	var mode database.TenantMode
+	switch cfg.Mode {
+	case "shared":
+		mode = database.SharedSchema
+	case "schema":
+		mode = database.SchemaPerTenant
+	default:
+		return 0, TenantConfig{}, fmt.Errorf("framework: unknown tenancy mode %q", cfg.Mode)
+	}
+
+	var resolver TenantResolver
+	switch cfg.Source {
+	case "host":
+		resolver = TenantFromHost(cfg.Domain)
+	case "header":
+		resolver = TenantFromHeader(cfg.Header)
+	case "path":
+		resolver = TenantFromPath(cfg.Param)
+	default:
+		return 0, TenantConfig{}, fmt.Errorf("framework: unknown tenancy source %q", cfg.Source)
+	}
+	return mode, TenantConfig{Resolvers: []TenantResolver{resolver}, Optional: !cfg.Required}, nil
+}
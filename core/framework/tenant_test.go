@@ -0,0 +1,120 @@
+package framework
+
+import (
+	"goplugins/core/framework/config"
+	"goplugins/core/framework/database"
+	"goplugins/core/routing"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type invoice struct {
+	Model
+	Tenanted
+	Number string `json:"number"`
+}
+
+func serveTenant(t *testing.T, config TenantConfig, path, target string, header http.Header, host string) (int, string) {
+	mux := routing.New()
+	mux.Use(ResolveTenant(config))
+	var tenant string
+	mux.GET(path, func(c routing.Context) error {
+		if v, ok := c.Get(ContextKeyTenant).(string); ok {
+			tenant = v
+			fromCtx, _ := database.TenantFromContext(c.Request().Context())
+			assert.Equal(t, v, fromCtx)
+		}
+		return c.NoContent(http.StatusNoContent)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	if host != "" {
+		req.Host = host
+	}
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	return rec.Code, tenant
+}
+
+func TestResolveTenant(t *testing.T) {
+	header := TenantConfig{Resolvers: []TenantResolver{TenantFromHeader("X-Tenant-ID")}}
+	code, tenant := serveTenant(t, header, "/", "/", http.Header{"X-Tenant-Id": {"acme"}}, "")
+	assert.Equal(t, http.StatusNoContent, code)
+	assert.Equal(t, "acme", tenant)
+
+	code, _ = serveTenant(t, header, "/", "/", nil, "")
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _ = serveTenant(t, header, "/", "/", http.Header{"X-Tenant-Id": {"ACME;drop"}}, "")
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	header.Optional = true
+	code, tenant = serveTenant(t, header, "/", "/", nil, "")
+	assert.Equal(t, http.StatusNoContent, code)
+	assert.Empty(t, tenant)
+
+	path := TenantConfig{Resolvers: []TenantResolver{TenantFromPath("tenant")}}
+	_, tenant = serveTenant(t, path, "/:tenant/invoices", "/globex/invoices", nil, "")
+	assert.Equal(t, "globex", tenant)
+}
+
+func TestTenantFromHost(t *testing.T) {
+	tests := []struct {
+		domain, host, tenant string
+	}{
+		{"example.com", "acme.example.com", "acme"},
+		{"example.com", "ACME.example.com:8080", "acme"},
+		{"example.com", "example.com", ""},
+		{"example.com", "a.b.example.com", ""},
+		{"example.com", "acme.example.org", ""},
+		{"", "acme.example.com", "acme"},
+		{"", "localhost:3000", ""},
+	}
+	for _, tt := range tests {
+		c := newContext(http.MethodGet, "/")
+		c.Request().Host = tt.host
+		assert.Equal(t, tt.tenant, TenantFromHost(tt.domain)(c), tt.host)
+	}
+}
+
+func TestTenantedRepository(t *testing.T) {
+	db := newTestDB(t)
+	require.NoError(t, db.EnableTenancy(database.SharedSchema))
+	require.NoError(t, db.AutoMigrate(&invoice{}))
+	repo := NewRepository(db, &invoice{})
+
+	acme := repo.WithContext(database.WithTenant(newContext(http.MethodGet, "/").Request().Context(), "acme"))
+	globex := repo.WithContext(database.WithTenant(newContext(http.MethodGet, "/").Request().Context(), "globex"))
+	a := &invoice{Number: "A-1"}
+	require.NoError(t, acme.Create(a))
+	require.NoError(t, globex.Create(&invoice{Number: "G-1"}))
+	assert.Equal(t, "acme", a.TenantID)
+
+	var items []*invoice
+	page, err := acme.List(listParams(t, "/invoices"), &items)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), page.Total)
+	require.Len(t, items, 1)
+	assert.Equal(t, "A-1", items[0].Number)
+
+	assert.Error(t, globex.Find(a.ID, &invoice{}))
+}
+
+func TestTenancyFromConfig(t *testing.T) {
+	mode, cfg, err := tenancyFromConfig(config.Tenancy{Mode: "schema", Source: "host", Required: true})
+	require.NoError(t, err)
+	assert.Equal(t, database.SchemaPerTenant, mode)
+	assert.Len(t, cfg.Resolvers, 1)
+	assert.False(t, cfg.Optional)
+
+	_, _, err = tenancyFromConfig(config.Tenancy{Mode: "shared", Source: "cookie"})
+	assert.Error(t, err)
+	_, _, err = tenancyFromConfig(config.Tenancy{Mode: "isolated", Source: "header"})
+	assert.Error(t, err)
+}
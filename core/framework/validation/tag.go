@@ -0,0 +1,35 @@
+package validation
+
+import "strings"
+
+// Rule is a single validator of a validation tag, e.g. "gte=1".
+type Rule struct {
+	Type  ValidatorType
+	Value string
+}
+
+// ParseTag parses a validation tag, e.g. for generating API documentation.
+// It returns the alternatives of rules applying to the value, one of which
+// has to be met completely, the tag applying to the keys of maps, and the tag
+// applying to the elements of slices, arrays, maps and pointers.
+//
+//	rules, keys, elements, err := validation.ParseTag("empty=false > gte=1 & lte=10")
+//	// rules: [[{empty false}]], keys: "", elements: "gte=1 & lte=10"
+func ParseTag(tag string) (rules [][]Rule, keys string, elements string, err error) {
+	keys, values, elements, errField := splitValidators(tag)
+	if errField != nil {
+		return nil, "", "", errField
+	}
+	validatorsOr, errField := parseValidators(values)
+	if errField != nil {
+		return nil, "", "", errField
+	}
+	for _, validatorsAnd := range validatorsOr {
+		and := make([]Rule, len(validatorsAnd))
+		for i, v := range validatorsAnd {
+			and[i] = Rule{Type: v.Type, Value: v.Value}
+		}
+		rules = append(rules, and)
+	}
+	return rules, strings.TrimSpace(keys), strings.TrimSpace(elements), nil
+}
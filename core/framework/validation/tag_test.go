@@ -0,0 +1,34 @@
+package validation
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseTag(t *testing.T) {
+	rules, keys, elements, err := ParseTag("empty=false [format=alpha] > gte=1 & lte=10 | eq=0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(rules, [][]Rule{{{ValidatorEmpty, "false"}}}) {
+		t.Errorf("unexpected rules %v", rules)
+	}
+	if keys != "format=alpha" {
+		t.Errorf("unexpected keys %q", keys)
+	}
+	if elements != "gte=1 & lte=10 | eq=0" {
+		t.Errorf("unexpected elements %q", elements)
+	}
+
+	rules, _, _, err = ParseTag(elements)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(rules, [][]Rule{{{ValidatorGte, "1"}, {ValidatorLte, "10"}}, {{ValidatorEq, "0"}}}) {
+		t.Errorf("unexpected rules %v", rules)
+	}
+
+	if _, _, _, err = ParseTag("empty=false >"); err == nil {
+		t.Errorf("expected syntax error")
+	}
+}
@@ -6,10 +6,17 @@ import (
 	"encoding/xml"
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
+
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/protobuf/proto"
 )
 
 type (
@@ -19,7 +26,52 @@ type (
 	}
 
 	// DefaultBinder is the default implementation of the Binder interface.
-	DefaultBinder struct{}
+	// Bodies are decoded by the decoder registered for their media type, see
+	// `RegisterDecoder()`.
+	DefaultBinder struct {
+		// StrictJSON rejects JSON bodies with fields unknown to the bound
+		// struct.
+		StrictJSON bool
+
+		// TimeLayouts are tried in order to bind time.Time fields, a
+		// `time_format` tag sets the layout of a field. By default RFC 3339 is
+		// used.
+		TimeLayouts []string
+
+		once     sync.Once
+		decoders map[string]BodyDecoder
+	}
+
+	// BodyDecoder decodes request bodies of a media type into i, see
+	// `DefaultBinder#RegisterDecoder()`. Errors are returned as they are, so
+	// decoders should return an *HTTPError.
+	BodyDecoder interface {
+		Decode(c Context, i interface{}) error
+	}
+
+	// BodyDecoderFunc adapts a function to a BodyDecoder.
+	BodyDecoderFunc func(c Context, i interface{}) error
+
+	// BindingError is returned for values which can't be bound to a field,
+	// Field is its full path like "items[0].price".
+	BindingError struct {
+		Field string
+		Value string
+		Err   error
+	}
+
+	// bindNode is a segment of the paths of bound keys.
+	bindNode struct {
+		values   []string
+		children map[string]*bindNode
+		keys     []string
+	}
+
+	// bindState is shared by the binding of the keys of a source.
+	bindState struct {
+		tag      string
+		elements int
+	}
 
 	// BindUnmarshaler is the interface used to wrap the UnmarshalParam method.
 	// Types that don't implement this, but do implement encoding.TextUnmarshaler
@@ -30,6 +82,59 @@ type (
 	}
 )
 
+const (
+	// MaxBindIndex limits the indexes of slices bound from keys like
+	// "items[0][sku]".
+	MaxBindIndex = 1000
+	// MaxBindElements limits the total number of slice elements allocated
+	// for such keys per bound source, e.g. the query, so sparse nested
+	// indexes cannot multiply.
+	MaxBindElements = 10000
+)
+
+var (
+	timeType            = reflect.TypeOf(time.Time{})
+	bindUnmarshalerType = reflect.TypeOf((*BindUnmarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// Decode implements `BodyDecoder#Decode()`.
+func (f BodyDecoderFunc) Decode(c Context, i interface{}) error {
+	return f(c, i)
+}
+
+// RegisterDecoder registers the decoder of request bodies of mediaType, e.g.
+// "application/yaml", replacing a decoder registered before. JSON, XML, forms,
+// MessagePack and protobuf are registered by default.
+func (b *DefaultBinder) RegisterDecoder(mediaType string, d BodyDecoder) {
+	b.once.Do(b.registerDefaultDecoders)
+	b.decoders[strings.ToLower(mediaType)] = d
+}
+
+// RegisterDecoder registers the decoder of request bodies of mediaType with
+// the binder, see `DefaultBinder#RegisterDecoder()`. It panics if the binder
+// doesn't support decoders.
+func (m *Mux) RegisterDecoder(mediaType string, d BodyDecoder) {
+	r, ok := m.Binder.(interface {
+		RegisterDecoder(mediaType string, d BodyDecoder)
+	})
+	if !ok {
+		panic("routing: binder does not support decoders")
+	}
+	r.RegisterDecoder(mediaType, d)
+}
+
+func (b *DefaultBinder) registerDefaultDecoders() {
+	b.decoders = map[string]BodyDecoder{}
+	b.decoders[MIMEApplicationJSON] = BodyDecoderFunc(b.decodeJSON)
+	b.decoders[MIMEApplicationXML] = BodyDecoderFunc(decodeXML)
+	b.decoders[MIMETextXML] = BodyDecoderFunc(decodeXML)
+	b.decoders[MIMEApplicationForm] = BodyDecoderFunc(b.decodeForm)
+	b.decoders[MIMEMultipartForm] = BodyDecoderFunc(b.decodeForm)
+	b.decoders[MIMEApplicationMsgpack] = BodyDecoderFunc(decodeMsgpack)
+	b.decoders[MIMEApplicationProtobuf] = BodyDecoderFunc(decodeProtobuf)
+}
+
 // Bind implements the `Binder#Bind` function.
 func (b *DefaultBinder) Bind(i interface{}, c Context) (err error) {
 	req := c.Request()
@@ -46,43 +151,116 @@ func (b *DefaultBinder) Bind(i interface{}, c Context) (err error) {
 	if err = b.bindData(i, c.QueryParams(), "query"); err != nil {
 		return NewHTTPError(http.StatusBadRequest, err.Error()).SetInternal(err)
 	}
+	if err = b.bindHeaders(i, req.Header); err != nil {
+		return NewHTTPError(http.StatusBadRequest, err.Error()).SetInternal(err)
+	}
 	if req.ContentLength == 0 {
 		return
 	}
 	ctype := req.Header.Get(HeaderContentType)
-	switch {
-	case strings.HasPrefix(ctype, MIMEApplicationJSON):
-		if err = json.NewDecoder(req.Body).Decode(i); err != nil {
-			if ute, ok := err.(*json.UnmarshalTypeError); ok {
-				return NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Unmarshal type error: expected=%v, got=%v, field=%v, offset=%v", ute.Type, ute.Value, ute.Field, ute.Offset)).SetInternal(err)
-			} else if se, ok := err.(*json.SyntaxError); ok {
-				return NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Syntax error: offset=%v, error=%v", se.Offset, se.Error())).SetInternal(err)
-			}
-			return NewHTTPError(http.StatusBadRequest, err.Error()).SetInternal(err)
-		}
-	case strings.HasPrefix(ctype, MIMEApplicationXML), strings.HasPrefix(ctype, MIMETextXML):
-		if err = xml.NewDecoder(req.Body).Decode(i); err != nil {
-			if ute, ok := err.(*xml.UnsupportedTypeError); ok {
-				return NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Unsupported type error: type=%v, error=%v", ute.Type, ute.Error())).SetInternal(err)
-			} else if se, ok := err.(*xml.SyntaxError); ok {
-				return NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Syntax error: line=%v, error=%v", se.Line, se.Error())).SetInternal(err)
-			}
-			return NewHTTPError(http.StatusBadRequest, err.Error()).SetInternal(err)
+	if i := strings.IndexByte(ctype, ';'); i != -1 {
+		ctype = ctype[:i]
+	}
+	b.once.Do(b.registerDefaultDecoders)
+	d, ok := b.decoders[strings.ToLower(strings.TrimSpace(ctype))]
+	if !ok {
+		return ErrUnsupportedMediaType
+	}
+	return d.Decode(c, i)
+}
+
+// bindHeaders binds the request headers to the struct fields tagged with
+// `header`. Other targets, e.g. slices bound from JSON, are left alone.
+func (b *DefaultBinder) bindHeaders(i interface{}, header http.Header) error {
+	t := reflect.TypeOf(i)
+	if t == nil || t.Kind() != reflect.Ptr || t.Elem().Kind() != reflect.Struct {
+		return nil
+	}
+	return b.bindData(i, header, "header")
+}
+
+func (b *DefaultBinder) decodeJSON(c Context, i interface{}) error {
+	dec := json.NewDecoder(c.Request().Body)
+	if b.StrictJSON {
+		dec.DisallowUnknownFields()
+	}
+	if err := dec.Decode(i); err != nil {
+		if he, ok := err.(*HTTPError); ok {
+			// Raised while reading the body, e.g. by the BodyLimit middleware.
+			return he
+		} else if ute, ok := err.(*json.UnmarshalTypeError); ok {
+			return NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Unmarshal type error: expected=%v, got=%v, field=%v, offset=%v", ute.Type, ute.Value, ute.Field, ute.Offset)).SetInternal(err)
+		} else if se, ok := err.(*json.SyntaxError); ok {
+			return NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Syntax error: offset=%v, error=%v", se.Offset, se.Error())).SetInternal(err)
 		}
-	case strings.HasPrefix(ctype, MIMEApplicationForm), strings.HasPrefix(ctype, MIMEMultipartForm):
-		params, err := c.FormParams()
-		if err != nil {
-			return NewHTTPError(http.StatusBadRequest, err.Error()).SetInternal(err)
+		return NewHTTPError(http.StatusBadRequest, err.Error()).SetInternal(err)
+	}
+	return nil
+}
+
+func decodeXML(c Context, i interface{}) error {
+	if err := xml.NewDecoder(c.Request().Body).Decode(i); err != nil {
+		if he, ok := err.(*HTTPError); ok {
+			return he
+		} else if ute, ok := err.(*xml.UnsupportedTypeError); ok {
+			return NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Unsupported type error: type=%v, error=%v", ute.Type, ute.Error())).SetInternal(err)
+		} else if se, ok := err.(*xml.SyntaxError); ok {
+			return NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Syntax error: line=%v, error=%v", se.Line, se.Error())).SetInternal(err)
 		}
-		if err = b.bindData(i, params, "form"); err != nil {
-			return NewHTTPError(http.StatusBadRequest, err.Error()).SetInternal(err)
+		return NewHTTPError(http.StatusBadRequest, err.Error()).SetInternal(err)
+	}
+	return nil
+}
+
+func (b *DefaultBinder) decodeForm(c Context, i interface{}) error {
+	params, err := c.FormParams()
+	if he, ok := err.(*HTTPError); ok {
+		return he
+	} else if err != nil {
+		return NewHTTPError(http.StatusBadRequest, err.Error()).SetInternal(err)
+	}
+	if err = b.bindData(i, params, "form"); err != nil {
+		return NewHTTPError(http.StatusBadRequest, err.Error()).SetInternal(err)
+	}
+	return nil
+}
+
+// decodeMsgpack decodes MessagePack bodies, struct fields are named by the
+// `msgpack` tag.
+func decodeMsgpack(c Context, i interface{}) error {
+	if err := msgpack.NewDecoder(c.Request().Body).Decode(i); err != nil {
+		if he, ok := err.(*HTTPError); ok {
+			return he
 		}
-	default:
-		return ErrUnsupportedMediaType
+		return NewHTTPError(http.StatusBadRequest, err.Error()).SetInternal(err)
+	}
+	return nil
+}
+
+// decodeProtobuf decodes protobuf bodies into a proto.Message.
+func decodeProtobuf(c Context, i interface{}) error {
+	msg, ok := i.(proto.Message)
+	if !ok {
+		err := errors.New("binding element must be a proto.Message")
+		return NewHTTPError(http.StatusUnsupportedMediaType, err.Error()).SetInternal(err)
+	}
+	data, err := ioutil.ReadAll(c.Request().Body)
+	if err != nil {
+		if he, ok := err.(*HTTPError); ok {
+			return he
+		}
+		return NewHTTPError(http.StatusBadRequest, err.Error()).SetInternal(err)
 	}
-	return
+	if err = proto.Unmarshal(data, msg); err != nil {
+		return NewHTTPError(http.StatusBadRequest, err.Error()).SetInternal(err)
+	}
+	return nil
 }
 
+// bindData binds data to the struct or map ptr points to. Keys address nested
+// fields with dotted or bracketed paths, e.g. "address.city",
+// "address[city]", "items[0][sku]" or "meta[color]"; "tags[]" is the same as
+// "tags".
 func (b *DefaultBinder) bindData(ptr interface{}, data map[string][]string, tag string) error {
 	if ptr == nil || len(data) == 0 {
 		return nil
@@ -103,71 +281,347 @@ func (b *DefaultBinder) bindData(ptr interface{}, data map[string][]string, tag
 		return errors.New("binding element must be a struct")
 	}
 
+	_, err := b.bindStruct(val, newBindTree(data), &bindState{tag: tag, elements: MaxBindElements}, "")
+	return err
+}
+
+// bindStruct binds the fields of the struct val to the children of n. It
+// reports whether any field was bound; path is the path of val in errors.
+func (b *DefaultBinder) bindStruct(val reflect.Value, n *bindNode, state *bindState, path string) (bool, error) {
+	typ := val.Type()
+	bound := false
 	for i := 0; i < typ.NumField(); i++ {
 		typeField := typ.Field(i)
 		structField := val.Field(i)
 		if !structField.CanSet() {
+			// The exported fields of embedded unexported structs are
+			// settable, like with encoding/json.
+			if typeField.Anonymous && structField.Kind() == reflect.Struct && isNestedStruct(typeField.Type) {
+				ok, err := b.bindStruct(structField, n, state, path)
+				if err != nil {
+					return bound, err
+				}
+				bound = bound || ok
+			}
+			continue
+		}
+		inputFieldName := typeField.Tag.Get(state.tag)
+		if inputFieldName == "-" {
 			continue
 		}
-		structFieldKind := structField.Kind()
-		inputFieldName := typeField.Tag.Get(tag)
 
 		if inputFieldName == "" {
 			inputFieldName = typeField.Name
-			// If tag is nil, we inspect if the field is a struct.
-			if _, ok := structField.Addr().Interface().(BindUnmarshaler); !ok && structFieldKind == reflect.Struct {
-				if err := b.bindData(structField.Addr().Interface(), data, tag); err != nil {
-					return err
+			// If tag is nil, we inspect if the field is a struct. Embedded
+			// structs and structs without nested keys bind the keys of their
+			// parent.
+			if isNestedStruct(typeField.Type) {
+				child := n.lookup(inputFieldName)
+				if typeField.Anonymous || child == nil || len(child.children) == 0 {
+					ok, err := b.bindValue(structField, n, state, path, "")
+					if err != nil {
+						return bound, err
+					}
+					bound = bound || ok
+					continue
 				}
+			} else if state.tag == "header" {
+				// Headers are only bound to tagged fields.
 				continue
 			}
 		}
 
-		inputValue, exists := data[inputFieldName]
-		if !exists {
-			// Go json.Unmarshal supports case insensitive binding.  However the
-			// url params are bound case sensitive which is inconsistent.  To
-			// fix this we must check all of the map values in a
-			// case-insensitive search.
-			for k, v := range data {
-				if strings.EqualFold(k, inputFieldName) {
-					inputValue = v
-					exists = true
-					break
-				}
-			}
-		}
-
-		if !exists {
+		child := n.lookupPath(inputFieldName)
+		if child == nil {
 			continue
 		}
+		ok, err := b.bindValue(structField, child, state, joinPath(path, inputFieldName), typeField.Tag.Get("time_format"))
+		if err != nil {
+			return bound, err
+		}
+		bound = bound || ok
+	}
+	return bound, nil
+}
+
+// bindValue binds n to v and reports whether anything was bound.
+func (b *DefaultBinder) bindValue(v reflect.Value, n *bindNode, state *bindState, path, layout string) (bool, error) {
+	t := v.Type()
 
-		// Call this first, in case we're dealing with an alias to an array type
-		if ok, err := unmarshalField(typeField.Type.Kind(), inputValue[0], structField); ok {
+	// Call this first, in case we're dealing with an alias to an array type
+	if t.Kind() != reflect.Ptr && len(n.values) > 0 {
+		if t == timeType && (layout != "" || len(b.TimeLayouts) > 0) {
+			return true, b.setTimeField(n.values[0], layout, v, path)
+		}
+		if ok, err := unmarshalFieldNonPtr(n.values[0], v); ok {
 			if err != nil {
-				return err
+				return true, &BindingError{Field: path, Value: n.values[0], Err: err}
 			}
-			continue
+			return true, nil
 		}
+	}
 
-		numElems := len(inputValue)
-		if structFieldKind == reflect.Slice && numElems > 0 {
-			sliceOf := structField.Type().Elem().Kind()
-			slice := reflect.MakeSlice(structField.Type(), numElems, numElems)
-			for j := 0; j < numElems; j++ {
-				if err := setWithProperType(sliceOf, inputValue[j], slice.Index(j)); err != nil {
-					return err
-				}
+	switch t.Kind() {
+	case reflect.Ptr:
+		if !v.IsNil() {
+			return b.bindValue(v.Elem(), n, state, path, layout)
+		}
+		// Only allocate the value if anything is bound, e.g. for embedded
+		// pointers to structs.
+		elem := reflect.New(t.Elem())
+		ok, err := b.bindValue(elem.Elem(), n, state, path, layout)
+		if ok {
+			v.Set(elem)
+		}
+		return ok, err
+	case reflect.Struct:
+		return b.bindStruct(v, n, state, path)
+	case reflect.Slice:
+		if len(n.children) > 0 {
+			return b.bindIndexed(v, n, state, path, layout)
+		}
+		if len(n.values) == 0 {
+			return false, nil
+		}
+		slice := reflect.MakeSlice(t, len(n.values), len(n.values))
+		for i, value := range n.values {
+			if _, err := b.bindValue(slice.Index(i), &bindNode{values: []string{value}}, state, indexPath(path, strconv.Itoa(i)), layout); err != nil {
+				return true, err
+			}
+		}
+		v.Set(slice)
+		return true, nil
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String || len(n.children) == 0 {
+			return false, nil
+		}
+		if v.IsNil() {
+			v.Set(reflect.MakeMap(t))
+		}
+		for _, key := range n.keys {
+			elem := reflect.New(t.Elem()).Elem()
+			if _, err := b.bindValue(elem, n.children[key], state, indexPath(path, key), layout); err != nil {
+				return true, err
 			}
-			val.Field(i).Set(slice)
-		} else if err := setWithProperType(typeField.Type.Kind(), inputValue[0], structField); err != nil {
-			return err
+			v.SetMapIndex(reflect.ValueOf(key).Convert(t.Key()), elem)
+		}
+		return true, nil
+	case reflect.Interface:
+		if len(n.values) == 0 || t.NumMethod() > 0 {
+			return false, nil
+		}
+		v.Set(reflect.ValueOf(n.values[0]))
+		return true, nil
+	}
+
+	if len(n.values) == 0 {
+		return false, nil
+	}
+	if err := setWithProperType(t.Kind(), n.values[0], v); err != nil {
+		return true, &BindingError{Field: path, Value: n.values[0], Err: err}
+	}
+	return true, nil
+}
 
+// bindIndexed binds the children of n, indexed like "items[0][sku]", to the
+// slice v.
+func (b *DefaultBinder) bindIndexed(v reflect.Value, n *bindNode, state *bindState, path, layout string) (bool, error) {
+	length := 0
+	indexes := make([]int, len(n.keys))
+	for i, key := range n.keys {
+		index, err := strconv.Atoi(key)
+		if err != nil || index < 0 || index >= MaxBindIndex {
+			return true, &BindingError{Field: indexPath(path, key), Err: errors.New("invalid index")}
+		}
+		indexes[i] = index
+		if index >= length {
+			length = index + 1
+		}
+	}
+	if state.elements -= length; state.elements < 0 {
+		return true, &BindingError{Field: path, Err: errors.New("too many elements")}
+	}
+	slice := reflect.MakeSlice(v.Type(), length, length)
+	for i, key := range n.keys {
+		if _, err := b.bindValue(slice.Index(indexes[i]), n.children[key], state, indexPath(path, key), layout); err != nil {
+			return true, err
+		}
+	}
+	v.Set(slice)
+	return true, nil
+}
+
+// setTimeField parses value with layout, or else the layouts of the binder.
+// Empty values leave the zero time.
+func (b *DefaultBinder) setTimeField(value, layout string, field reflect.Value, path string) error {
+	if value == "" {
+		field.Set(reflect.Zero(field.Type()))
+		return nil
+	}
+	layouts := b.TimeLayouts
+	if layout != "" {
+		layouts = []string{layout}
+	}
+	var err error
+	for _, l := range layouts {
+		var t time.Time
+		if t, err = time.Parse(l, value); err == nil {
+			field.Set(reflect.ValueOf(t))
+			return nil
+		}
+	}
+	return &BindingError{Field: path, Value: value, Err: err}
+}
+
+// Error implements the error interface.
+func (e *BindingError) Error() string {
+	return fmt.Sprintf("invalid value for %s: %v", e.Field, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *BindingError) Unwrap() error {
+	return e.Err
+}
+
+// newBindTree builds the tree of the paths of the keys of data.
+func newBindTree(data map[string][]string) *bindNode {
+	keys := make([]string, 0, len(data))
+	flat := true
+	for k := range data {
+		keys = append(keys, k)
+		flat = flat && k != "" && !isPath(k)
+	}
+	if flat {
+		// Most keys are plain names, which don't need paths.
+		root := &bindNode{children: make(map[string]*bindNode, len(data)), keys: keys}
+		nodes := make([]bindNode, len(keys))
+		for i, k := range keys {
+			nodes[i].values = data[k]
+			root.children[k] = &nodes[i]
+		}
+		return root
+	}
+	// Keep the order of values of equal paths, e.g. "tags" and "tags[]".
+	sort.Strings(keys)
+
+	root := &bindNode{}
+	for _, k := range keys {
+		n := root
+		for _, segment := range splitPath(k) {
+			n = n.child(segment)
+		}
+		n.values = append(n.values, data[k]...)
+	}
+	return root
+}
+
+func (n *bindNode) child(segment string) *bindNode {
+	if c, ok := n.children[segment]; ok {
+		return c
+	}
+	if n.children == nil {
+		n.children = map[string]*bindNode{}
+	}
+	c := &bindNode{}
+	n.children[segment] = c
+	n.keys = append(n.keys, segment)
+	return c
+}
+
+// lookup returns the child named segment. Go json.Unmarshal supports case
+// insensitive binding. However the url params are bound case sensitive which
+// is inconsistent. To fix this we must check all of the children in a
+// case-insensitive search.
+func (n *bindNode) lookup(segment string) *bindNode {
+	if c, ok := n.children[segment]; ok {
+		return c
+	}
+	for _, k := range n.keys {
+		if strings.EqualFold(k, segment) {
+			return n.children[k]
 		}
 	}
 	return nil
 }
 
+// lookupPath returns the descendant at path, e.g. "filter.name".
+func (n *bindNode) lookupPath(path string) *bindNode {
+	if !isPath(path) {
+		return n.lookup(path)
+	}
+	for _, s := range splitPath(path) {
+		if n = n.lookup(s); n == nil {
+			return nil
+		}
+	}
+	return n
+}
+
+// splitPath splits a key like "items[0][sku]" or "items.0.sku" into its
+// segments.
+func splitPath(key string) []string {
+	var segments []string
+	for key != "" {
+		switch key[0] {
+		case '.':
+			key = key[1:]
+			continue
+		case '[':
+			end := strings.IndexByte(key, ']')
+			if end == -1 {
+				return append(segments, key)
+			}
+			// "[]" adds to the values of the parent.
+			if end > 1 {
+				segments = append(segments, key[1:end])
+			}
+			key = key[end+1:]
+			continue
+		}
+		i := strings.IndexAny(key, ".[")
+		if i == -1 {
+			i = len(key)
+		}
+		segments = append(segments, key[:i])
+		key = key[i:]
+	}
+	return segments
+}
+
+// isPath reports whether key has more than one segment.
+func isPath(key string) bool {
+	for i := 0; i < len(key); i++ {
+		if key[i] == '.' || key[i] == '[' {
+			return true
+		}
+	}
+	return false
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+func indexPath(path, key string) string {
+	return path + "[" + key + "]"
+}
+
+// isNestedStruct reports whether fields of type t are bound field by field,
+// i.e. t is a struct or pointer to a struct which doesn't unmarshal itself.
+func isNestedStruct(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || t == timeType {
+		return false
+	}
+	pt := reflect.PtrTo(t)
+	return !pt.Implements(bindUnmarshalerType) && !pt.Implements(textUnmarshalerType)
+}
+
 func setWithProperType(valueKind reflect.Kind, val string, structField reflect.Value) error {
 	// But also call it here, in case we're dealing with an array of BindUnmarshalers
 	if ok, err := unmarshalField(valueKind, val, structField); ok {
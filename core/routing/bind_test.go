@@ -16,6 +16,9 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
 )
 
 type (
@@ -322,6 +325,124 @@ func TestBindUnsupportedMediaType(t *testing.T) {
 	testBindError(assert, strings.NewReader(invalidContent), MIMEApplicationJSON, &json.SyntaxError{})
 }
 
+func TestBindRegisteredMediaTypes(t *testing.T) {
+	assert := assert.New(t)
+
+	testBindOkay(assert, strings.NewReader(userJSON), MIMEApplicationJSONCharsetUTF8)
+	testBindError(assert, strings.NewReader("id: 1"), "application/yaml", nil)
+
+	e := New()
+	e.RegisterDecoder("application/yaml", BodyDecoderFunc(func(c Context, i interface{}) error {
+		i.(*user).ID, i.(*user).Name = 1, "Jon Snow"
+		return nil
+	}))
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("id: 1"))
+	req.Header.Set(HeaderContentType, "Application/YAML; charset=utf-8")
+	u := new(user)
+	if assert.NoError(e.NewContext(req, httptest.NewRecorder()).Bind(u)) {
+		assert.Equal(&user{1, "Jon Snow"}, u)
+	}
+
+	e.Binder = struct{ Binder }{e.Binder}
+	assert.Panics(func() {
+		e.RegisterDecoder("application/yaml", nil)
+	})
+}
+
+func TestBindStrictJSON(t *testing.T) {
+	e := New()
+	e.Binder = &DefaultBinder{StrictJSON: true}
+	body := `{"id":1,"name":"Jon Snow","admin":true}`
+
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	req.Header.Set(HeaderContentType, MIMEApplicationJSON)
+	err := e.NewContext(req, httptest.NewRecorder()).Bind(new(user))
+	if assert.IsType(t, new(HTTPError), err) {
+		assert.Equal(t, http.StatusBadRequest, err.(*HTTPError).Code)
+		assert.Contains(t, err.Error(), `unknown field "admin"`)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(userJSON))
+	req.Header.Set(HeaderContentType, MIMEApplicationJSON)
+	assert.NoError(t, e.NewContext(req, httptest.NewRecorder()).Bind(new(user)))
+}
+
+func TestBindMsgpack(t *testing.T) {
+	assert := assert.New(t)
+
+	body, err := msgpack.Marshal(map[string]interface{}{"ID": 1, "Name": "Jon Snow"})
+	if assert.NoError(err) {
+		testBindOkay(assert, bytes.NewReader(body), MIMEApplicationMsgpack)
+	}
+	e := New()
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(invalidContent))
+	req.Header.Set(HeaderContentType, MIMEApplicationMsgpack)
+	err = e.NewContext(req, httptest.NewRecorder()).Bind(new(user))
+	if assert.IsType(new(HTTPError), err) {
+		assert.Equal(http.StatusBadRequest, err.(*HTTPError).Code)
+	}
+}
+
+func TestBindProtobuf(t *testing.T) {
+	assert := assert.New(t)
+	e := New()
+
+	body, err := proto.Marshal(wrapperspb.String("Jon Snow"))
+	assert.NoError(err)
+	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+	req.Header.Set(HeaderContentType, MIMEApplicationProtobuf)
+	v := new(wrapperspb.StringValue)
+	if assert.NoError(e.NewContext(req, httptest.NewRecorder()).Bind(v)) {
+		assert.Equal("Jon Snow", v.Value)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(invalidContent))
+	req.Header.Set(HeaderContentType, MIMEApplicationProtobuf)
+	err = e.NewContext(req, httptest.NewRecorder()).Bind(new(wrapperspb.StringValue))
+	if assert.IsType(new(HTTPError), err) {
+		assert.Equal(http.StatusBadRequest, err.(*HTTPError).Code)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+	req.Header.Set(HeaderContentType, MIMEApplicationProtobuf)
+	err = e.NewContext(req, httptest.NewRecorder()).Bind(new(user))
+	if assert.IsType(new(HTTPError), err) {
+		assert.Equal(http.StatusUnsupportedMediaType, err.(*HTTPError).Code)
+	}
+}
+
+func TestBindHeader(t *testing.T) {
+	e := New()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Request-ID", "abc")
+	req.Header.Set("X-Page", "2")
+	req.Header.Set("Host", "example.com")
+	result := struct {
+		RequestID string `header:"x-request-id"`
+		Page      int    `header:"X-Page"`
+		Host      string
+	}{}
+	if assert.NoError(t, e.NewContext(req, httptest.NewRecorder()).Bind(&result)) {
+		assert.Equal(t, "abc", result.RequestID)
+		assert.Equal(t, 2, result.Page)
+		assert.Empty(t, result.Host)
+	}
+
+	req.Header.Set("X-Page", "two")
+	err := e.NewContext(req, httptest.NewRecorder()).Bind(&result)
+	if assert.IsType(t, new(HTTPError), err) {
+		assert.Equal(t, http.StatusBadRequest, err.(*HTTPError).Code)
+	}
+
+	// Headers aren't bound to other targets.
+	req = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`[{"id":1}]`))
+	req.Header.Set(HeaderContentType, MIMEApplicationJSON)
+	var users []user
+	if assert.NoError(t, e.NewContext(req, httptest.NewRecorder()).Bind(&users)) {
+		assert.Len(t, users, 1)
+	}
+}
+
 func TestBindbindData(t *testing.T) {
 	assert := assert.New(t)
 	ts := new(bindTestStruct)
@@ -380,6 +501,194 @@ func TestBindParam(t *testing.T) {
 	}
 }
 
+func TestBindNested(t *testing.T) {
+	type (
+		address struct {
+			Street string `form:"street"`
+			City   string `form:"city"`
+		}
+		item struct {
+			SKU      string  `form:"sku"`
+			Quantity int     `form:"qty"`
+			Price    float64 `form:"price"`
+		}
+		Audit struct {
+			CreatedBy string `form:"created_by"`
+		}
+		order struct {
+			*Audit
+			Name     string             `form:"name"`
+			Address  address            `form:"address"`
+			Billing  *address           `form:"billing"`
+			Shipping *address           `form:"shipping"`
+			Items    []item             `form:"items"`
+			Tags     []string           `form:"tags"`
+			Meta     map[string]string  `form:"meta"`
+			Counts   map[string]int     `form:"counts"`
+			Extra    map[string]address `form:"extra"`
+			Filter   string             `form:"filter.name"`
+		}
+	)
+	data := map[string][]string{
+		"name":              {"order"},
+		"created_by":        {"jon"},
+		"address[street]":   {"Main St"},
+		"address.city":      {"Winterfell"},
+		"billing[city]":     {"Castle Black"},
+		"items[0][sku]":     {"A1"},
+		"items[0][qty]":     {"2"},
+		"items[1].sku":      {"B2"},
+		"items[1].price":    {"9.5"},
+		"tags":              {"a"},
+		"tags[]":            {"b"},
+		"meta[color]":       {"red"},
+		"counts.apples":     {"3"},
+		"extra[home][city]": {"Winterfell"},
+		"filter[name]":      {"x"},
+	}
+	o := new(order)
+	if assert.NoError(t, new(DefaultBinder).bindData(o, data, "form")) {
+		assert.Equal(t, "order", o.Name)
+		if assert.NotNil(t, o.Audit) {
+			assert.Equal(t, "jon", o.Audit.CreatedBy)
+		}
+		assert.Equal(t, address{"Main St", "Winterfell"}, o.Address)
+		assert.Equal(t, &address{City: "Castle Black"}, o.Billing)
+		assert.Nil(t, o.Shipping)
+		assert.Equal(t, []item{{SKU: "A1", Quantity: 2}, {SKU: "B2", Price: 9.5}}, o.Items)
+		assert.Equal(t, []string{"a", "b"}, o.Tags)
+		assert.Equal(t, map[string]string{"color": "red"}, o.Meta)
+		assert.Equal(t, map[string]int{"apples": 3}, o.Counts)
+		assert.Equal(t, map[string]address{"home": {City: "Winterfell"}}, o.Extra)
+		assert.Equal(t, "x", o.Filter)
+	}
+
+	// Embedded pointers are only allocated if anything is bound.
+	o = new(order)
+	if assert.NoError(t, new(DefaultBinder).bindData(o, map[string][]string{"name": {"order"}}, "form")) {
+		assert.Nil(t, o.Audit)
+	}
+
+	for key, field := range map[string]string{
+		"items[1][qty]":     "items[1].qty",
+		"counts[apples]":    "counts[apples]",
+		"items[x][qty]":     "items[x]",
+		"items[5000][qty]":  "items[5000]",
+		"address.city.name": "",
+	} {
+		err := new(DefaultBinder).bindData(new(order), map[string][]string{key: {"x"}}, "form")
+		if field == "" {
+			assert.NoError(t, err, key)
+			continue
+		}
+		if be, ok := err.(*BindingError); assert.True(t, ok, key) {
+			assert.Equal(t, field, be.Field)
+		}
+	}
+}
+
+func TestBindSparseIndexes(t *testing.T) {
+	type (
+		sub struct {
+			N int
+		}
+		item struct {
+			Subs []sub
+		}
+	)
+	var query strings.Builder
+	for i := 0; i < MaxBindIndex-1; i++ {
+		if i > 0 {
+			query.WriteByte('&')
+		}
+		query.WriteString("items[" + strconv.Itoa(i) + "][subs][999][n]=1")
+	}
+
+	e := New()
+	req := httptest.NewRequest(http.MethodGet, "/?"+query.String(), nil)
+	c := e.NewContext(req, httptest.NewRecorder())
+	var v struct {
+		Items []item `query:"items"`
+	}
+	err := c.Bind(&v)
+	if he, ok := err.(*HTTPError); assert.True(t, ok, "%v", err) {
+		assert.Equal(t, http.StatusBadRequest, he.Code)
+		var be *BindingError
+		assert.True(t, errors.As(err, &be))
+	}
+	assert.Nil(t, v.Items)
+}
+
+func TestBindNestedUntagged(t *testing.T) {
+	type (
+		page struct {
+			Page int
+			Size int
+		}
+		query struct {
+			page
+			Sort   page
+			Filter struct {
+				Name string
+			}
+		}
+	)
+	q := new(query)
+	data := map[string][]string{"page": {"2"}, "size": {"10"}, "sort.page": {"3"}, "Filter[Name]": {"x"}}
+	if assert.NoError(t, new(DefaultBinder).bindData(q, data, "query")) {
+		assert.Equal(t, page{2, 10}, q.page)
+		assert.Equal(t, page{Page: 3}, q.Sort)
+		assert.Equal(t, "x", q.Filter.Name)
+	}
+}
+
+func TestBindTimeLayouts(t *testing.T) {
+	type event struct {
+		Start time.Time  `query:"start"`
+		End   *time.Time `query:"end"`
+		Day   time.Time  `query:"day" time_format:"2006-01-02"`
+		Empty time.Time  `query:"empty"`
+	}
+	data := map[string][]string{
+		"start": {"2020-07-01T10:00:00Z"},
+		"end":   {"2020-07-01 12:00"},
+		"day":   {"2020-07-02"},
+		"empty": {""},
+	}
+	b := &DefaultBinder{TimeLayouts: []string{time.RFC3339, "2006-01-02 15:04"}}
+	e := new(event)
+	if assert.NoError(t, b.bindData(e, data, "query")) {
+		assert.Equal(t, time.Date(2020, 7, 1, 10, 0, 0, 0, time.UTC), e.Start)
+		assert.Equal(t, time.Date(2020, 7, 1, 12, 0, 0, 0, time.UTC), *e.End)
+		assert.Equal(t, time.Date(2020, 7, 2, 0, 0, 0, 0, time.UTC), e.Day)
+		assert.True(t, e.Empty.IsZero())
+	}
+
+	// RFC 3339 is used by default.
+	err := new(DefaultBinder).bindData(new(event), data, "query")
+	if be, ok := err.(*BindingError); assert.True(t, ok) {
+		assert.Equal(t, "end", be.Field)
+		assert.Equal(t, "2020-07-01 12:00", be.Value)
+	}
+}
+
+func TestBindNestedQuery(t *testing.T) {
+	e := New()
+	req := httptest.NewRequest(http.MethodGet, "/?filter[ids][0]=1&filter[ids][1]=x", nil)
+	c := e.NewContext(req, httptest.NewRecorder())
+	result := struct {
+		Filter struct {
+			IDs []int `query:"ids"`
+		} `query:"filter"`
+	}{}
+	err := c.Bind(&result)
+	if assert.IsType(t, new(HTTPError), err) {
+		assert.Equal(t, http.StatusBadRequest, err.(*HTTPError).Code)
+		assert.Contains(t, err.(*HTTPError).Message, "filter.ids[1]")
+		assert.IsType(t, new(BindingError), err.(*HTTPError).Internal)
+	}
+}
+
 func TestBindUnmarshalTypeError(t *testing.T) {
 	body := bytes.NewBufferString(`{ "id": "text" }`)
 	e := New()
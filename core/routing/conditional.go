@@ -0,0 +1,102 @@
+package routing
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+func (c *context) NotModified(lastModified time.Time, etag string) bool {
+	header := c.response.Header()
+	if !isZeroTime(lastModified) {
+		header.Set(HeaderLastModified, lastModified.UTC().Format(http.TimeFormat))
+	}
+	if etag != "" {
+		header.Set(HeaderETag, etag)
+	}
+
+	req := c.request
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		return false
+	}
+	// If-Modified-Since is ignored if If-None-Match is present.
+	if inm := req.Header.Get(HeaderIfNoneMatch); inm != "" {
+		return etag != "" && matchETag(inm, etag, false)
+	}
+	if ims := req.Header.Get(HeaderIfModifiedSince); ims != "" && !isZeroTime(lastModified) {
+		t, err := http.ParseTime(ims)
+		return err == nil && !lastModified.Truncate(time.Second).After(t)
+	}
+	return false
+}
+
+func (c *context) CheckPreconditions(lastModified time.Time, etag string) error {
+	req := c.request
+	// If-Unmodified-Since is ignored if If-Match is present.
+	if im := req.Header.Get(HeaderIfMatch); im != "" {
+		// Weak entity tags are compared weakly. They are meant for tags derived
+		// from the version of a resource rather than the bytes of the body.
+		if strings.TrimSpace(im) == "*" || etag != "" && matchETag(im, etag, !isWeakETag(etag)) {
+			return nil
+		}
+		return ErrPreconditionFailed
+	}
+	if ius := req.Header.Get(HeaderIfUnmodifiedSince); ius != "" && !isZeroTime(lastModified) {
+		t, err := http.ParseTime(ius)
+		if err == nil && lastModified.Truncate(time.Second).After(t) {
+			return ErrPreconditionFailed
+		}
+	}
+	return nil
+}
+
+// matchETag reports whether etag is in the list of entity tags of an If-Match
+// or If-None-Match header. Weak tags never match using strong comparison.
+func matchETag(list, etag string, strong bool) bool {
+	for {
+		list = strings.TrimLeft(list, " \t,")
+		if list == "" {
+			return false
+		}
+		if list[0] == '*' {
+			return true
+		}
+		tag, rest := scanETag(list)
+		if tag == "" {
+			return false
+		}
+		if strong {
+			if tag == etag && !isWeakETag(tag) {
+				return true
+			}
+		} else if strings.TrimPrefix(tag, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+		list = rest
+	}
+}
+
+// scanETag returns the entity tag at the start of s and the remaining string.
+func scanETag(s string) (etag, rest string) {
+	start := 0
+	if strings.HasPrefix(s, "W/") {
+		start = 2
+	}
+	if len(s)-start < 2 || s[start] != '"' {
+		return "", ""
+	}
+	end := strings.IndexByte(s[start+1:], '"')
+	if end < 0 {
+		return "", ""
+	}
+	end += start + 2
+	return s[:end], s[end:]
+}
+
+func isWeakETag(etag string) bool {
+	return strings.HasPrefix(etag, "W/")
+}
+
+func isZeroTime(t time.Time) bool {
+	return t.IsZero() || t.Equal(time.Unix(0, 0))
+}
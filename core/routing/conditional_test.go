@@ -0,0 +1,93 @@
+package routing
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContextNotModified(t *testing.T) {
+	m := New()
+	modified := time.Date(2020, 1, 1, 12, 0, 0, 500, time.UTC)
+	etag := `"v1"`
+
+	for _, tt := range []struct {
+		method string
+		header string
+		value  string
+		want   bool
+	}{
+		{http.MethodGet, "", "", false},
+		{http.MethodGet, HeaderIfNoneMatch, `"v1"`, true},
+		{http.MethodHead, HeaderIfNoneMatch, `"v1"`, true},
+		{http.MethodGet, HeaderIfNoneMatch, `W/"v1"`, true},
+		{http.MethodGet, HeaderIfNoneMatch, `"v0", "v1"`, true},
+		{http.MethodGet, HeaderIfNoneMatch, `*`, true},
+		{http.MethodGet, HeaderIfNoneMatch, `"v2"`, false},
+		{http.MethodPut, HeaderIfNoneMatch, `"v1"`, false},
+		{http.MethodGet, HeaderIfModifiedSince, modified.Format(http.TimeFormat), true},
+		{http.MethodGet, HeaderIfModifiedSince, modified.Add(time.Hour).Format(http.TimeFormat), true},
+		{http.MethodGet, HeaderIfModifiedSince, modified.Add(-time.Hour).Format(http.TimeFormat), false},
+		{http.MethodGet, HeaderIfModifiedSince, "invalid", false},
+	} {
+		req := httptest.NewRequest(tt.method, "/", nil)
+		if tt.header != "" {
+			req.Header.Set(tt.header, tt.value)
+		}
+		rec := httptest.NewRecorder()
+		c := m.NewContext(req, rec)
+		assert.Equal(t, tt.want, c.NotModified(modified, etag), "%s %s: %s", tt.method, tt.header, tt.value)
+		assert.Equal(t, "Wed, 01 Jan 2020 12:00:00 GMT", rec.Header().Get(HeaderLastModified))
+		assert.Equal(t, etag, rec.Header().Get(HeaderETag))
+	}
+
+	// If-None-Match takes precedence
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(HeaderIfNoneMatch, `"v2"`)
+	req.Header.Set(HeaderIfModifiedSince, modified.Format(http.TimeFormat))
+	c := m.NewContext(req, httptest.NewRecorder())
+	assert.False(t, c.NotModified(modified, etag))
+
+	// No validators
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(HeaderIfModifiedSince, modified.Format(http.TimeFormat))
+	rec := httptest.NewRecorder()
+	c = m.NewContext(req, rec)
+	assert.False(t, c.NotModified(time.Time{}, ""))
+	assert.Equal(t, "", rec.Header().Get(HeaderLastModified))
+	assert.Equal(t, "", rec.Header().Get(HeaderETag))
+}
+
+func TestContextCheckPreconditions(t *testing.T) {
+	m := New()
+	modified := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	for _, tt := range []struct {
+		header string
+		value  string
+		etag   string
+		want   error
+	}{
+		{"", "", `"v1"`, nil},
+		{HeaderIfMatch, `"v1"`, `"v1"`, nil},
+		{HeaderIfMatch, `"v0", "v1"`, `"v1"`, nil},
+		{HeaderIfMatch, `*`, `"v1"`, nil},
+		{HeaderIfMatch, `"v0"`, `"v1"`, ErrPreconditionFailed},
+		{HeaderIfMatch, `W/"v1"`, `"v1"`, ErrPreconditionFailed},
+		{HeaderIfMatch, `W/"v1"`, `W/"v1"`, nil},
+		{HeaderIfMatch, `"v1"`, `W/"v1"`, nil},
+		{HeaderIfMatch, `"v1"`, "", ErrPreconditionFailed},
+		{HeaderIfUnmodifiedSince, modified.Format(http.TimeFormat), `"v1"`, nil},
+		{HeaderIfUnmodifiedSince, modified.Add(-time.Second).Format(http.TimeFormat), `"v1"`, ErrPreconditionFailed},
+	} {
+		req := httptest.NewRequest(http.MethodPut, "/", nil)
+		if tt.header != "" {
+			req.Header.Set(tt.header, tt.value)
+		}
+		c := m.NewContext(req, httptest.NewRecorder())
+		assert.Equal(t, tt.want, c.CheckPreconditions(modified, tt.etag), "%s: %s", tt.header, tt.value)
+	}
+}
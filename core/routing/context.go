@@ -12,8 +12,12 @@ import (
 	"net/url"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
+
+	"github.com/google/uuid"
 )
 
 type (
@@ -52,9 +56,21 @@ type (
 		// SetPath sets the registered path for the handler.
 		SetPath(p string)
 
+		// Route returns the route matched by the request including its
+		// metadata, or nil if no route matched.
+		Route() *Route
+
 		// Param returns path parameter by name.
 		Param(name string) string
 
+		// ParamInt returns path parameter by name converted to int. It returns
+		// a 400 HTTPError if the value is not an integer.
+		ParamInt(name string) (int, error)
+
+		// ParamUUID returns path parameter by name parsed as UUID. It returns a
+		// 400 HTTPError if the value is not a UUID.
+		ParamUUID(name string) (uuid.UUID, error)
+
 		// ParamNames returns path parameter names.
 		ParamNames() []string
 
@@ -115,6 +131,12 @@ type (
 		// code. Renderer must be registered using `Echo.Renderer`.
 		Render(code int, name string, data interface{}) error
 
+		// Negotiate sends data with status code encoded in the media type
+		// preferred by the `Accept` header of the request, see
+		// `Mux#RegisterEncoder()`. It returns ErrNotAcceptable if no encoder
+		// matches.
+		Negotiate(code int, data interface{}) error
+
 		// HTML sends an HTTP response with status code.
 		HTML(code int, html string) error
 
@@ -156,6 +178,12 @@ type (
 		// Stream sends a streaming response with status code and content type.
 		Stream(code int, contentType string, r io.Reader) error
 
+		// SSE starts a stream of server-sent events and passes it to fn.
+		// Heartbeats are sent every DefaultSSEHeartbeat, the stream is done
+		// once the client disconnects and closed when fn returns. It returns
+		// the error of fn.
+		SSE(fn func(s *EventStream) error) error
+
 		// File sends a response with the content of the file.
 		File(file string) error
 
@@ -172,6 +200,19 @@ type (
 		// Redirect redirects the request to a provided URL with status code.
 		Redirect(code int, url string) error
 
+		// NotModified sets the Last-Modified and ETag headers of the response,
+		// either of which may be zero, and reports whether the copy of the client
+		// is still fresh according to the If-None-Match and If-Modified-Since
+		// headers of a GET or HEAD request. If so, respond with
+		// `NoContent(http.StatusNotModified)`.
+		NotModified(lastModified time.Time, etag string) bool
+
+		// CheckPreconditions checks the If-Match and If-Unmodified-Since headers
+		// of the request against the current state of the resource. It returns
+		// ErrPreconditionFailed if the client's copy is outdated, so updates are
+		// not lost. If-Match is compared strongly, unless etag is weak.
+		CheckPreconditions(lastModified time.Time, etag string) error
+
 		// Error invokes the registered HTTP error handler. Generally used by middleware.
 		Error(err error)
 
@@ -293,6 +334,13 @@ func (c *context) SetPath(p string) {
 	c.path = p
 }
 
+func (c *context) Route() *Route {
+	if c.request == nil {
+		return nil
+	}
+	return c.mux.findRouter(c.request.Host).routes[c.request.Method+c.path]
+}
+
 func (c *context) Param(name string) string {
 	for i, n := range c.pnames {
 		if i < len(c.pvalues) {
@@ -304,6 +352,22 @@ func (c *context) Param(name string) string {
 	return ""
 }
 
+func (c *context) ParamInt(name string) (int, error) {
+	i, err := strconv.Atoi(c.Param(name))
+	if err != nil {
+		return 0, NewHTTPError(http.StatusBadRequest, fmt.Sprintf("invalid param %s: expected integer", name)).SetInternal(err)
+	}
+	return i, nil
+}
+
+func (c *context) ParamUUID(name string) (uuid.UUID, error) {
+	id, err := uuid.Parse(c.Param(name))
+	if err != nil {
+		return uuid.Nil, NewHTTPError(http.StatusBadRequest, fmt.Sprintf("invalid param %s: expected uuid", name)).SetInternal(err)
+	}
+	return id, nil
+}
+
 func (c *context) ParamNames() []string {
 	return c.pnames
 }
@@ -487,6 +487,33 @@ func TestContextPathParam(t *testing.T) {
 	testify.Equal(t, "", c.Param("undefined"))
 }
 
+func TestContextTypedParams(t *testing.T) {
+	e := New()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	c := e.NewContext(req, nil)
+	c.SetParamNames("n", "id", "bad")
+	c.SetParamValues("42", "0b36e5c4-8d02-4c3b-9c3a-0e5a6c1f6f10", "x")
+
+	n, err := c.ParamInt("n")
+	if testify.NoError(t, err) {
+		testify.Equal(t, 42, n)
+	}
+	id, err := c.ParamUUID("id")
+	if testify.NoError(t, err) {
+		testify.Equal(t, "0b36e5c4-8d02-4c3b-9c3a-0e5a6c1f6f10", id.String())
+	}
+
+	_, err = c.ParamInt("bad")
+	if he, ok := err.(*HTTPError); testify.True(t, ok) {
+		testify.Equal(t, http.StatusBadRequest, he.Code)
+		testify.Equal(t, "invalid param bad: expected integer", he.Message)
+	}
+	_, err = c.ParamUUID("bad")
+	if he, ok := err.(*HTTPError); testify.True(t, ok) {
+		testify.Equal(t, http.StatusBadRequest, he.Code)
+	}
+}
+
 func TestContextGetAndSetParam(t *testing.T) {
 	e := New()
 	r := e.Router()
@@ -20,11 +20,14 @@ var (
 	ErrInternalServerError         = NewHTTPError(http.StatusInternalServerError)
 	ErrRequestTimeout              = NewHTTPError(http.StatusRequestTimeout)
 	ErrServiceUnavailable          = NewHTTPError(http.StatusServiceUnavailable)
+	ErrPreconditionFailed          = NewHTTPError(http.StatusPreconditionFailed)
+	ErrNotAcceptable               = NewHTTPError(http.StatusNotAcceptable)
 	ErrValidatorNotRegistered      = errors.New("validator not registered")
 	ErrRendererNotRegistered       = errors.New("renderer not registered")
 	ErrInvalidRedirectCode         = errors.New("invalid redirect status code")
 	ErrCookieNotFound              = errors.New("cookie not found")
 	ErrInvalidCertOrKeyType        = errors.New("invalid cert or key type, must be string or []byte")
+	ErrEventStreamClosed           = errors.New("event stream closed")
 
 	NotFoundHandler = func(c Context) error {
 		return ErrNotFound
@@ -8,10 +8,12 @@ type (
 	// from the parent mux instance while still inheriting from it.
 	Group struct {
 		common
-		host       string
-		prefix     string
-		middleware []MiddlewareFunc
-		mux        *Mux
+		host        string
+		prefix      string
+		middleware  []MiddlewareFunc
+		tags        []string
+		permissions []string
+		mux         *Mux
 	}
 )
 
@@ -23,8 +25,23 @@ func (g *Group) Use(middleware ...MiddlewareFunc) {
 	}
 	// Allow all requests to reach the group as they might get dropped if router
 	// doesn't find a match, making none of the group middleware process.
-	g.Any("", NotFoundHandler)
-	g.Any("/*", NotFoundHandler)
+	for _, r := range append(g.Any("", NotFoundHandler), g.Any("/*", NotFoundHandler)...) {
+		r.Internal = true
+	}
+}
+
+// AddTags adds tags to all routes registered on the group and its sub-groups
+// from now on.
+func (g *Group) AddTags(tags ...string) *Group {
+	g.tags = appendUnique(g.tags, tags...)
+	return g
+}
+
+// RequirePermissions adds permissions required to access all routes registered
+// on the group and its sub-groups from now on.
+func (g *Group) RequirePermissions(permissions ...string) *Group {
+	g.permissions = appendUnique(g.permissions, permissions...)
+	return g
 }
 
 // CONNECT implements `Echo#CONNECT()` for sub-routes within the Group.
@@ -73,8 +90,8 @@ func (g *Group) TRACE(path string, h HandlerFunc, m ...MiddlewareFunc) *Route {
 }
 
 // Any implements `Echo#Any()` for sub-routes within the Group.
-func (g *Group) Any(path string, handler HandlerFunc, middleware ...MiddlewareFunc) []*Route {
-	routes := make([]*Route, len(methods))
+func (g *Group) Any(path string, handler HandlerFunc, middleware ...MiddlewareFunc) Routes {
+	routes := make(Routes, len(methods))
 	for i, m := range methods {
 		routes[i] = g.Add(m, path, handler, middleware...)
 	}
@@ -82,8 +99,8 @@ func (g *Group) Any(path string, handler HandlerFunc, middleware ...MiddlewareFu
 }
 
 // Match implements `Echo#Match()` for sub-routes within the Group.
-func (g *Group) Match(methods []string, path string, handler HandlerFunc, middleware ...MiddlewareFunc) []*Route {
-	routes := make([]*Route, len(methods))
+func (g *Group) Match(methods []string, path string, handler HandlerFunc, middleware ...MiddlewareFunc) Routes {
+	routes := make(Routes, len(methods))
 	for i, m := range methods {
 		routes[i] = g.Add(m, path, handler, middleware...)
 	}
@@ -97,6 +114,8 @@ func (g *Group) Group(prefix string, middleware ...MiddlewareFunc) (sg *Group) {
 	m = append(m, middleware...)
 	sg = g.mux.Group(g.prefix+prefix, m...)
 	sg.host = g.host
+	sg.tags = append([]string(nil), g.tags...)
+	sg.permissions = append([]string(nil), g.permissions...)
 	return
 }
 
@@ -118,5 +137,7 @@ func (g *Group) Add(method, path string, handler HandlerFunc, middleware ...Midd
 	m := make([]MiddlewareFunc, 0, len(g.middleware)+len(middleware))
 	m = append(m, g.middleware...)
 	m = append(m, middleware...)
-	return g.mux.add(g.host, method, g.prefix+path, handler, m...)
+	return g.mux.add(g.host, method, g.prefix+path, handler, m...).
+		AddTags(g.tags...).
+		RequirePermissions(g.permissions...)
 }
@@ -0,0 +1,121 @@
+package middleware
+
+import (
+	"fmt"
+	"goplugins/core/routing"
+	"io"
+	"strconv"
+	"strings"
+)
+
+type (
+	// BodyLimitConfig defines the config for BodyLimit middleware.
+	BodyLimitConfig struct {
+		// Skipper defines a function to skip middleware.
+		Skipper Skipper
+
+		// Limit is the maximum allowed size of the request body, given in bytes
+		// or with a binary unit, e.g. "512K", "2M" or "1.5G".
+		// Required.
+		Limit string
+	}
+
+	limitedReader struct {
+		io.ReadCloser
+		limit int64
+		read  int64
+	}
+)
+
+// DefaultBodyLimitConfig is the default BodyLimit middleware config.
+var DefaultBodyLimitConfig = BodyLimitConfig{
+	Skipper: DefaultSkipper,
+}
+
+var sizeUnits = map[string]int64{
+	"":  1,
+	"B": 1,
+	"K": 1 << 10, "KB": 1 << 10, "KIB": 1 << 10,
+	"M": 1 << 20, "MB": 1 << 20, "MIB": 1 << 20,
+	"G": 1 << 30, "GB": 1 << 30, "GIB": 1 << 30,
+	"T": 1 << 40, "TB": 1 << 40, "TIB": 1 << 40,
+}
+
+// BodyLimit returns a middleware which limits the size of the request body.
+// Requests with a larger Content-Length are rejected right away with
+// "413 Request Entity Too Large". Otherwise reading the body fails with the
+// same error once more than limit bytes are read, which Bind returns as is.
+//
+// Add it to routes or groups to raise or lower the limit for them; note that
+// a route can not read more than the limits of the enclosing groups allow.
+func BodyLimit(limit string) routing.MiddlewareFunc {
+	c := DefaultBodyLimitConfig
+	c.Limit = limit
+	return BodyLimitWithConfig(c)
+}
+
+// BodyLimitWithConfig returns a BodyLimit middleware with config.
+// See: `BodyLimit()`.
+func BodyLimitWithConfig(config BodyLimitConfig) routing.MiddlewareFunc {
+	// Defaults
+	if config.Skipper == nil {
+		config.Skipper = DefaultBodyLimitConfig.Skipper
+	}
+	limit, err := parseSize(config.Limit)
+	if err != nil {
+		panic(fmt.Sprintf("routing: invalid body limit %q", config.Limit))
+	}
+
+	return func(next routing.HandlerFunc) routing.HandlerFunc {
+		return func(c routing.Context) error {
+			if config.Skipper(c) {
+				return next(c)
+			}
+
+			req := c.Request()
+			if req.ContentLength > limit {
+				return routing.ErrStatusRequestEntityTooLarge
+			}
+			if req.Body == nil {
+				return next(c)
+			}
+			req.Body = &limitedReader{ReadCloser: req.Body, limit: limit}
+			return next(c)
+		}
+	}
+}
+
+func (r *limitedReader) Read(b []byte) (n int, err error) {
+	if r.read > r.limit {
+		return 0, routing.ErrStatusRequestEntityTooLarge
+	}
+	n, err = r.ReadCloser.Read(b)
+	r.read += int64(n)
+	if r.read > r.limit {
+		// Drop the excess, so decoders can not succeed on a truncated body.
+		n -= int(r.read - r.limit)
+		return n, routing.ErrStatusRequestEntityTooLarge
+	}
+	return
+}
+
+// parseSize parses sizes like "1024", "512K", "2MB" or "1.5G" into bytes.
+// Units are case insensitive and binary, i.e. "1K" is 1024 bytes.
+func parseSize(s string) (int64, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	i := strings.IndexFunc(s, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+	if i < 0 {
+		i = len(s)
+	}
+	unit, ok := sizeUnits[strings.TrimSpace(s[i:])]
+	if !ok {
+		return 0, fmt.Errorf("unknown unit in size %q", s)
+	}
+	n, err := strconv.ParseFloat(s[:i], 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return int64(n * float64(unit)), nil
+}
@@ -0,0 +1,107 @@
+package middleware
+
+import (
+	"bytes"
+	"goplugins/core/routing"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBodyLimit(t *testing.T) {
+	m := routing.New()
+	hw := []byte("Hello, World!")
+	h := func(c routing.Context) error {
+		body, err := ioutil.ReadAll(c.Request().Body)
+		if err != nil {
+			return err
+		}
+		return c.String(http.StatusOK, string(body))
+	}
+
+	// Based on content length (within limit)
+	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(hw))
+	rec := httptest.NewRecorder()
+	c := m.NewContext(req, rec)
+	if assert.NoError(t, BodyLimit("2M")(h)(c)) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, hw, rec.Body.Bytes())
+	}
+
+	// Based on content length (overlimit)
+	req = httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(hw))
+	c = m.NewContext(req, httptest.NewRecorder())
+	assert.Equal(t, routing.ErrStatusRequestEntityTooLarge, BodyLimit("2B")(h)(c))
+
+	// Based on content read (within limit)
+	req = httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(hw))
+	req.ContentLength = -1
+	rec = httptest.NewRecorder()
+	c = m.NewContext(req, rec)
+	if assert.NoError(t, BodyLimit("2M")(h)(c)) {
+		assert.Equal(t, hw, rec.Body.Bytes())
+	}
+
+	// Based on content read (overlimit)
+	req = httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(hw))
+	req.ContentLength = -1
+	c = m.NewContext(req, httptest.NewRecorder())
+	assert.Equal(t, routing.ErrStatusRequestEntityTooLarge, BodyLimit("2B")(h)(c))
+}
+
+func TestBodyLimitBind(t *testing.T) {
+	m := routing.New()
+	m.Use(BodyLimit("16B"))
+	m.POST("/", func(c routing.Context) error {
+		var v struct {
+			Name string `json:"name"`
+		}
+		if err := c.Bind(&v); err != nil {
+			return err
+		}
+		return c.String(http.StatusOK, v.Name)
+	})
+
+	for body, code := range map[string]int{
+		`{"name":"test"}`: http.StatusOK,
+		`{"name":"` + strings.Repeat("x", 32) + `"}`: http.StatusRequestEntityTooLarge,
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		req.Header.Set(routing.HeaderContentType, routing.MIMEApplicationJSON)
+		req.ContentLength = -1
+		rec := httptest.NewRecorder()
+		m.ServeHTTP(rec, req)
+		assert.Equal(t, code, rec.Code, body)
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	for s, want := range map[string]int64{
+		"0":      0,
+		"1024":   1024,
+		"10B":    10,
+		"2K":     2 << 10,
+		"2kb":    2 << 10,
+		"2 KiB":  2 << 10,
+		"2M":     2 << 20,
+		"1.5G":   3 << 29,
+		"1T":     1 << 40,
+		" 64MB ": 64 << 20,
+	} {
+		n, err := parseSize(s)
+		require.NoError(t, err, s)
+		assert.Equal(t, want, n, s)
+	}
+	for _, s := range []string{"", "M", "2X", "-1K", "1..5M"} {
+		_, err := parseSize(s)
+		assert.Error(t, err, s)
+	}
+	assert.Panics(t, func() {
+		BodyLimit("lots")
+	})
+}
@@ -0,0 +1,600 @@
+package middleware
+
+import (
+	"bufio"
+	"container/list"
+	"errors"
+	"goplugins/core/routing"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type (
+	// CacheConfig defines the config for Cache middleware.
+	CacheConfig struct {
+		// Skipper defines a function to skip middleware.
+		Skipper Skipper
+
+		// Store keeps the cached responses.
+		// Required.
+		Store CacheStore
+
+		// TTL is the time responses are cached for, unless the response sets
+		// a shorter "s-maxage" or "max-age" Cache-Control directive.
+		// Optional. Default value 1 minute.
+		TTL time.Duration
+
+		// VaryHeaders are request headers whose values are part of the cache
+		// key, e.g. "Accept-Language" or the header carrying the tenant.
+		// Optional.
+		VaryHeaders []string
+
+		// KeyGenerator returns the cache key of a request.
+		// Optional. Default value generates the key from the method, host,
+		// path, sorted query and VaryHeaders.
+		KeyGenerator func(c routing.Context, varyHeaders []string) string
+
+		// Tags are added to every cached response, so they can be invalidated
+		// together. Handlers may add further tags using CacheTags.
+		// Optional.
+		Tags []string
+
+		// MaxSize is the size of the largest body cached.
+		// Optional. Default value 1 MB.
+		MaxSize int
+
+		// IgnoreRequestCacheControl serves cached responses even if the client
+		// asks for a fresh one using "Cache-Control: no-cache" or "no-store".
+		// Optional. Default value false.
+		IgnoreRequestCacheControl bool
+
+		// CacheCredentialed caches the responses to requests carrying an
+		// Authorization or Cookie header. Only enable it if the responses do
+		// not depend on the credentials.
+		// Optional. Default value false.
+		CacheCredentialed bool
+	}
+
+	// CacheStore is the interface to be implemented by custom stores of the
+	// Cache middleware.
+	CacheStore interface {
+		// Get returns the entry stored under key unless it has expired.
+		Get(key string) (*CacheEntry, bool)
+		// Set stores entry under key for ttl.
+		Set(key string, entry *CacheEntry, ttl time.Duration)
+		// Delete removes the entry stored under key.
+		Delete(key string)
+		// InvalidateTags removes all entries carrying one of tags.
+		InvalidateTags(tags ...string)
+	}
+
+	// CacheEntry is a cached response.
+	CacheEntry struct {
+		Status   int
+		Header   http.Header
+		Body     []byte
+		Tags     []string
+		StoredAt time.Time
+		// Vary is set on the entry stored under the key of responses
+		// carrying a Vary header. It lists the request headers whose values
+		// select the entry holding the response, see CacheVariantKey.
+		Vary []string
+	}
+
+	// CacheMemoryStore is the built-in store keeping up to a number of
+	// entries in memory, evicting the least recently used ones first. It is
+	// safe for concurrent use.
+	CacheMemoryStore struct {
+		mutex    sync.Mutex
+		capacity int
+		entries  *list.List
+		keys     map[string]*list.Element
+		tags     map[string]map[string]struct{}
+		timeNow  func() time.Time
+	}
+
+	cacheItem struct {
+		key     string
+		entry   *CacheEntry
+		expires time.Time
+	}
+
+	// cacheFlight is a response being computed. Concurrent requests for the
+	// same key wait for it instead of running the handler as well.
+	cacheFlight struct {
+		done  chan struct{}
+		entry *CacheEntry
+		key   string
+	}
+
+	cacheResponseWriter struct {
+		http.ResponseWriter
+		maxSize   int
+		header    http.Header
+		code      int
+		buf       []byte
+		uncached  bool
+		committed bool
+	}
+)
+
+// CacheTagsKey is the context key of the tags added using CacheTags.
+const CacheTagsKey = "cache_tags"
+
+// DefaultCacheConfig is the default Cache middleware config.
+var DefaultCacheConfig = CacheConfig{
+	Skipper:      DefaultSkipper,
+	TTL:          time.Minute,
+	KeyGenerator: DefaultCacheKey,
+	MaxSize:      1 << 20,
+}
+
+// Cache returns a middleware which caches the responses of GET and HEAD
+// requests in store, to serve them without running the handler again. Only
+// "200 OK" responses are cached; responses setting cookies, using a CSP nonce,
+// carrying "Vary: *" or a "private", "no-cache" or "no-store" Cache-Control
+// directive are not. Requests carrying an Authorization or Cookie header
+// bypass the cache unless CacheCredentialed is set. Responses varying by
+// request headers, e.g. by content negotiation, are cached per value of these
+// headers.
+//
+//	catalog := middleware.NewCacheMemoryStore(1000)
+//	g := m.Group("/products", middleware.CacheWithConfig(middleware.CacheConfig{
+//		Store: catalog,
+//		TTL:   10 * time.Minute,
+//		Tags:  []string{"products"},
+//	}))
+//	// after changing products
+//	catalog.InvalidateTags("products")
+//
+// Concurrent requests for a response which is not cached yet wait for the
+// first one, so the handler runs only once.
+func Cache(store CacheStore) routing.MiddlewareFunc {
+	config := DefaultCacheConfig
+	config.Store = store
+	return CacheWithConfig(config)
+}
+
+// CacheWithConfig returns a Cache middleware with config.
+// See: `Cache()`.
+func CacheWithConfig(config CacheConfig) routing.MiddlewareFunc {
+	// Defaults
+	if config.Skipper == nil {
+		config.Skipper = DefaultCacheConfig.Skipper
+	}
+	if config.TTL == 0 {
+		config.TTL = DefaultCacheConfig.TTL
+	}
+	if config.KeyGenerator == nil {
+		config.KeyGenerator = DefaultCacheConfig.KeyGenerator
+	}
+	if config.MaxSize == 0 {
+		config.MaxSize = DefaultCacheConfig.MaxSize
+	}
+	if config.Store == nil {
+		panic("routing: cache middleware requires a store")
+	}
+
+	var (
+		mutex   sync.Mutex
+		flights = map[string]*cacheFlight{}
+	)
+
+	return func(next routing.HandlerFunc) routing.HandlerFunc {
+		return func(c routing.Context) error {
+			req := c.Request()
+			if config.Skipper(c) || req.Method != http.MethodGet && req.Method != http.MethodHead {
+				return next(c)
+			}
+			directives := parseCacheControl(req.Header.Get(routing.HeaderCacheControl))
+			_, noStore := directives["no-store"]
+			_, noCache := directives["no-cache"]
+			if noStore && !config.IgnoreRequestCacheControl {
+				return next(c)
+			}
+			if !config.CacheCredentialed && (req.Header.Get(routing.HeaderAuthorization) != "" || req.Header.Get(routing.HeaderCookie) != "") {
+				return next(c)
+			}
+
+			key := config.KeyGenerator(c, config.VaryHeaders)
+			if !noCache || config.IgnoreRequestCacheControl {
+				if entry, ok := lookupCacheEntry(config.Store, key, req); ok {
+					return serveCacheEntry(c, entry)
+				}
+			}
+
+			mutex.Lock()
+			if f, ok := flights[key]; ok {
+				mutex.Unlock()
+				select {
+				case <-f.done:
+				case <-req.Context().Done():
+					return req.Context().Err()
+				}
+				if f.entry != nil && CacheVariantKey(key, f.entry.Vary, req) == f.key {
+					return serveCacheEntry(c, f.entry)
+				}
+				// The response was not cacheable, so compute our own.
+				return next(c)
+			}
+			f := &cacheFlight{done: make(chan struct{})}
+			flights[key] = f
+			mutex.Unlock()
+			defer func() {
+				mutex.Lock()
+				delete(flights, key)
+				mutex.Unlock()
+				close(f.done)
+			}()
+
+			res := c.Response()
+			// Headers set by outer middleware are request specific, e.g. CORS
+			// headers, so they are not cached.
+			outer := res.Header().Clone()
+			rw := res.Writer
+			w := &cacheResponseWriter{ResponseWriter: rw, maxSize: config.MaxSize}
+			res.Writer = w
+			res.Header().Set(routing.HeaderXCache, "MISS")
+			err := next(c)
+			res.Writer = rw
+			if err != nil {
+				return err
+			}
+
+			ttl, ok := w.cacheable(config.TTL)
+			if !ok {
+				return nil
+			}
+			if _, ok := c.Get(CSPNonceKey).(string); ok {
+				// The nonce is specific to the request and likely part of
+				// the body.
+				return nil
+			}
+			// Vary values added by outer middleware are computed for every
+			// request, so they don't tell cached responses apart.
+			vary := cacheVary(w.header, outer)
+			if len(vary) == 1 && vary[0] == "*" {
+				return nil
+			}
+			tags := append([]string(nil), config.Tags...)
+			if t, ok := c.Get(CacheTagsKey).([]string); ok {
+				tags = append(tags, t...)
+			}
+			w.header.Del(routing.HeaderXCache)
+			for k, v := range outer {
+				if equalValues(w.header[k], v) {
+					delete(w.header, k)
+				}
+			}
+			entry := &CacheEntry{
+				Status:   w.code,
+				Header:   w.header,
+				Body:     w.buf,
+				Tags:     tags,
+				StoredAt: time.Now(),
+			}
+			f.key = key
+			if len(vary) > 0 {
+				entry.Vary = vary
+				f.key = CacheVariantKey(key, vary, req)
+				config.Store.Set(key, &CacheEntry{Tags: tags, StoredAt: entry.StoredAt, Vary: vary}, ttl)
+			}
+			config.Store.Set(f.key, entry, ttl)
+			f.entry = entry
+			return nil
+		}
+	}
+}
+
+// CacheTags adds tags to the response cached for the request, see
+// `CacheStore.InvalidateTags`.
+func CacheTags(c routing.Context, tags ...string) {
+	t, _ := c.Get(CacheTagsKey).([]string)
+	c.Set(CacheTagsKey, append(t, tags...))
+}
+
+// DefaultCacheKey returns a cache key made of the method, host, path, sorted
+// query parameters and the values of varyHeaders. HEAD requests share the
+// entries of GET requests.
+func DefaultCacheKey(c routing.Context, varyHeaders []string) string {
+	req := c.Request()
+	method := req.Method
+	if method == http.MethodHead {
+		method = http.MethodGet
+	}
+	var b strings.Builder
+	b.WriteString(method + " " + req.Host + req.URL.Path)
+	if query := req.URL.Query(); len(query) > 0 {
+		b.WriteString("?" + query.Encode())
+	}
+	for _, h := range varyHeaders {
+		b.WriteString("\n" + http.CanonicalHeaderKey(h) + ": " + strings.Join(req.Header.Values(h), ", "))
+	}
+	return b.String()
+}
+
+// CacheVariantKey returns the key of the variant of a response varying by
+// the request headers vary.
+func CacheVariantKey(key string, vary []string, req *http.Request) string {
+	if len(vary) == 0 {
+		return key
+	}
+	var b strings.Builder
+	b.WriteString(key + "\nVary")
+	for _, h := range vary {
+		b.WriteString("\n" + h + ": " + strings.Join(req.Header.Values(h), ", "))
+	}
+	return b.String()
+}
+
+// lookupCacheEntry returns the entry cached for req under key, following
+// entries of varying responses to the variant of the request.
+func lookupCacheEntry(store CacheStore, key string, req *http.Request) (*CacheEntry, bool) {
+	entry, ok := store.Get(key)
+	if !ok || len(entry.Vary) == 0 {
+		return entry, ok
+	}
+	entry, ok = store.Get(CacheVariantKey(key, entry.Vary, req))
+	if !ok || len(entry.Vary) == 0 {
+		// Entries stored under variant keys always carry Vary.
+		return nil, false
+	}
+	return entry, true
+}
+
+// cacheVary returns the sorted, canonical request headers named by the Vary
+// header of the response, except those already set in outer. It returns
+// []string{"*"} if the response varies by anything.
+func cacheVary(header, outer http.Header) []string {
+	skip := map[string]bool{}
+	for _, h := range splitHeaderValues(outer.Values(routing.HeaderVary)) {
+		skip[http.CanonicalHeaderKey(h)] = true
+	}
+	var vary []string
+	for _, h := range splitHeaderValues(header.Values(routing.HeaderVary)) {
+		if h == "*" {
+			return []string{"*"}
+		}
+		h = http.CanonicalHeaderKey(h)
+		if !skip[h] {
+			skip[h] = true
+			vary = append(vary, h)
+		}
+	}
+	sort.Strings(vary)
+	return vary
+}
+
+func splitHeaderValues(values []string) []string {
+	var parts []string
+	for _, v := range values {
+		for _, p := range strings.Split(v, ",") {
+			if p = strings.TrimSpace(p); p != "" {
+				parts = append(parts, p)
+			}
+		}
+	}
+	return parts
+}
+
+// serveCacheEntry writes entry to the response, or "304 Not Modified" if the
+// client's copy is still fresh.
+func serveCacheEntry(c routing.Context, entry *CacheEntry) error {
+	header := c.Response().Header()
+	for k, v := range entry.Header {
+		header[k] = v
+	}
+	header.Set(routing.HeaderXCache, "HIT")
+	header.Set(routing.HeaderAge, strconv.Itoa(int(time.Since(entry.StoredAt).Seconds())))
+
+	lastModified, _ := http.ParseTime(entry.Header.Get(routing.HeaderLastModified))
+	if c.NotModified(lastModified, entry.Header.Get(routing.HeaderETag)) {
+		header.Del(routing.HeaderContentType)
+		header.Del(routing.HeaderContentLength)
+		return c.NoContent(http.StatusNotModified)
+	}
+	c.Response().WriteHeader(entry.Status)
+	_, err := c.Response().Write(entry.Body)
+	return err
+}
+
+func equalValues(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// parseCacheControl returns the directives of a Cache-Control header.
+func parseCacheControl(s string) map[string]string {
+	directives := map[string]string{}
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, value := part, ""
+		if i := strings.IndexByte(part, '='); i >= 0 {
+			name, value = part[:i], strings.Trim(part[i+1:], `"`)
+		}
+		directives[strings.ToLower(name)] = value
+	}
+	return directives
+}
+
+func (w *cacheResponseWriter) WriteHeader(code int) {
+	if !w.committed {
+		w.committed = true
+		w.code = code
+		w.header = w.ResponseWriter.Header().Clone()
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *cacheResponseWriter) Write(b []byte) (int, error) {
+	if !w.committed {
+		w.WriteHeader(http.StatusOK)
+	}
+	if !w.uncached {
+		if len(w.buf)+len(b) > w.maxSize {
+			w.uncached = true
+			w.buf = nil
+		} else {
+			w.buf = append(w.buf, b...)
+		}
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush passes the response on. Streamed responses are not cached.
+func (w *cacheResponseWriter) Flush() {
+	w.uncached = true
+	w.buf = nil
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *cacheResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("routing: response does not implement http.Hijacker")
+	}
+	w.uncached = true
+	return h.Hijack()
+}
+
+// cacheable reports whether the response may be cached and for how long.
+func (w *cacheResponseWriter) cacheable(ttl time.Duration) (time.Duration, bool) {
+	if w.uncached || w.code != http.StatusOK {
+		return 0, false
+	}
+	if _, ok := w.header[routing.HeaderSetCookie]; ok {
+		return 0, false
+	}
+	directives := parseCacheControl(w.header.Get(routing.HeaderCacheControl))
+	for _, d := range []string{"no-store", "no-cache", "private"} {
+		if _, ok := directives[d]; ok {
+			return 0, false
+		}
+	}
+	for _, d := range []string{"s-maxage", "max-age"} {
+		if v, ok := directives[d]; ok {
+			seconds, err := strconv.Atoi(v)
+			if err != nil || seconds <= 0 {
+				return 0, false
+			}
+			if max := time.Duration(seconds) * time.Second; max < ttl {
+				ttl = max
+			}
+			break
+		}
+	}
+	return ttl, true
+}
+
+// NewCacheMemoryStore returns a CacheMemoryStore keeping up to capacity
+// entries.
+func NewCacheMemoryStore(capacity int) *CacheMemoryStore {
+	if capacity <= 0 {
+		panic("routing: cache memory store requires a positive capacity")
+	}
+	return &CacheMemoryStore{
+		capacity: capacity,
+		entries:  list.New(),
+		keys:     map[string]*list.Element{},
+		tags:     map[string]map[string]struct{}{},
+		timeNow:  time.Now,
+	}
+}
+
+// Get implements CacheStore.
+func (s *CacheMemoryStore) Get(key string) (*CacheEntry, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	e, ok := s.keys[key]
+	if !ok {
+		return nil, false
+	}
+	item := e.Value.(*cacheItem)
+	if !s.timeNow().Before(item.expires) {
+		s.remove(e)
+		return nil, false
+	}
+	s.entries.MoveToFront(e)
+	return item.entry, true
+}
+
+// Set implements CacheStore.
+func (s *CacheMemoryStore) Set(key string, entry *CacheEntry, ttl time.Duration) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if e, ok := s.keys[key]; ok {
+		s.remove(e)
+	}
+	item := &cacheItem{key: key, entry: entry, expires: s.timeNow().Add(ttl)}
+	s.keys[key] = s.entries.PushFront(item)
+	for _, tag := range entry.Tags {
+		if s.tags[tag] == nil {
+			s.tags[tag] = map[string]struct{}{}
+		}
+		s.tags[tag][key] = struct{}{}
+	}
+	for s.entries.Len() > s.capacity {
+		s.remove(s.entries.Back())
+	}
+}
+
+// Delete implements CacheStore.
+func (s *CacheMemoryStore) Delete(key string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if e, ok := s.keys[key]; ok {
+		s.remove(e)
+	}
+}
+
+// InvalidateTags implements CacheStore.
+func (s *CacheMemoryStore) InvalidateTags(tags ...string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, tag := range tags {
+		for key := range s.tags[tag] {
+			if e, ok := s.keys[key]; ok {
+				s.remove(e)
+			}
+		}
+	}
+}
+
+// Len returns the number of entries, including expired ones not removed yet.
+func (s *CacheMemoryStore) Len() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.entries.Len()
+}
+
+func (s *CacheMemoryStore) remove(e *list.Element) {
+	item := s.entries.Remove(e).(*cacheItem)
+	delete(s.keys, item.key)
+	for _, tag := range item.entry.Tags {
+		delete(s.tags[tag], item.key)
+		if len(s.tags[tag]) == 0 {
+			delete(s.tags, tag)
+		}
+	}
+}
@@ -0,0 +1,331 @@
+package middleware
+
+import (
+	"goplugins/core/routing"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func cacheRequest(m *routing.Mux, target string, header ...string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestCache(t *testing.T) {
+	var calls int32
+	store := NewCacheMemoryStore(10)
+	m := routing.New()
+	m.Use(Cache(store))
+	m.GET("/products", func(c routing.Context) error {
+		n := atomic.AddInt32(&calls, 1)
+		c.Response().Header().Set("X-Test", "test")
+		return c.String(http.StatusOK, "products "+strconv.Itoa(int(n)))
+	})
+	m.POST("/products", func(c routing.Context) error {
+		return c.String(http.StatusOK, "created")
+	})
+
+	rec := cacheRequest(m, "/products")
+	assert.Equal(t, "MISS", rec.Header().Get(routing.HeaderXCache))
+	assert.Equal(t, "products 1", rec.Body.String())
+
+	rec = cacheRequest(m, "/products")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "HIT", rec.Header().Get(routing.HeaderXCache))
+	assert.Equal(t, "0", rec.Header().Get(routing.HeaderAge))
+	assert.Equal(t, "test", rec.Header().Get("X-Test"))
+	assert.Equal(t, routing.MIMETextPlainCharsetUTF8, rec.Header().Get(routing.HeaderContentType))
+	assert.Equal(t, "products 1", rec.Body.String())
+
+	// Query is part of the key
+	assert.Equal(t, "products 2", cacheRequest(m, "/products?page=2").Body.String())
+	assert.Equal(t, "products 2", cacheRequest(m, "/products?page=2").Body.String())
+
+	// Client asks for a fresh response
+	assert.Equal(t, "products 3", cacheRequest(m, "/products", routing.HeaderCacheControl, "no-cache").Body.String())
+	assert.Equal(t, "products 3", cacheRequest(m, "/products").Body.String())
+	assert.Equal(t, "products 4", cacheRequest(m, "/products", routing.HeaderCacheControl, "no-store").Body.String())
+	assert.Equal(t, "products 3", cacheRequest(m, "/products").Body.String())
+
+	// Unsafe methods are not cached
+	req := httptest.NewRequest(http.MethodPost, "/products", nil)
+	rec = httptest.NewRecorder()
+	m.ServeHTTP(rec, req)
+	assert.Equal(t, "created", rec.Body.String())
+	assert.Equal(t, "", rec.Header().Get(routing.HeaderXCache))
+}
+
+func TestCacheVary(t *testing.T) {
+	m := routing.New()
+	m.Use(CacheWithConfig(CacheConfig{
+		Store:       NewCacheMemoryStore(10),
+		VaryHeaders: []string{"Accept-Language"},
+	}))
+	m.GET("/", func(c routing.Context) error {
+		return c.String(http.StatusOK, c.Request().Header.Get("Accept-Language"))
+	})
+
+	assert.Equal(t, "de", cacheRequest(m, "/", "Accept-Language", "de").Body.String())
+	assert.Equal(t, "en", cacheRequest(m, "/", "Accept-Language", "en").Body.String())
+	rec := cacheRequest(m, "/", "Accept-Language", "de")
+	assert.Equal(t, "HIT", rec.Header().Get(routing.HeaderXCache))
+	assert.Equal(t, "de", rec.Body.String())
+}
+
+func TestCacheResponseVary(t *testing.T) {
+	var calls int32
+	m := routing.New()
+	m.Use(Cache(NewCacheMemoryStore(10)))
+	m.GET("/", func(c routing.Context) error {
+		atomic.AddInt32(&calls, 1)
+		c.Response().Header().Add(routing.HeaderVary, "x-tenant-id")
+		return c.String(http.StatusOK, c.Request().Header.Get("X-Tenant-ID"))
+	})
+	m.GET("/any", func(c routing.Context) error {
+		atomic.AddInt32(&calls, 1)
+		c.Response().Header().Set(routing.HeaderVary, "*")
+		return c.String(http.StatusOK, "test")
+	})
+	m.GET("/negotiated", func(c routing.Context) error {
+		return c.Negotiate(http.StatusOK, map[string]string{"name": "Jon"})
+	})
+
+	assert.Equal(t, "acme", cacheRequest(m, "/", "X-Tenant-ID", "acme").Body.String())
+	rec := cacheRequest(m, "/", "X-Tenant-ID", "globex")
+	assert.Equal(t, "MISS", rec.Header().Get(routing.HeaderXCache))
+	assert.Equal(t, "globex", rec.Body.String())
+	rec = cacheRequest(m, "/", "X-Tenant-ID", "acme")
+	assert.Equal(t, "HIT", rec.Header().Get(routing.HeaderXCache))
+	assert.Equal(t, "acme", rec.Body.String())
+	assert.Equal(t, "HIT", cacheRequest(m, "/", "X-Tenant-ID", "globex").Header().Get(routing.HeaderXCache))
+	assert.Equal(t, "", cacheRequest(m, "/").Body.String())
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+
+	atomic.StoreInt32(&calls, 0)
+	cacheRequest(m, "/any")
+	assert.Equal(t, "MISS", cacheRequest(m, "/any").Header().Get(routing.HeaderXCache))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+
+	rec = cacheRequest(m, "/negotiated", routing.HeaderAccept, routing.MIMEApplicationXML)
+	assert.Contains(t, rec.Header().Get(routing.HeaderContentType), "xml")
+	rec = cacheRequest(m, "/negotiated", routing.HeaderAccept, routing.MIMEApplicationJSON)
+	assert.Equal(t, "MISS", rec.Header().Get(routing.HeaderXCache))
+	assert.Contains(t, rec.Header().Get(routing.HeaderContentType), "json")
+}
+
+func TestCacheCredentialed(t *testing.T) {
+	var calls int32
+	handler := func(c routing.Context) error {
+		atomic.AddInt32(&calls, 1)
+		return c.String(http.StatusOK, "test")
+	}
+	m := routing.New()
+	m.GET("/", handler, Cache(NewCacheMemoryStore(10)))
+	m.GET("/shared", handler, CacheWithConfig(CacheConfig{Store: NewCacheMemoryStore(10), CacheCredentialed: true}))
+
+	for _, header := range [][]string{{routing.HeaderAuthorization, "Bearer a"}, {routing.HeaderCookie, "session=a"}} {
+		atomic.StoreInt32(&calls, 0)
+		cacheRequest(m, "/", header...)
+		rec := cacheRequest(m, "/", header...)
+		assert.Equal(t, "", rec.Header().Get(routing.HeaderXCache))
+		assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+	}
+	cacheRequest(m, "/shared", routing.HeaderCookie, "session=a")
+	assert.Equal(t, "HIT", cacheRequest(m, "/shared", routing.HeaderCookie, "session=b").Header().Get(routing.HeaderXCache))
+}
+
+func TestCacheCSPNonce(t *testing.T) {
+	m := routing.New()
+	m.Use(SecureWithConfig(SecureConfig{ContentSecurityPolicy: "script-src 'nonce-${nonce}'"}))
+	m.Use(Cache(NewCacheMemoryStore(10)))
+	m.GET("/", func(c routing.Context) error {
+		return c.HTML(http.StatusOK, `<script nonce="`+CSPNonce(c)+`"></script>`)
+	})
+
+	first := cacheRequest(m, "/")
+	second := cacheRequest(m, "/")
+	assert.Equal(t, "MISS", second.Header().Get(routing.HeaderXCache))
+	assert.NotEqual(t, first.Body.String(), second.Body.String())
+	nonce := strings.TrimSuffix(strings.TrimPrefix(second.Body.String(), `<script nonce="`), `"></script>`)
+	assert.Contains(t, second.Header().Get(routing.HeaderContentSecurityPolicy), "'nonce-"+nonce+"'")
+}
+
+func TestCacheHijackNotSupported(t *testing.T) {
+	m := routing.New()
+	m.Use(Cache(NewCacheMemoryStore(10)))
+	m.GET("/", func(c routing.Context) error {
+		_, _, err := c.Response().Hijack()
+		return err
+	})
+	assert.Equal(t, http.StatusInternalServerError, cacheRequest(m, "/").Code)
+}
+
+func TestCacheUncacheable(t *testing.T) {
+	var calls int32
+	m := routing.New()
+	m.Use(Cache(NewCacheMemoryStore(10)))
+	m.GET("/:case", func(c routing.Context) error {
+		atomic.AddInt32(&calls, 1)
+		switch c.Param("case") {
+		case "private":
+			c.Response().Header().Set(routing.HeaderCacheControl, "private, max-age=60")
+		case "cookie":
+			c.SetCookie(&http.Cookie{Name: "session", Value: "test"})
+		case "missing":
+			return c.String(http.StatusNotFound, "not found")
+		case "error":
+			return routing.ErrForbidden
+		case "stream":
+			c.Response().WriteHeader(http.StatusOK)
+			c.Response().Flush()
+		}
+		return c.String(http.StatusOK, "test")
+	})
+
+	for _, path := range []string{"/private", "/cookie", "/missing", "/error", "/stream"} {
+		atomic.StoreInt32(&calls, 0)
+		cacheRequest(m, path)
+		rec := cacheRequest(m, path)
+		assert.Equal(t, int32(2), atomic.LoadInt32(&calls), path)
+		assert.Equal(t, "MISS", rec.Header().Get(routing.HeaderXCache), path)
+	}
+}
+
+func TestCacheMaxAge(t *testing.T) {
+	store := NewCacheMemoryStore(10)
+	clk := &clock{now: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)}
+	store.timeNow = clk.Now
+	var calls int32
+	m := routing.New()
+	m.Use(CacheWithConfig(CacheConfig{Store: store, TTL: time.Hour}))
+	m.GET("/", func(c routing.Context) error {
+		atomic.AddInt32(&calls, 1)
+		c.Response().Header().Set(routing.HeaderCacheControl, "public, max-age=60")
+		return c.String(http.StatusOK, "test")
+	})
+
+	cacheRequest(m, "/")
+	clk.Add(59 * time.Second)
+	assert.Equal(t, "HIT", cacheRequest(m, "/").Header().Get(routing.HeaderXCache))
+	clk.Add(time.Second)
+	assert.Equal(t, "MISS", cacheRequest(m, "/").Header().Get(routing.HeaderXCache))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
+
+func TestCacheOuterHeaders(t *testing.T) {
+	m := routing.New()
+	m.Use(CORSWithConfig(CORSConfig{AllowOrigins: []string{"https://a.example.com", "https://b.example.com"}}))
+	m.Use(Cache(NewCacheMemoryStore(10)))
+	m.GET("/", func(c routing.Context) error {
+		return c.String(http.StatusOK, "test")
+	})
+
+	rec := cacheRequest(m, "/", routing.HeaderOrigin, "https://a.example.com")
+	assert.Equal(t, "https://a.example.com", rec.Header().Get(routing.HeaderAccessControlAllowOrigin))
+	rec = cacheRequest(m, "/", routing.HeaderOrigin, "https://b.example.com")
+	assert.Equal(t, "HIT", rec.Header().Get(routing.HeaderXCache))
+	assert.Equal(t, "https://b.example.com", rec.Header().Get(routing.HeaderAccessControlAllowOrigin))
+	rec = cacheRequest(m, "/", routing.HeaderOrigin, "https://c.example.com")
+	assert.Equal(t, "", rec.Header().Get(routing.HeaderAccessControlAllowOrigin))
+}
+
+func TestCacheNotModified(t *testing.T) {
+	m := routing.New()
+	m.Use(Cache(NewCacheMemoryStore(10)), ETag())
+	m.GET("/", func(c routing.Context) error {
+		return c.String(http.StatusOK, "test")
+	})
+
+	etag := cacheRequest(m, "/").Header().Get(routing.HeaderETag)
+	require.NotEmpty(t, etag)
+	rec := cacheRequest(m, "/", routing.HeaderIfNoneMatch, etag)
+	assert.Equal(t, http.StatusNotModified, rec.Code)
+	assert.Equal(t, "HIT", rec.Header().Get(routing.HeaderXCache))
+	assert.Equal(t, 0, rec.Body.Len())
+}
+
+func TestCacheStampede(t *testing.T) {
+	var calls int32
+	release := make(chan struct{})
+	m := routing.New()
+	m.Use(Cache(NewCacheMemoryStore(10)))
+	m.GET("/", func(c routing.Context) error {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return c.String(http.StatusOK, "test")
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.Equal(t, "test", cacheRequest(m, "/").Body.String())
+		}()
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestCacheMemoryStore(t *testing.T) {
+	store := NewCacheMemoryStore(2)
+	store.Set("a", &CacheEntry{Tags: []string{"products"}}, time.Minute)
+	store.Set("b", &CacheEntry{Tags: []string{"products", "featured"}}, time.Minute)
+
+	// Least recently used entry is evicted
+	_, ok := store.Get("a")
+	assert.True(t, ok)
+	store.Set("c", &CacheEntry{Tags: []string{"featured"}}, time.Minute)
+	_, ok = store.Get("b")
+	assert.False(t, ok)
+	assert.Equal(t, 2, store.Len())
+
+	store.InvalidateTags("featured")
+	_, ok = store.Get("c")
+	assert.False(t, ok)
+	_, ok = store.Get("a")
+	assert.True(t, ok)
+
+	store.InvalidateTags("products")
+	assert.Equal(t, 0, store.Len())
+	assert.Empty(t, store.tags)
+
+	store.Set("a", &CacheEntry{}, time.Minute)
+	store.Delete("a")
+	assert.Equal(t, 0, store.Len())
+}
+
+func TestCacheTags(t *testing.T) {
+	store := NewCacheMemoryStore(10)
+	m := routing.New()
+	m.Use(CacheWithConfig(CacheConfig{Store: store, Tags: []string{"catalog"}}))
+	m.GET("/products/:id", func(c routing.Context) error {
+		CacheTags(c, "product:"+c.Param("id"))
+		return c.String(http.StatusOK, time.Now().String())
+	})
+
+	cacheRequest(m, "/products/1")
+	cacheRequest(m, "/products/2")
+	assert.Equal(t, 2, store.Len())
+	store.InvalidateTags("product:1")
+	assert.Equal(t, "MISS", cacheRequest(m, "/products/1").Header().Get(routing.HeaderXCache))
+	assert.Equal(t, "HIT", cacheRequest(m, "/products/2").Header().Get(routing.HeaderXCache))
+	store.InvalidateTags("catalog")
+	assert.Equal(t, 0, store.Len())
+}
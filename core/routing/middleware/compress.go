@@ -0,0 +1,439 @@
+package middleware
+
+import (
+	"bufio"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"goplugins/core/routing"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+)
+
+type (
+	// CompressConfig defines the config for Compress middleware.
+	CompressConfig struct {
+		// Skipper defines a function to skip middleware.
+		Skipper Skipper
+
+		// Encodings offered, in order of preference if the client accepts
+		// several of them equally.
+		// Optional. Default value []string{"br", "gzip", "deflate"}.
+		Encodings []string
+
+		// Level is the compression level of gzip and deflate.
+		// Optional. Default value -1 (gzip.DefaultCompression).
+		Level int
+
+		// BrotliLevel is the compression level of brotli, 0 to 11.
+		// Optional. Default value 4, which compresses better than gzip at
+		// a similar speed.
+		BrotliLevel int
+
+		// MinLength is the minimum size of the response body to be compressed.
+		// Smaller bodies are sent as they are, unless the response is flushed.
+		// Optional. Default value 1024.
+		MinLength int
+
+		// SkipContentTypes are media types, or prefixes of media types ending
+		// in "/", which are already compressed.
+		// Optional. Default value DefaultCompressConfig.SkipContentTypes.
+		SkipContentTypes []string
+	}
+
+	// DecompressConfig defines the config for Decompress middleware.
+	DecompressConfig struct {
+		// Skipper defines a function to skip middleware.
+		Skipper Skipper
+	}
+
+	// encoder is implemented by the writers of all supported encodings.
+	encoder interface {
+		io.WriteCloser
+		Flush() error
+		Reset(w io.Writer)
+	}
+
+	compressResponseWriter struct {
+		http.ResponseWriter
+		config   *CompressConfig
+		encoding string
+		pool     *sync.Pool
+		encoder  encoder
+		buf      []byte
+		code     int
+		decided  bool
+		hijacked bool
+	}
+)
+
+// Encodings
+const (
+	brotliScheme  = "br"
+	gzipScheme    = "gzip"
+	deflateScheme = "deflate"
+)
+
+var (
+	// DefaultCompressConfig is the default Compress middleware config.
+	DefaultCompressConfig = CompressConfig{
+		Skipper:     DefaultSkipper,
+		Encodings:   []string{brotliScheme, gzipScheme, deflateScheme},
+		Level:       gzip.DefaultCompression,
+		BrotliLevel: 4,
+		MinLength:   1024,
+		SkipContentTypes: []string{
+			"image/png", "image/jpeg", "image/gif", "image/webp", "image/avif",
+			"video/", "audio/", "font/woff", "font/woff2",
+			"application/zip", "application/gzip", "application/x-gzip",
+			"application/x-bzip2", "application/x-7z-compressed", "application/x-rar-compressed",
+		},
+	}
+
+	// DefaultDecompressConfig is the default Decompress middleware config.
+	DefaultDecompressConfig = DecompressConfig{
+		Skipper: DefaultSkipper,
+	}
+
+	// ErrUnsupportedContentEncoding is returned for request bodies with an
+	// unknown Content-Encoding.
+	ErrUnsupportedContentEncoding = routing.NewHTTPError(http.StatusUnsupportedMediaType, "unsupported content encoding")
+)
+
+// Compress returns a middleware which compresses HTTP responses with brotli,
+// gzip or deflate, as accepted by the client.
+func Compress() routing.MiddlewareFunc {
+	return CompressWithConfig(DefaultCompressConfig)
+}
+
+// CompressWithConfig returns a Compress middleware with config.
+// See: `Compress()`.
+//
+// The response is buffered until MinLength bytes are written, so small bodies
+// can be sent uncompressed. Flushing the response, e.g. when streaming,
+// starts compressing right away; the compressed data written so far is
+// flushed to the client as well. Hijacked connections are left alone.
+func CompressWithConfig(config CompressConfig) routing.MiddlewareFunc {
+	// Defaults
+	if config.Skipper == nil {
+		config.Skipper = DefaultCompressConfig.Skipper
+	}
+	if len(config.Encodings) == 0 {
+		config.Encodings = DefaultCompressConfig.Encodings
+	}
+	if config.Level == 0 {
+		config.Level = DefaultCompressConfig.Level
+	}
+	if config.BrotliLevel == 0 {
+		config.BrotliLevel = DefaultCompressConfig.BrotliLevel
+	}
+	if config.MinLength == 0 {
+		config.MinLength = DefaultCompressConfig.MinLength
+	}
+	if config.SkipContentTypes == nil {
+		config.SkipContentTypes = DefaultCompressConfig.SkipContentTypes
+	}
+
+	pools := map[string]*sync.Pool{}
+	for _, e := range config.Encodings {
+		var pool *sync.Pool
+		switch e {
+		case brotliScheme:
+			pool = &sync.Pool{New: func() interface{} {
+				return brotli.NewWriterLevel(ioutil.Discard, config.BrotliLevel)
+			}}
+		case gzipScheme:
+			if _, err := gzip.NewWriterLevel(nil, config.Level); err != nil {
+				panic("routing: invalid compression level " + strconv.Itoa(config.Level))
+			}
+			pool = &sync.Pool{New: func() interface{} {
+				w, _ := gzip.NewWriterLevel(nil, config.Level)
+				return w
+			}}
+		case deflateScheme:
+			if _, err := zlib.NewWriterLevel(nil, config.Level); err != nil {
+				panic("routing: invalid compression level " + strconv.Itoa(config.Level))
+			}
+			pool = &sync.Pool{New: func() interface{} {
+				w, _ := zlib.NewWriterLevel(nil, config.Level)
+				return w
+			}}
+		default:
+			panic("routing: unsupported encoding " + e)
+		}
+		pools[e] = pool
+	}
+
+	return func(next routing.HandlerFunc) routing.HandlerFunc {
+		return func(c routing.Context) error {
+			if config.Skipper(c) || c.IsWebSocket() {
+				return next(c)
+			}
+
+			res := c.Response()
+			res.Header().Add(routing.HeaderVary, routing.HeaderAcceptEncoding)
+			encoding := negotiateEncoding(c.Request().Header.Get(routing.HeaderAcceptEncoding), config.Encodings)
+			if encoding == "" || c.Request().Method == http.MethodHead {
+				return next(c)
+			}
+
+			rw := res.Writer
+			w := &compressResponseWriter{
+				ResponseWriter: rw,
+				config:         &config,
+				encoding:       encoding,
+				pool:           pools[encoding],
+			}
+			res.Writer = w
+			defer func() {
+				w.close()
+				res.Writer = rw
+			}()
+			return next(c)
+		}
+	}
+}
+
+// Decompress returns a middleware which decompresses request bodies sent
+// with Content-Encoding gzip, deflate or br.
+func Decompress() routing.MiddlewareFunc {
+	return DecompressWithConfig(DefaultDecompressConfig)
+}
+
+// DecompressWithConfig returns a Decompress middleware with config.
+// See: `Decompress()`.
+//
+// Limit the size of the decompressed body using the BodyLimit middleware
+// after this one.
+func DecompressWithConfig(config DecompressConfig) routing.MiddlewareFunc {
+	// Defaults
+	if config.Skipper == nil {
+		config.Skipper = DefaultDecompressConfig.Skipper
+	}
+
+	return func(next routing.HandlerFunc) routing.HandlerFunc {
+		return func(c routing.Context) error {
+			if config.Skipper(c) {
+				return next(c)
+			}
+
+			req := c.Request()
+			encoding := strings.ToLower(strings.TrimSpace(req.Header.Get(routing.HeaderContentEncoding)))
+			if encoding == "" || encoding == "identity" || req.Body == nil || req.Body == http.NoBody {
+				return next(c)
+			}
+
+			var body io.ReadCloser
+			switch encoding {
+			case gzipScheme, "x-gzip":
+				r, err := gzip.NewReader(req.Body)
+				if err != nil {
+					if err == io.EOF {
+						return next(c)
+					}
+					return routing.NewHTTPError(http.StatusBadRequest, "invalid gzip body").SetInternal(err)
+				}
+				body = r
+			case deflateScheme:
+				r, err := zlib.NewReader(req.Body)
+				if err != nil {
+					return routing.NewHTTPError(http.StatusBadRequest, "invalid deflate body").SetInternal(err)
+				}
+				body = r
+			case brotliScheme:
+				body = ioutil.NopCloser(brotli.NewReader(req.Body))
+			default:
+				return ErrUnsupportedContentEncoding
+			}
+
+			orig := req.Body
+			defer orig.Close()
+			defer body.Close()
+			req.Body = body
+			req.Header.Del(routing.HeaderContentEncoding)
+			req.Header.Del(routing.HeaderContentLength)
+			req.ContentLength = -1
+			return next(c)
+		}
+	}
+}
+
+// negotiateEncoding returns the encoding of offered the client prefers
+// according to the Accept-Encoding header, or "" if none is acceptable.
+func negotiateEncoding(header string, offered []string) string {
+	if header == "" {
+		return ""
+	}
+	accepted := map[string]float64{}
+	for _, part := range strings.Split(header, ",") {
+		params := strings.Split(part, ";")
+		name := strings.ToLower(strings.TrimSpace(params[0]))
+		if name == "" {
+			continue
+		}
+		q := 1.0
+		for _, p := range params[1:] {
+			p = strings.TrimSpace(p)
+			if strings.HasPrefix(p, "q=") {
+				if v, err := strconv.ParseFloat(p[2:], 64); err == nil {
+					q = v
+				}
+			}
+		}
+		accepted[name] = q
+	}
+
+	best, bestQ := "", 0.0
+	for _, e := range offered {
+		q, ok := accepted[e]
+		if !ok {
+			q = accepted["*"]
+		}
+		if q > bestQ {
+			best, bestQ = e, q
+		}
+	}
+	return best
+}
+
+func (w *compressResponseWriter) WriteHeader(code int) {
+	if w.decided {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	w.code = code
+}
+
+func (w *compressResponseWriter) Write(b []byte) (int, error) {
+	if !w.decided {
+		w.buf = append(w.buf, b...)
+		if len(w.buf) >= w.config.MinLength {
+			if err := w.decide(false); err != nil {
+				return 0, err
+			}
+		}
+		return len(b), nil
+	}
+	if w.encoder != nil {
+		return w.encoder.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *compressResponseWriter) Flush() {
+	if !w.decided {
+		if err := w.decide(true); err != nil {
+			return
+		}
+	}
+	if w.encoder != nil {
+		w.encoder.Flush()
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *compressResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("routing: response does not implement http.Hijacker")
+	}
+	w.decided = true
+	w.hijacked = true
+	return h.Hijack()
+}
+
+// decide writes the header and the buffered body, compressed if the
+// response qualifies. force compresses bodies smaller than MinLength.
+func (w *compressResponseWriter) decide(force bool) error {
+	w.decided = true
+	header := w.Header()
+	code := w.code
+	if code == 0 {
+		code = http.StatusOK
+	}
+
+	if header.Get(routing.HeaderContentType) == "" && len(w.buf) > 0 {
+		// Sniff before compressing, net/http would sniff the compressed data.
+		header.Set(routing.HeaderContentType, http.DetectContentType(w.buf))
+	}
+
+	if w.compressible(code, force) {
+		header.Set(routing.HeaderContentEncoding, w.encoding)
+		header.Del(routing.HeaderContentLength)
+		if etag := header.Get(routing.HeaderETag); etag != "" && !strings.HasPrefix(etag, "W/") {
+			// The compressed representation is not byte-for-byte the same.
+			header.Set(routing.HeaderETag, "W/"+etag)
+		}
+		w.encoder = w.pool.Get().(encoder)
+		w.encoder.Reset(w.ResponseWriter)
+	}
+
+	w.ResponseWriter.WriteHeader(code)
+	if len(w.buf) == 0 {
+		return nil
+	}
+	buf := w.buf
+	w.buf = nil
+	var err error
+	if w.encoder != nil {
+		_, err = w.encoder.Write(buf)
+	} else {
+		_, err = w.ResponseWriter.Write(buf)
+	}
+	return err
+}
+
+func (w *compressResponseWriter) compressible(code int, force bool) bool {
+	header := w.Header()
+	if code < http.StatusOK || code == http.StatusNoContent || code == http.StatusNotModified ||
+		code == http.StatusPartialContent {
+		return false
+	}
+	if header.Get(routing.HeaderContentEncoding) != "" || header.Get(routing.HeaderContentRange) != "" {
+		return false
+	}
+	if !force && len(w.buf) < w.config.MinLength {
+		return false
+	}
+	contentType := header.Get(routing.HeaderContentType)
+	if contentType == "" {
+		return false
+	}
+	mediaType := strings.ToLower(strings.TrimSpace(strings.Split(contentType, ";")[0]))
+	for _, t := range w.config.SkipContentTypes {
+		if strings.HasSuffix(t, "/") && strings.HasPrefix(mediaType, t) || mediaType == t {
+			return false
+		}
+	}
+	return true
+}
+
+// close finishes the response once the handler has returned.
+func (w *compressResponseWriter) close() {
+	if w.hijacked {
+		return
+	}
+	if !w.decided {
+		if len(w.buf) == 0 && w.code == 0 {
+			// Nothing has been written, e.g. the handler returned an error,
+			// which is written by the error handler using the original writer.
+			return
+		}
+		w.decide(false)
+	}
+	if w.encoder != nil {
+		w.encoder.Close()
+		w.encoder.Reset(ioutil.Discard)
+		w.pool.Put(w.encoder)
+		w.encoder = nil
+	}
+}
@@ -0,0 +1,282 @@
+package middleware
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"goplugins/core/routing"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var compressBody = strings.Repeat("test ", 512)
+
+func compressRequest(h routing.HandlerFunc, mw routing.MiddlewareFunc, acceptEncoding string) *httptest.ResponseRecorder {
+	m := routing.New()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if acceptEncoding != "" {
+		req.Header.Set(routing.HeaderAcceptEncoding, acceptEncoding)
+	}
+	rec := httptest.NewRecorder()
+	c := m.NewContext(req, rec)
+	if err := mw(h)(c); err != nil {
+		m.HTTPErrorHandler(err, c)
+	}
+	return rec
+}
+
+func TestCompress(t *testing.T) {
+	h := func(c routing.Context) error {
+		return c.String(http.StatusOK, compressBody)
+	}
+
+	// Gzip
+	rec := compressRequest(h, Compress(), "gzip")
+	assert.Equal(t, gzipScheme, rec.Header().Get(routing.HeaderContentEncoding))
+	assert.Equal(t, routing.HeaderAcceptEncoding, rec.Header().Get(routing.HeaderVary))
+	assert.Equal(t, routing.MIMETextPlainCharsetUTF8, rec.Header().Get(routing.HeaderContentType))
+	r, err := gzip.NewReader(rec.Body)
+	require.NoError(t, err)
+	b, err := ioutil.ReadAll(r)
+	require.NoError(t, err)
+	assert.Equal(t, compressBody, string(b))
+
+	// Deflate
+	rec = compressRequest(h, Compress(), "deflate")
+	assert.Equal(t, deflateScheme, rec.Header().Get(routing.HeaderContentEncoding))
+	zr, err := zlib.NewReader(rec.Body)
+	require.NoError(t, err)
+	b, err = ioutil.ReadAll(zr)
+	require.NoError(t, err)
+	assert.Equal(t, compressBody, string(b))
+
+	// Brotli
+	rec = compressRequest(h, Compress(), "gzip, deflate, br")
+	assert.Equal(t, brotliScheme, rec.Header().Get(routing.HeaderContentEncoding))
+	b, err = ioutil.ReadAll(brotli.NewReader(rec.Body))
+	require.NoError(t, err)
+	assert.Equal(t, compressBody, string(b))
+
+	// Not accepted
+	rec = compressRequest(h, Compress(), "")
+	assert.Equal(t, "", rec.Header().Get(routing.HeaderContentEncoding))
+	assert.Equal(t, compressBody, rec.Body.String())
+	rec = compressRequest(h, Compress(), "identity")
+	assert.Equal(t, "", rec.Header().Get(routing.HeaderContentEncoding))
+	assert.Equal(t, compressBody, rec.Body.String())
+}
+
+func TestCompressNegotiation(t *testing.T) {
+	offered := DefaultCompressConfig.Encodings
+	for header, want := range map[string]string{
+		"gzip":                        "gzip",
+		"GZIP":                        "gzip",
+		"gzip, br":                    "br",
+		"gzip;q=1.0, br;q=0.5":        "gzip",
+		"br;q=0, deflate":             "deflate",
+		"*":                           "br",
+		"*, br;q=0":                   "gzip",
+		"*;q=0":                       "",
+		"compress, identity":          "",
+		"deflate;q=0.8, gzip;q=0.8":   "gzip",
+		" deflate ; q=0.9 , gzip;q=x": "gzip",
+	} {
+		assert.Equal(t, want, negotiateEncoding(header, offered), header)
+	}
+}
+
+func TestCompressSkip(t *testing.T) {
+	// Small body
+	rec := compressRequest(func(c routing.Context) error {
+		return c.String(http.StatusOK, "test")
+	}, Compress(), "gzip")
+	assert.Equal(t, "", rec.Header().Get(routing.HeaderContentEncoding))
+	assert.Equal(t, "test", rec.Body.String())
+
+	// Compressed content type
+	rec = compressRequest(func(c routing.Context) error {
+		return c.Blob(http.StatusOK, "image/png", []byte(compressBody))
+	}, Compress(), "gzip")
+	assert.Equal(t, "", rec.Header().Get(routing.HeaderContentEncoding))
+	assert.Equal(t, compressBody, rec.Body.String())
+
+	// Already encoded
+	rec = compressRequest(func(c routing.Context) error {
+		c.Response().Header().Set(routing.HeaderContentEncoding, "identity")
+		return c.String(http.StatusOK, compressBody)
+	}, Compress(), "gzip")
+	assert.Equal(t, "identity", rec.Header().Get(routing.HeaderContentEncoding))
+	assert.Equal(t, compressBody, rec.Body.String())
+
+	// No content
+	rec = compressRequest(func(c routing.Context) error {
+		return c.NoContent(http.StatusNoContent)
+	}, Compress(), "gzip")
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.Equal(t, "", rec.Header().Get(routing.HeaderContentEncoding))
+	assert.Equal(t, 0, rec.Body.Len())
+
+	// Error
+	rec = compressRequest(func(c routing.Context) error {
+		return routing.ErrNotFound
+	}, Compress(), "gzip")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Equal(t, "", rec.Header().Get(routing.HeaderContentEncoding))
+
+	// Skipper
+	rec = compressRequest(func(c routing.Context) error {
+		return c.String(http.StatusOK, compressBody)
+	}, CompressWithConfig(CompressConfig{
+		Skipper: func(routing.Context) bool { return true },
+	}), "gzip")
+	assert.Equal(t, "", rec.Header().Get(routing.HeaderContentEncoding))
+	assert.Equal(t, compressBody, rec.Body.String())
+}
+
+func TestCompressHeaders(t *testing.T) {
+	rec := compressRequest(func(c routing.Context) error {
+		c.Response().Header().Set(routing.HeaderETag, `"abc"`)
+		c.Response().Header().Set(routing.HeaderContentLength, "2560")
+		c.Response().WriteHeader(http.StatusCreated)
+		_, err := c.Response().Write([]byte(compressBody))
+		return err
+	}, Compress(), "gzip")
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Equal(t, gzipScheme, rec.Header().Get(routing.HeaderContentEncoding))
+	assert.Equal(t, `W/"abc"`, rec.Header().Get(routing.HeaderETag))
+	assert.Equal(t, "", rec.Header().Get(routing.HeaderContentLength))
+	// Sniffed before compressing.
+	assert.Equal(t, "text/plain; charset=utf-8", rec.Header().Get(routing.HeaderContentType))
+}
+
+func TestCompressFlush(t *testing.T) {
+	rec := compressRequest(func(c routing.Context) error {
+		c.Response().Header().Set(routing.HeaderContentType, routing.MIMETextPlain)
+		c.Response().WriteHeader(http.StatusOK)
+		c.Response().Write([]byte("first"))
+		c.Response().Flush()
+		assert.Equal(t, gzipScheme, c.Response().Header().Get(routing.HeaderContentEncoding))
+		c.Response().Write([]byte("second"))
+		return nil
+	}, Compress(), "gzip")
+	assert.True(t, rec.Flushed)
+	r, err := gzip.NewReader(rec.Body)
+	require.NoError(t, err)
+	b, err := ioutil.ReadAll(r)
+	require.NoError(t, err)
+	assert.Equal(t, "firstsecond", string(b))
+}
+
+func TestCompressStream(t *testing.T) {
+	rec := compressRequest(func(c routing.Context) error {
+		return c.Stream(http.StatusOK, routing.MIMEApplicationJSON, strings.NewReader(compressBody))
+	}, Compress(), "gzip")
+	assert.Equal(t, gzipScheme, rec.Header().Get(routing.HeaderContentEncoding))
+	r, err := gzip.NewReader(rec.Body)
+	require.NoError(t, err)
+	b, err := ioutil.ReadAll(r)
+	require.NoError(t, err)
+	assert.Equal(t, compressBody, string(b))
+}
+
+func TestCompressHijack(t *testing.T) {
+	m := routing.New()
+	m.Use(Compress())
+	m.GET("/", func(c routing.Context) error {
+		conn, _, err := c.Response().Hijack()
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+		_, err = conn.Write([]byte("HTTP/1.1 200 OK\r\nContent-Length: 4\r\n\r\ntest"))
+		return err
+	})
+	srv := httptest.NewServer(m)
+	defer srv.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+	req.Header.Set(routing.HeaderAcceptEncoding, "gzip")
+	res, err := http.DefaultTransport.RoundTrip(req)
+	require.NoError(t, err)
+	defer res.Body.Close()
+	b, err := ioutil.ReadAll(res.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "", res.Header.Get(routing.HeaderContentEncoding))
+	assert.Equal(t, "test", string(b))
+}
+
+func TestCompressPool(t *testing.T) {
+	mw := CompressWithConfig(CompressConfig{MinLength: 1})
+	for i := 0; i < 3; i++ {
+		body := strings.Repeat("x", i+1)
+		rec := compressRequest(func(c routing.Context) error {
+			return c.String(http.StatusOK, body)
+		}, mw, "br")
+		b, err := ioutil.ReadAll(brotli.NewReader(rec.Body))
+		require.NoError(t, err)
+		assert.Equal(t, body, string(b))
+	}
+}
+
+func TestCompressInvalidConfig(t *testing.T) {
+	assert.Panics(t, func() {
+		CompressWithConfig(CompressConfig{Encodings: []string{"compress"}})
+	})
+	assert.Panics(t, func() {
+		CompressWithConfig(CompressConfig{Level: 42})
+	})
+}
+
+func TestDecompress(t *testing.T) {
+	h := func(c routing.Context) error {
+		b, err := ioutil.ReadAll(c.Request().Body)
+		if err != nil {
+			return err
+		}
+		assert.Equal(t, "", c.Request().Header.Get(routing.HeaderContentEncoding))
+		return c.String(http.StatusOK, string(b))
+	}
+	encode := map[string]func(io.Writer) io.WriteCloser{
+		"gzip":    func(w io.Writer) io.WriteCloser { return gzip.NewWriter(w) },
+		"deflate": func(w io.Writer) io.WriteCloser { return zlib.NewWriter(w) },
+		"br":      func(w io.Writer) io.WriteCloser { return brotli.NewWriter(w) },
+	}
+
+	m := routing.New()
+	for encoding, newWriter := range encode {
+		body := new(bytes.Buffer)
+		w := newWriter(body)
+		w.Write([]byte(compressBody))
+		w.Close()
+
+		req := httptest.NewRequest(http.MethodPost, "/", body)
+		req.Header.Set(routing.HeaderContentEncoding, encoding)
+		rec := httptest.NewRecorder()
+		c := m.NewContext(req, rec)
+		require.NoError(t, Decompress()(h)(c), encoding)
+		assert.Equal(t, compressBody, rec.Body.String(), encoding)
+	}
+
+	// Invalid body
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("test"))
+	req.Header.Set(routing.HeaderContentEncoding, "gzip")
+	c := m.NewContext(req, httptest.NewRecorder())
+	err := Decompress()(h)(c)
+	if assert.IsType(t, &routing.HTTPError{}, err) {
+		assert.Equal(t, http.StatusBadRequest, err.(*routing.HTTPError).Code)
+	}
+
+	// Unsupported encoding
+	req = httptest.NewRequest(http.MethodPost, "/", strings.NewReader("test"))
+	req.Header.Set(routing.HeaderContentEncoding, "compress")
+	c = m.NewContext(req, httptest.NewRecorder())
+	assert.Equal(t, ErrUnsupportedContentEncoding, Decompress()(h)(c))
+}
@@ -0,0 +1,262 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"goplugins/core/framework/config"
+	"goplugins/core/routing"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+type (
+	// CORSConfig defines the config for CORS middleware.
+	CORSConfig struct {
+		// Skipper defines a function to skip middleware.
+		Skipper Skipper
+
+		// AllowOrigins defines a list of origins that may access the resource.
+		// An origin may contain a wildcard for subdomains, e.g.
+		// "https://*.example.com", or be "*" to allow all origins. "*" cannot
+		// be combined with AllowCredentials.
+		// Optional. Default value []string{"*"}.
+		AllowOrigins []string
+
+		// AllowOriginPatterns defines a list of regular expressions matching
+		// origins that may access the resource, e.g. `^https://[a-z]+\.example\.com$`.
+		// Optional.
+		AllowOriginPatterns []string
+
+		// AllowOriginFunc is a custom function to validate the origin. It takes the
+		// origin as an argument and returns true if allowed or false otherwise. If
+		// an error is returned, it is returned by the handler. If this option is
+		// set, AllowOrigins and AllowOriginPatterns are ignored.
+		// Optional.
+		AllowOriginFunc func(origin string) (bool, error)
+
+		// AllowMethods defines a list methods allowed when accessing the resource.
+		// This is used in response to a preflight request.
+		// Optional. Default value DefaultCORSConfig.AllowMethods.
+		AllowMethods []string
+
+		// AllowHeaders defines a list of request headers that can be used when
+		// making the actual request. This is in response to a preflight request.
+		// Optional. Default value []string{}, which allows the headers requested
+		// by the preflight request.
+		AllowHeaders []string
+
+		// AllowCredentials indicates whether or not the response to the request
+		// can be exposed when the credentials flag is true. When used as part of
+		// a response to a preflight request, this indicates whether or not the
+		// actual request can be made using credentials. Only origins which are
+		// allowed explicitly are sent back in this case.
+		// Optional. Default value false.
+		AllowCredentials bool
+
+		// ExposeHeaders defines a whitelist headers that clients are allowed to
+		// access.
+		// Optional. Default value []string{}.
+		ExposeHeaders []string
+
+		// MaxAge indicates how long (in seconds) the results of a preflight request
+		// can be cached.
+		// Optional. Default value 0.
+		MaxAge int
+	}
+)
+
+var (
+	// DefaultCORSConfig is the default CORS middleware config.
+	DefaultCORSConfig = CORSConfig{
+		Skipper:      DefaultSkipper,
+		AllowOrigins: []string{"*"},
+		AllowMethods: []string{http.MethodGet, http.MethodHead, http.MethodPut, http.MethodPatch, http.MethodPost, http.MethodDelete},
+	}
+)
+
+// CORS returns a Cross-Origin Resource Sharing (CORS) middleware.
+// See: https://developer.mozilla.org/en/docs/Web/HTTP/Access_control_CORS
+func CORS() routing.MiddlewareFunc {
+	return CORSWithConfig(DefaultCORSConfig)
+}
+
+// CORSFromConfig returns a CORS middleware configured by the CORS_* settings.
+// Unlike CORSWithConfig it returns an error for invalid settings.
+func CORSFromConfig(cfg config.CORS) (routing.MiddlewareFunc, error) {
+	c := CORSConfig{
+		AllowOrigins:        cfg.AllowOrigins,
+		AllowOriginPatterns: cfg.AllowOriginPatterns,
+		AllowMethods:        cfg.AllowMethods,
+		AllowHeaders:        cfg.AllowHeaders,
+		AllowCredentials:    cfg.AllowCredentials,
+		ExposeHeaders:       cfg.ExposeHeaders,
+		MaxAge:              cfg.MaxAge,
+	}
+	if _, err := corsPatterns(c); err != nil {
+		return nil, err
+	}
+	return CORSWithConfig(c), nil
+}
+
+// CORSWithConfig returns a CORS middleware with config.
+// See: `CORS()`.
+//
+// Preflight requests are answered by the middleware itself, so no OPTIONS
+// routes are needed. Register it using `Mux#Pre()` to answer preflight
+// requests for paths which are not routed for every method. It panics if
+// AllowCredentials is combined with the origin "*", which would let any site
+// make credentialed requests.
+func CORSWithConfig(config CORSConfig) routing.MiddlewareFunc {
+	// Defaults
+	if config.Skipper == nil {
+		config.Skipper = DefaultCORSConfig.Skipper
+	}
+	if len(config.AllowOrigins) == 0 && len(config.AllowOriginPatterns) == 0 {
+		config.AllowOrigins = DefaultCORSConfig.AllowOrigins
+	}
+	if len(config.AllowMethods) == 0 {
+		config.AllowMethods = DefaultCORSConfig.AllowMethods
+	}
+	patterns, err := corsPatterns(config)
+	if err != nil {
+		panic(err.Error())
+	}
+
+	allowMethods := strings.Join(config.AllowMethods, ",")
+	allowHeaders := strings.Join(config.AllowHeaders, ",")
+	exposeHeaders := strings.Join(config.ExposeHeaders, ",")
+	maxAge := strconv.Itoa(config.MaxAge)
+
+	allowOrigin := func(origin string) (string, error) {
+		if config.AllowOriginFunc != nil {
+			allowed, err := config.AllowOriginFunc(origin)
+			if err != nil || !allowed {
+				return "", err
+			}
+			return origin, nil
+		}
+		for _, o := range config.AllowOrigins {
+			if o == "*" {
+				return "*", nil
+			}
+			if strings.EqualFold(o, origin) {
+				return origin, nil
+			}
+		}
+		for _, re := range patterns {
+			if re.MatchString(origin) {
+				return origin, nil
+			}
+		}
+		return "", nil
+	}
+
+	return func(next routing.HandlerFunc) routing.HandlerFunc {
+		return func(c routing.Context) error {
+			if config.Skipper(c) {
+				return next(c)
+			}
+
+			req := c.Request()
+			res := c.Response()
+			origin := req.Header.Get(routing.HeaderOrigin)
+			preflight := req.Method == http.MethodOptions && req.Header.Get(routing.HeaderAccessControlRequestMethod) != ""
+
+			res.Header().Add(routing.HeaderVary, routing.HeaderOrigin)
+
+			// No Origin provided, not a CORS request
+			if origin == "" {
+				return next(c)
+			}
+
+			allowed, err := allowOrigin(origin)
+			if err != nil {
+				return err
+			}
+
+			// Origin not allowed
+			if allowed == "" {
+				if !preflight {
+					return next(c)
+				}
+				return c.NoContent(http.StatusNoContent)
+			}
+
+			// Simple request
+			if !preflight {
+				res.Header().Set(routing.HeaderAccessControlAllowOrigin, allowed)
+				if config.AllowCredentials {
+					res.Header().Set(routing.HeaderAccessControlAllowCredentials, "true")
+				}
+				if exposeHeaders != "" {
+					res.Header().Set(routing.HeaderAccessControlExposeHeaders, exposeHeaders)
+				}
+				return next(c)
+			}
+
+			// Preflight request
+			res.Header().Add(routing.HeaderVary, routing.HeaderAccessControlRequestMethod)
+			res.Header().Add(routing.HeaderVary, routing.HeaderAccessControlRequestHeaders)
+			res.Header().Set(routing.HeaderAccessControlAllowOrigin, allowed)
+			res.Header().Set(routing.HeaderAccessControlAllowMethods, allowMethods)
+			if config.AllowCredentials {
+				res.Header().Set(routing.HeaderAccessControlAllowCredentials, "true")
+			}
+			if allowHeaders != "" {
+				res.Header().Set(routing.HeaderAccessControlAllowHeaders, allowHeaders)
+			} else {
+				h := req.Header.Get(routing.HeaderAccessControlRequestHeaders)
+				if h != "" {
+					res.Header().Set(routing.HeaderAccessControlAllowHeaders, h)
+				}
+			}
+			if config.MaxAge > 0 {
+				res.Header().Set(routing.HeaderAccessControlMaxAge, maxAge)
+			}
+			return c.NoContent(http.StatusNoContent)
+		}
+	}
+}
+
+// wildcardOrigin returns a pattern matching origin, in which every "*"
+// stands for one or more subdomain labels.
+// corsPatterns validates the origins of config and compiles its wildcard
+// origins and origin patterns. Empty origins allow all origins, like the
+// default config.
+func corsPatterns(config CORSConfig) ([]*regexp.Regexp, error) {
+	if config.AllowCredentials && config.AllowOriginFunc == nil {
+		if len(config.AllowOrigins) == 0 && len(config.AllowOriginPatterns) == 0 {
+			return nil, errors.New("routing: CORS origin \"*\" cannot be combined with AllowCredentials")
+		}
+		for _, o := range config.AllowOrigins {
+			if o == "*" {
+				return nil, errors.New("routing: CORS origin \"*\" cannot be combined with AllowCredentials")
+			}
+		}
+	}
+
+	patterns := make([]*regexp.Regexp, 0, len(config.AllowOrigins)+len(config.AllowOriginPatterns))
+	for _, o := range config.AllowOrigins {
+		if o != "*" && strings.Contains(o, "*") {
+			patterns = append(patterns, wildcardOrigin(o))
+		}
+	}
+	for _, p := range config.AllowOriginPatterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("routing: invalid CORS origin pattern %q: %v", p, err)
+		}
+		patterns = append(patterns, re)
+	}
+	return patterns, nil
+}
+
+func wildcardOrigin(origin string) *regexp.Regexp {
+	parts := strings.Split(origin, "*")
+	for i, p := range parts {
+		parts[i] = regexp.QuoteMeta(p)
+	}
+	return regexp.MustCompile("(?i)^" + strings.Join(parts, `[a-z0-9-]+(\.[a-z0-9-]+)*`) + "$")
+}
@@ -0,0 +1,139 @@
+package middleware
+
+import (
+	"goplugins/core/framework/config"
+	"goplugins/core/routing"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCORS(t *testing.T) {
+	m := routing.New()
+
+	// Wildcard origin
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rec := httptest.NewRecorder()
+	c := m.NewContext(req, rec)
+	h := CORS()(routing.NotFoundHandler)
+	req.Header.Set(routing.HeaderOrigin, "localhost")
+	h(c)
+	assert.Equal(t, "*", rec.Header().Get(routing.HeaderAccessControlAllowOrigin))
+
+	// Allow origins
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	rec = httptest.NewRecorder()
+	c = m.NewContext(req, rec)
+	h = CORSWithConfig(CORSConfig{
+		AllowOrigins:  []string{"localhost"},
+		ExposeHeaders: []string{"X-Total"},
+	})(routing.NotFoundHandler)
+	req.Header.Set(routing.HeaderOrigin, "localhost")
+	h(c)
+	assert.Equal(t, "localhost", rec.Header().Get(routing.HeaderAccessControlAllowOrigin))
+	assert.Equal(t, "X-Total", rec.Header().Get(routing.HeaderAccessControlExposeHeaders))
+
+	// Preflight request
+	req = httptest.NewRequest(http.MethodOptions, "/", nil)
+	rec = httptest.NewRecorder()
+	c = m.NewContext(req, rec)
+	req.Header.Set(routing.HeaderOrigin, "localhost")
+	req.Header.Set(routing.HeaderAccessControlRequestMethod, http.MethodPost)
+	req.Header.Set(routing.HeaderAccessControlRequestHeaders, "X-Custom")
+	cors := CORSWithConfig(CORSConfig{
+		AllowOrigins:     []string{"localhost"},
+		AllowCredentials: true,
+		MaxAge:           3600,
+	})
+	h = cors(routing.NotFoundHandler)
+	h(c)
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.Equal(t, "localhost", rec.Header().Get(routing.HeaderAccessControlAllowOrigin))
+	assert.NotEmpty(t, rec.Header().Get(routing.HeaderAccessControlAllowMethods))
+	assert.Equal(t, "X-Custom", rec.Header().Get(routing.HeaderAccessControlAllowHeaders))
+	assert.Equal(t, "true", rec.Header().Get(routing.HeaderAccessControlAllowCredentials))
+	assert.Equal(t, "3600", rec.Header().Get(routing.HeaderAccessControlMaxAge))
+
+	// Credentials can't be allowed for any origin
+	assert.Panics(t, func() {
+		CORSWithConfig(CORSConfig{AllowOrigins: []string{"*"}, AllowCredentials: true})
+	})
+	assert.Panics(t, func() {
+		CORSWithConfig(CORSConfig{AllowCredentials: true})
+	})
+	_, err := CORSFromConfig(config.CORS{AllowOrigins: []string{"https://app.example.com", "*"}, AllowCredentials: true})
+	assert.Error(t, err)
+}
+
+func TestCORSOrigins(t *testing.T) {
+	tests := []struct {
+		config  CORSConfig
+		origin  string
+		allowed bool
+	}{
+		{CORSConfig{AllowOrigins: []string{"https://example.com"}}, "https://example.com", true},
+		{CORSConfig{AllowOrigins: []string{"https://example.com"}}, "https://EXAMPLE.com", true},
+		{CORSConfig{AllowOrigins: []string{"https://example.com"}}, "http://example.com", false},
+		{CORSConfig{AllowOrigins: []string{"https://*.example.com"}}, "https://app.example.com", true},
+		{CORSConfig{AllowOrigins: []string{"https://*.example.com"}}, "https://a.b.example.com", true},
+		{CORSConfig{AllowOrigins: []string{"https://*.example.com"}}, "https://example.com", false},
+		{CORSConfig{AllowOrigins: []string{"https://*.example.com"}}, "https://evil.com/.example.com", false},
+		{CORSConfig{AllowOrigins: []string{"https://*.example.com"}}, "https://app.example.com.evil.com", false},
+		{CORSConfig{AllowOriginPatterns: []string{`^https://[a-z]+\.example\.(com|org)$`}}, "https://app.example.org", true},
+		{CORSConfig{AllowOriginPatterns: []string{`^https://[a-z]+\.example\.(com|org)$`}}, "https://app.example.net", false},
+		{CORSConfig{AllowOriginFunc: func(o string) (bool, error) { return o == "null", nil }}, "null", true},
+		{CORSConfig{AllowOrigins: []string{"https://*.example.com"}, AllowCredentials: true}, "https://app.example.com", true},
+		{CORSConfig{AllowOrigins: []string{"https://*.example.com"}, AllowCredentials: true}, "https://evil.example", false},
+	}
+	for _, tt := range tests {
+		m := routing.New()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(routing.HeaderOrigin, tt.origin)
+		rec := httptest.NewRecorder()
+		CORSWithConfig(tt.config)(routing.NotFoundHandler)(m.NewContext(req, rec))
+		if tt.allowed {
+			assert.Equal(t, tt.origin, rec.Header().Get(routing.HeaderAccessControlAllowOrigin), tt.origin)
+		} else {
+			assert.Empty(t, rec.Header().Get(routing.HeaderAccessControlAllowOrigin), tt.origin)
+		}
+	}
+
+	assert.Panics(t, func() {
+		CORSWithConfig(CORSConfig{AllowOriginPatterns: []string{"("}})
+	})
+	_, err := CORSFromConfig(config.CORS{AllowOriginPatterns: []string{"("}})
+	assert.Error(t, err)
+}
+
+func TestCORSPreflightWithoutRoute(t *testing.T) {
+	cors, err := CORSFromConfig(config.CORS{
+		AllowOrigins: []string{"https://app.example.com"},
+		AllowMethods: []string{http.MethodGet, http.MethodPost},
+	})
+	require.NoError(t, err)
+	m := routing.New()
+	m.Pre(cors)
+	m.POST("/users", func(c routing.Context) error {
+		return c.NoContent(http.StatusCreated)
+	})
+
+	req := httptest.NewRequest(http.MethodOptions, "/users", nil)
+	req.Header.Set(routing.HeaderOrigin, "https://app.example.com")
+	req.Header.Set(routing.HeaderAccessControlRequestMethod, http.MethodPost)
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.Equal(t, "GET,POST", rec.Header().Get(routing.HeaderAccessControlAllowMethods))
+	assert.Contains(t, rec.Header()[routing.HeaderVary], routing.HeaderAccessControlRequestMethod)
+
+	// the actual request reaches the handler
+	req = httptest.NewRequest(http.MethodPost, "/users", nil)
+	req.Header.Set(routing.HeaderOrigin, "https://app.example.com")
+	rec = httptest.NewRecorder()
+	m.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Equal(t, "https://app.example.com", rec.Header().Get(routing.HeaderAccessControlAllowOrigin))
+}
@@ -0,0 +1,313 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"goplugins/core/framework/crypto"
+	"goplugins/core/framework/session"
+	"goplugins/core/routing"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type (
+	// CSRFConfig defines the config for CSRF middleware.
+	CSRFConfig struct {
+		// Skipper defines a function to skip middleware.
+		Skipper Skipper
+
+		// Mode selects where the token is kept between requests.
+		// Optional. Default value CSRFDoubleSubmit.
+		Mode CSRFMode
+
+		// Session stores the tokens in CSRFSession mode. Its LoadAndSave
+		// middleware has to run before the CSRF middleware, e.g.
+		// `m.Use(routing.WrapMiddleware(s.LoadAndSave), middleware.CSRFWithConfig(...))`.
+		// Required in CSRFSession mode.
+		Session *session.Session
+
+		// TokenLookup is a comma separated list of "<source>:<key>" pairs
+		// used to extract the token from the request. The sources are tried in
+		// order until one holds a token.
+		// Optional. Default value "header:X-CSRF-Token,form:_csrf,query:_csrf".
+		// Possible sources:
+		// - "header:<name>"
+		// - "form:<name>"
+		// - "query:<name>"
+		TokenLookup string
+
+		// ContextKey is the key the token is stored under in the context, e.g.
+		// for templates.
+		// Optional. Default value "csrf".
+		ContextKey string
+
+		// ExemptRoutes are not checked, e.g. webhooks authenticated otherwise.
+		// Entries are route paths as registered, e.g. "/hooks/:id", or path
+		// prefixes ending in "*", e.g. "/api/public/*".
+		// Optional.
+		ExemptRoutes []string
+
+		// SessionKey is the key the token is stored under in the session in
+		// CSRFSession mode.
+		// Optional. Default value "csrf_token".
+		SessionKey string
+
+		// Name of the CSRF cookie in CSRFDoubleSubmit mode. This cookie will
+		// store the CSRF token.
+		// Optional. Default value "_csrf".
+		CookieName string
+
+		// Domain of the CSRF cookie.
+		// Optional. Default value none.
+		CookieDomain string
+
+		// Path of the CSRF cookie.
+		// Optional. Default value "/".
+		CookiePath string
+
+		// Max age (in seconds) of the CSRF cookie.
+		// Optional. Default value 86400 (24hr).
+		CookieMaxAge int
+
+		// Indicates if CSRF cookie is secure.
+		// Optional. Default value false.
+		CookieSecure bool
+
+		// Indicates if CSRF cookie is HTTP only. Clients sending the token in a
+		// header read it from the page, e.g. a meta tag rendered from the
+		// context, so the cookie does not need to be readable by scripts.
+		// Optional. Default value true.
+		CookieHTTPOnly *bool
+
+		// Indicates SameSite mode of the CSRF cookie.
+		// Optional. Default value http.SameSiteLaxMode.
+		CookieSameSite http.SameSite
+	}
+
+	// CSRFMode selects how the CSRF middleware keeps the token.
+	CSRFMode int
+
+	// csrfTokenExtractor defines a function that takes `routing.Context` and returns
+	// the token.
+	csrfTokenExtractor func(routing.Context) string
+)
+
+// CSRF modes
+const (
+	// CSRFDoubleSubmit keeps the token in a cookie. Requests must repeat the
+	// value of the cookie, which other sites cannot read.
+	CSRFDoubleSubmit CSRFMode = iota
+	// CSRFSession keeps the token in the session of the user (synchronizer
+	// token pattern).
+	CSRFSession
+)
+
+var (
+	// ErrCSRFTokenMissing is returned for unsafe requests without token.
+	ErrCSRFTokenMissing = routing.NewHTTPError(http.StatusBadRequest, "missing csrf token")
+	// ErrCSRFTokenInvalid is returned for unsafe requests with a token not
+	// matching the one issued.
+	ErrCSRFTokenInvalid = routing.NewHTTPError(http.StatusForbidden, "invalid csrf token")
+
+	// DefaultCSRFConfig is the default CSRF middleware config.
+	DefaultCSRFConfig = CSRFConfig{
+		Skipper:        DefaultSkipper,
+		Mode:           CSRFDoubleSubmit,
+		TokenLookup:    "header:" + routing.HeaderXCSRFToken + ",form:_csrf,query:_csrf",
+		ContextKey:     "csrf",
+		SessionKey:     "csrf_token",
+		CookieName:     "_csrf",
+		CookiePath:     "/",
+		CookieMaxAge:   86400,
+		CookieSameSite: http.SameSiteLaxMode,
+	}
+)
+
+// CSRF returns a Cross-Site Request Forgery (CSRF) middleware using the
+// double submit cookie mode.
+// See: https://en.wikipedia.org/wiki/Cross-site_request_forgery
+func CSRF() routing.MiddlewareFunc {
+	return CSRFWithConfig(DefaultCSRFConfig)
+}
+
+// CSRFWithConfig returns a CSRF middleware with config.
+// See `CSRF()`.
+//
+// Safe methods (GET, HEAD, OPTIONS and TRACE) are not checked, but issue the
+// token, which is stored in the context under config.ContextKey for forms and
+// templates. All other requests must send it back using one of the lookups.
+func CSRFWithConfig(config CSRFConfig) routing.MiddlewareFunc {
+	// Defaults
+	if config.Skipper == nil {
+		config.Skipper = DefaultCSRFConfig.Skipper
+	}
+	if config.TokenLookup == "" {
+		config.TokenLookup = DefaultCSRFConfig.TokenLookup
+	}
+	if config.ContextKey == "" {
+		config.ContextKey = DefaultCSRFConfig.ContextKey
+	}
+	if config.SessionKey == "" {
+		config.SessionKey = DefaultCSRFConfig.SessionKey
+	}
+	if config.CookieName == "" {
+		config.CookieName = DefaultCSRFConfig.CookieName
+	}
+	if config.CookiePath == "" {
+		config.CookiePath = DefaultCSRFConfig.CookiePath
+	}
+	if config.CookieMaxAge == 0 {
+		config.CookieMaxAge = DefaultCSRFConfig.CookieMaxAge
+	}
+	if config.CookieSameSite == 0 {
+		config.CookieSameSite = DefaultCSRFConfig.CookieSameSite
+	}
+	httpOnly := config.CookieHTTPOnly == nil || *config.CookieHTTPOnly
+	if config.Mode == CSRFSession && config.Session == nil {
+		panic("routing: csrf middleware requires a session in session mode")
+	}
+
+	extractors := make([]csrfTokenExtractor, 0, 3)
+	for _, lookup := range strings.Split(config.TokenLookup, ",") {
+		parts := strings.SplitN(strings.TrimSpace(lookup), ":", 2)
+		if len(parts) != 2 {
+			panic("routing: invalid csrf token lookup " + lookup)
+		}
+		switch parts[0] {
+		case "header":
+			extractors = append(extractors, csrfTokenFromHeader(parts[1]))
+		case "form":
+			extractors = append(extractors, csrfTokenFromForm(parts[1]))
+		case "query":
+			extractors = append(extractors, csrfTokenFromQuery(parts[1]))
+		default:
+			panic("routing: invalid csrf token lookup " + lookup)
+		}
+	}
+
+	// load returns the token issued before, if any.
+	load := func(c routing.Context) string {
+		if config.Mode == CSRFSession {
+			return config.Session.GetString(c.Request().Context(), config.SessionKey)
+		}
+		if k, err := c.Cookie(config.CookieName); err == nil {
+			return k.Value
+		}
+		return ""
+	}
+
+	// store issues token to the client.
+	store := func(c routing.Context, token string) {
+		if config.Mode == CSRFSession {
+			config.Session.Put(c.Request().Context(), config.SessionKey, token)
+			return
+		}
+		cookie := new(http.Cookie)
+		cookie.Name = config.CookieName
+		cookie.Value = token
+		if config.CookiePath != "" {
+			cookie.Path = config.CookiePath
+		}
+		if config.CookieDomain != "" {
+			cookie.Domain = config.CookieDomain
+		}
+		cookie.Expires = time.Now().Add(time.Duration(config.CookieMaxAge) * time.Second)
+		cookie.MaxAge = config.CookieMaxAge
+		cookie.SameSite = config.CookieSameSite
+		cookie.Secure = config.CookieSecure
+		cookie.HttpOnly = httpOnly
+		c.SetCookie(cookie)
+	}
+
+	return func(next routing.HandlerFunc) routing.HandlerFunc {
+		return func(c routing.Context) error {
+			if config.Skipper(c) || csrfExempt(c, config.ExemptRoutes) {
+				return next(c)
+			}
+
+			token := load(c)
+			issued := token != ""
+			if !issued {
+				token = crypto.SecureToken()
+			}
+
+			switch c.Request().Method {
+			case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+			default:
+				clientToken := ""
+				for _, extract := range extractors {
+					if clientToken = extract(c); clientToken != "" {
+						break
+					}
+				}
+				if clientToken == "" {
+					return ErrCSRFTokenMissing
+				}
+				if !issued || !validateCSRFToken(token, clientToken) {
+					return ErrCSRFTokenInvalid
+				}
+			}
+
+			if !issued || config.Mode == CSRFDoubleSubmit {
+				// The cookie is renewed on every request to extend its lifetime.
+				store(c, token)
+			}
+			c.Set(config.ContextKey, token)
+
+			// Protect clients from caching the response
+			c.Response().Header().Add(routing.HeaderVary, routing.HeaderCookie)
+
+			return next(c)
+		}
+	}
+}
+
+// CSRFToken returns the token issued by the CSRF middleware stored under the
+// default context key.
+func CSRFToken(c routing.Context) string {
+	token, _ := c.Get(DefaultCSRFConfig.ContextKey).(string)
+	return token
+}
+
+// csrfExempt reports whether the route of the request is exempt from the
+// CSRF check.
+func csrfExempt(c routing.Context, routes []string) bool {
+	for _, r := range routes {
+		if strings.HasSuffix(r, "*") {
+			if strings.HasPrefix(c.Request().URL.Path, r[:len(r)-1]) {
+				return true
+			}
+		} else if r == c.Path() {
+			return true
+		}
+	}
+	return false
+}
+
+// csrfTokenFromHeader returns a `csrfTokenExtractor` that extracts token from the
+// provided request header.
+func csrfTokenFromHeader(header string) csrfTokenExtractor {
+	return func(c routing.Context) string {
+		return c.Request().Header.Get(header)
+	}
+}
+
+// csrfTokenFromForm returns a `csrfTokenExtractor` that extracts token from the
+// provided form parameter.
+func csrfTokenFromForm(param string) csrfTokenExtractor {
+	return func(c routing.Context) string {
+		return c.FormValue(param)
+	}
+}
+
+// csrfTokenFromQuery returns a `csrfTokenExtractor` that extracts token from the
+// provided query parameter.
+func csrfTokenFromQuery(param string) csrfTokenExtractor {
+	return func(c routing.Context) string {
+		return c.QueryParam(param)
+	}
+}
+
+func validateCSRFToken(token, clientToken string) bool {
+	return subtle.ConstantTimeCompare([]byte(token), []byte(clientToken)) == 1
+}
@@ -0,0 +1,155 @@
+package middleware
+
+import (
+	"goplugins/core/framework/session"
+	"goplugins/core/routing"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCSRF(t *testing.T) {
+	m := routing.New()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rec := httptest.NewRecorder()
+	c := m.NewContext(req, rec)
+	csrf := CSRF()
+	h := csrf(func(c routing.Context) error {
+		return c.String(http.StatusOK, "test")
+	})
+
+	// Generate CSRF token
+	h(c)
+	assert.Contains(t, rec.Header().Get(routing.HeaderSetCookie), "_csrf")
+	assert.Contains(t, rec.Header().Get(routing.HeaderSetCookie), "HttpOnly")
+	assert.NotEmpty(t, CSRFToken(c))
+
+	// Without CSRF cookie
+	req = httptest.NewRequest(http.MethodPost, "/", nil)
+	rec = httptest.NewRecorder()
+	c = m.NewContext(req, rec)
+	req.Header.Set(routing.HeaderXCSRFToken, "token")
+	assert.Equal(t, ErrCSRFTokenInvalid, h(c))
+
+	// Empty/invalid CSRF token
+	req = httptest.NewRequest(http.MethodPost, "/", nil)
+	rec = httptest.NewRecorder()
+	c = m.NewContext(req, rec)
+	req.Header.Set(routing.HeaderCookie, "_csrf=token")
+	assert.Equal(t, ErrCSRFTokenMissing, h(c))
+	req.Header.Set(routing.HeaderXCSRFToken, "other")
+	assert.Equal(t, ErrCSRFTokenInvalid, h(c))
+
+	// Valid CSRF token
+	token := "QQGN4JSwtsRqXR2mGadVOQ"
+	req.Header.Set(routing.HeaderCookie, "_csrf="+token)
+	req.Header.Set(routing.HeaderXCSRFToken, token)
+	assert.NoError(t, h(c))
+}
+
+func TestCSRFTokenLookup(t *testing.T) {
+	m := routing.New()
+	token := "QQGN4JSwtsRqXR2mGadVOQ"
+	h := CSRF()(func(c routing.Context) error {
+		return c.NoContent(http.StatusNoContent)
+	})
+
+	form := url.Values{"_csrf": {token}}
+	for name, req := range map[string]*http.Request{
+		"header": httptest.NewRequest(http.MethodPost, "/", nil),
+		"form":   httptest.NewRequest(http.MethodPost, "/", strings.NewReader(form.Encode())),
+		"query":  httptest.NewRequest(http.MethodDelete, "/?_csrf="+token, nil),
+	} {
+		req.Header.Set(routing.HeaderCookie, "_csrf="+token)
+		switch name {
+		case "header":
+			req.Header.Set(routing.HeaderXCSRFToken, token)
+		case "form":
+			req.Header.Set(routing.HeaderContentType, routing.MIMEApplicationForm)
+		}
+		assert.NoError(t, h(m.NewContext(req, httptest.NewRecorder())), name)
+	}
+
+	assert.Panics(t, func() {
+		CSRFWithConfig(CSRFConfig{TokenLookup: "cookie:_csrf"})
+	})
+}
+
+func TestCSRFExemptRoutes(t *testing.T) {
+	m := routing.New()
+	m.Use(CSRFWithConfig(CSRFConfig{ExemptRoutes: []string{"/hooks/:id", "/public/*"}}))
+	h := func(c routing.Context) error {
+		return c.NoContent(http.StatusNoContent)
+	}
+	m.POST("/hooks/:id", h)
+	m.POST("/public/feedback", h)
+	m.POST("/account", h)
+
+	code, _ := requestCode(m, http.MethodPost, "/hooks/github")
+	assert.Equal(t, http.StatusNoContent, code)
+	code, _ = requestCode(m, http.MethodPost, "/public/feedback")
+	assert.Equal(t, http.StatusNoContent, code)
+	code, _ = requestCode(m, http.MethodPost, "/account")
+	assert.Equal(t, http.StatusBadRequest, code)
+}
+
+func TestCSRFSession(t *testing.T) {
+	s := session.New()
+	m := routing.New()
+	m.Use(routing.WrapMiddleware(s.LoadAndSave), CSRFWithConfig(CSRFConfig{Mode: CSRFSession, Session: s}))
+	m.GET("/form", func(c routing.Context) error {
+		return c.String(http.StatusOK, CSRFToken(c))
+	})
+	m.POST("/form", func(c routing.Context) error {
+		return c.NoContent(http.StatusNoContent)
+	})
+
+	// the token is issued and stored in the session
+	req := httptest.NewRequest(http.MethodGet, "/form", nil)
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+	token := rec.Body.String()
+	require.NotEmpty(t, token)
+	cookies := rec.Result().Cookies()
+	require.Len(t, cookies, 1)
+	assert.Equal(t, "session", cookies[0].Name)
+
+	post := func(token string, cookie *http.Cookie) int {
+		req := httptest.NewRequest(http.MethodPost, "/form", nil)
+		req.Header.Set(routing.HeaderXCSRFToken, token)
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		rec := httptest.NewRecorder()
+		m.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	assert.Equal(t, http.StatusNoContent, post(token, cookies[0]))
+	assert.Equal(t, http.StatusForbidden, post("forged", cookies[0]))
+	// the token is bound to the session
+	assert.Equal(t, http.StatusForbidden, post(token, nil))
+
+	// the token stays the same for the session
+	req = httptest.NewRequest(http.MethodGet, "/form", nil)
+	req.AddCookie(cookies[0])
+	rec = httptest.NewRecorder()
+	m.ServeHTTP(rec, req)
+	assert.Equal(t, token, rec.Body.String())
+
+	assert.Panics(t, func() {
+		CSRFWithConfig(CSRFConfig{Mode: CSRFSession})
+	})
+}
+
+func requestCode(m *routing.Mux, method, target string) (int, string) {
+	req := httptest.NewRequest(method, target, nil)
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, req)
+	return rec.Code, rec.Body.String()
+}
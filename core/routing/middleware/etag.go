@@ -0,0 +1,180 @@
+package middleware
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"goplugins/core/routing"
+	"net"
+	"net/http"
+)
+
+type (
+	// ETagConfig defines the config for ETag middleware.
+	ETagConfig struct {
+		// Skipper defines a function to skip middleware.
+		Skipper Skipper
+
+		// Weak generates weak entity tags, which only state that responses are
+		// semantically equivalent. Use it if the body is not byte-for-byte
+		// stable, e.g. with maps encoded in random order.
+		// Optional. Default value false.
+		Weak bool
+
+		// MaxSize is the size up to which responses are buffered to generate the
+		// entity tag. Larger and streamed responses are sent as they are.
+		// Optional. Default value 1 MB.
+		MaxSize int
+	}
+
+	etagResponseWriter struct {
+		http.ResponseWriter
+		maxSize     int
+		buf         []byte
+		code        int
+		wroteHeader bool
+		passthrough bool
+	}
+)
+
+// DefaultETagConfig is the default ETag middleware config.
+var DefaultETagConfig = ETagConfig{
+	Skipper: DefaultSkipper,
+	MaxSize: 1 << 20,
+}
+
+// ETag returns a middleware which adds entity tags to GET and HEAD responses,
+// generated from a hash of the body. Responses to conditional requests whose
+// If-None-Match or If-Modified-Since header matches are replaced by
+// "304 Not Modified". Handlers setting the ETag header themselves, e.g. using
+// `Context.NotModified`, keep their entity tag.
+func ETag() routing.MiddlewareFunc {
+	return ETagWithConfig(DefaultETagConfig)
+}
+
+// ETagWithConfig returns an ETag middleware with config.
+// See: `ETag()`.
+func ETagWithConfig(config ETagConfig) routing.MiddlewareFunc {
+	// Defaults
+	if config.Skipper == nil {
+		config.Skipper = DefaultETagConfig.Skipper
+	}
+	if config.MaxSize == 0 {
+		config.MaxSize = DefaultETagConfig.MaxSize
+	}
+
+	return func(next routing.HandlerFunc) routing.HandlerFunc {
+		return func(c routing.Context) error {
+			method := c.Request().Method
+			if config.Skipper(c) || method != http.MethodGet && method != http.MethodHead {
+				return next(c)
+			}
+
+			res := c.Response()
+			rw := res.Writer
+			w := &etagResponseWriter{ResponseWriter: rw, maxSize: config.MaxSize}
+			res.Writer = w
+			defer func() {
+				res.Writer = rw
+			}()
+			if err := next(c); err != nil {
+				w.flush()
+				return err
+			}
+			if w.passthrough || !w.wroteHeader {
+				return nil
+			}
+
+			header := rw.Header()
+			etag := header.Get(routing.HeaderETag)
+			if etag == "" && w.code == http.StatusOK {
+				etag = generateETag(w.buf, config.Weak)
+			}
+			lastModified, _ := http.ParseTime(header.Get(routing.HeaderLastModified))
+			if w.code == http.StatusOK && c.NotModified(lastModified, etag) {
+				// Representation headers are not sent with 304 responses.
+				header.Del(routing.HeaderContentType)
+				header.Del(routing.HeaderContentLength)
+				rw.WriteHeader(http.StatusNotModified)
+				res.Status = http.StatusNotModified
+				return nil
+			}
+			w.flush()
+			return nil
+		}
+	}
+}
+
+// generateETag returns the entity tag of body.
+func generateETag(body []byte, weak bool) string {
+	sum := sha256.Sum256(body)
+	etag := `"` + base64.RawURLEncoding.EncodeToString(sum[:18]) + `"`
+	if weak {
+		return "W/" + etag
+	}
+	return etag
+}
+
+func (w *etagResponseWriter) WriteHeader(code int) {
+	if w.passthrough {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		w.code = code
+	}
+}
+
+func (w *etagResponseWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.passthrough {
+		return w.ResponseWriter.Write(b)
+	}
+	if len(w.buf)+len(b) > w.maxSize {
+		if err := w.flush(); err != nil {
+			return 0, err
+		}
+		return w.ResponseWriter.Write(b)
+	}
+	w.buf = append(w.buf, b...)
+	return len(b), nil
+}
+
+// Flush sends the response without entity tag, as it is streamed.
+func (w *etagResponseWriter) Flush() {
+	w.flush()
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *etagResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("routing: response does not implement http.Hijacker")
+	}
+	w.passthrough = true
+	return h.Hijack()
+}
+
+// flush writes the buffered response and passes any further writes through.
+func (w *etagResponseWriter) flush() error {
+	if w.passthrough {
+		return nil
+	}
+	w.passthrough = true
+	if !w.wroteHeader {
+		return nil
+	}
+	w.ResponseWriter.WriteHeader(w.code)
+	if len(w.buf) == 0 {
+		return nil
+	}
+	_, err := w.ResponseWriter.Write(w.buf)
+	w.buf = nil
+	return err
+}
@@ -0,0 +1,156 @@
+package middleware
+
+import (
+	"goplugins/core/routing"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestETag(t *testing.T) {
+	m := routing.New()
+	m.Use(ETag())
+	m.GET("/", func(c routing.Context) error {
+		return c.JSON(http.StatusOK, map[string]string{"name": "Jon Snow"})
+	})
+	m.POST("/", func(c routing.Context) error {
+		return c.String(http.StatusOK, "test")
+	})
+	m.GET("/missing", func(c routing.Context) error {
+		return c.String(http.StatusNotFound, "not found")
+	})
+
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	etag := rec.Header().Get(routing.HeaderETag)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Regexp(t, `^"[\w-]+"$`, etag)
+	assert.Equal(t, `{"name":"Jon Snow"}`+"\n", rec.Body.String())
+
+	// Stable
+	rec = httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, etag, rec.Header().Get(routing.HeaderETag))
+
+	// Not modified
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(routing.HeaderIfNoneMatch, etag)
+	rec = httptest.NewRecorder()
+	m.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusNotModified, rec.Code)
+	assert.Equal(t, etag, rec.Header().Get(routing.HeaderETag))
+	assert.Equal(t, "", rec.Header().Get(routing.HeaderContentType))
+	assert.Equal(t, 0, rec.Body.Len())
+
+	// Modified
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(routing.HeaderIfNoneMatch, `"outdated"`)
+	rec = httptest.NewRecorder()
+	m.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NotEqual(t, 0, rec.Body.Len())
+
+	// Unsafe methods and errors are left alone
+	rec = httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", nil))
+	assert.Equal(t, "", rec.Header().Get(routing.HeaderETag))
+	rec = httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/missing", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Equal(t, "", rec.Header().Get(routing.HeaderETag))
+	assert.Equal(t, "not found", rec.Body.String())
+}
+
+func TestETagWeak(t *testing.T) {
+	m := routing.New()
+	m.Use(ETagWithConfig(ETagConfig{Weak: true}))
+	m.GET("/", func(c routing.Context) error {
+		return c.String(http.StatusOK, "test")
+	})
+
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	etag := rec.Header().Get(routing.HeaderETag)
+	assert.True(t, strings.HasPrefix(etag, `W/"`))
+
+	// Weak comparison
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(routing.HeaderIfNoneMatch, strings.TrimPrefix(etag, "W/"))
+	rec = httptest.NewRecorder()
+	m.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusNotModified, rec.Code)
+}
+
+func TestETagHandlerValidators(t *testing.T) {
+	modified := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	m := routing.New()
+	m.Use(ETag())
+	m.GET("/", func(c routing.Context) error {
+		c.Response().Header().Set(routing.HeaderLastModified, modified.Format(http.TimeFormat))
+		return c.String(http.StatusOK, "test")
+	})
+	m.GET("/custom", func(c routing.Context) error {
+		if c.NotModified(time.Time{}, `"custom"`) {
+			return c.NoContent(http.StatusNotModified)
+		}
+		return c.String(http.StatusOK, "test")
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(routing.HeaderIfModifiedSince, modified.Format(http.TimeFormat))
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusNotModified, rec.Code)
+
+	rec = httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/custom", nil))
+	assert.Equal(t, `"custom"`, rec.Header().Get(routing.HeaderETag))
+
+	req = httptest.NewRequest(http.MethodGet, "/custom", nil)
+	req.Header.Set(routing.HeaderIfNoneMatch, `"custom"`)
+	rec = httptest.NewRecorder()
+	m.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusNotModified, rec.Code)
+	assert.Equal(t, 0, rec.Body.Len())
+}
+
+func TestETagLargeResponse(t *testing.T) {
+	m := routing.New()
+	m.Use(ETagWithConfig(ETagConfig{MaxSize: 8}))
+	m.GET("/", func(c routing.Context) error {
+		return c.String(http.StatusOK, strings.Repeat("x", 16))
+	})
+	m.GET("/stream", func(c routing.Context) error {
+		c.Response().WriteHeader(http.StatusOK)
+		c.Response().Write([]byte("x"))
+		c.Response().Flush()
+		return nil
+	})
+
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, "", rec.Header().Get(routing.HeaderETag))
+	assert.Equal(t, strings.Repeat("x", 16), rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/stream", nil))
+	assert.Equal(t, "", rec.Header().Get(routing.HeaderETag))
+	assert.True(t, rec.Flushed)
+	assert.Equal(t, "x", rec.Body.String())
+}
+
+func TestETagHijackNotSupported(t *testing.T) {
+	m := routing.New()
+	m.Use(ETag())
+	m.GET("/", func(c routing.Context) error {
+		_, _, err := c.Response().Hijack()
+		return err
+	})
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+}
@@ -0,0 +1,236 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"goplugins/core/framework/color"
+	"goplugins/core/routing"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/valyala/fasttemplate"
+)
+
+type (
+	// LoggerConfig defines the config for Logger middleware.
+	LoggerConfig struct {
+		// Skipper defines a function to skip middleware.
+		Skipper Skipper
+
+		// Tags to construct the logger format.
+		//
+		// - time_unix
+		// - time_unix_nano
+		// - time_rfc3339
+		// - time_rfc3339_nano
+		// - time_custom
+		// - id (Request ID)
+		// - remote_ip
+		// - uri
+		// - host
+		// - method
+		// - path
+		// - protocol
+		// - referer
+		// - user_agent
+		// - status
+		// - error
+		// - latency (In nanoseconds)
+		// - latency_human (Human readable)
+		// - bytes_in (Bytes received)
+		// - bytes_out (Bytes sent)
+		// - header:<NAME>
+		// - query:<NAME>
+		// - form:<NAME>
+		// - cookie:<NAME>
+		//
+		// Example "${remote_ip} ${status}"
+		//
+		// Optional. Default value DefaultLoggerConfig.Format.
+		Format string
+
+		// Optional. Default value DefaultLoggerConfig.CustomTimeFormat.
+		CustomTimeFormat string
+
+		// Output is a writer where logs in JSON format are written.
+		// Optional. Default value is the output of the logger of the Mux.
+		Output io.Writer
+
+		template *fasttemplate.Template
+		colorer  *color.Color
+		pool     *sync.Pool
+	}
+)
+
+var (
+	// DefaultLoggerConfig is the default Logger middleware config.
+	DefaultLoggerConfig = LoggerConfig{
+		Skipper: DefaultSkipper,
+		Format: `{"time":"${time_rfc3339_nano}","id":"${id}","remote_ip":"${remote_ip}",` +
+			`"host":"${host}","method":"${method}","uri":"${uri}","user_agent":"${user_agent}",` +
+			`"status":${status},"error":"${error}","latency":${latency},"latency_human":"${latency_human}"` +
+			`,"bytes_in":${bytes_in},"bytes_out":${bytes_out}}` + "\n",
+		CustomTimeFormat: "2006-01-02 15:04:05.00000",
+	}
+)
+
+// Logger returns a middleware that logs HTTP requests.
+func Logger() routing.MiddlewareFunc {
+	return LoggerWithConfig(DefaultLoggerConfig)
+}
+
+// LoggerWithConfig returns a Logger middleware with config.
+// See: `Logger()`.
+func LoggerWithConfig(config LoggerConfig) routing.MiddlewareFunc {
+	// Defaults
+	if config.Skipper == nil {
+		config.Skipper = DefaultLoggerConfig.Skipper
+	}
+	if config.Format == "" {
+		config.Format = DefaultLoggerConfig.Format
+	}
+	if config.CustomTimeFormat == "" {
+		config.CustomTimeFormat = DefaultLoggerConfig.CustomTimeFormat
+	}
+
+	config.template = fasttemplate.New(config.Format, "${", "}")
+	// The colorer is shared by all requests, so its output is set once. The
+	// status is only colored for terminal outputs and never in JSON.
+	config.colorer = color.New()
+	config.colorer.SetOutput(config.Output)
+	if strings.HasPrefix(strings.TrimSpace(config.Format), "{") {
+		config.colorer.Disable()
+	}
+	config.pool = &sync.Pool{
+		New: func() interface{} {
+			return bytes.NewBuffer(make([]byte, 256))
+		},
+	}
+
+	return func(next routing.HandlerFunc) routing.HandlerFunc {
+		return func(c routing.Context) (err error) {
+			if config.Skipper(c) {
+				return next(c)
+			}
+
+			req := c.Request()
+			res := c.Response()
+			start := time.Now()
+			if err = next(c); err != nil {
+				c.Error(err)
+			}
+			stop := time.Now()
+			buf := config.pool.Get().(*bytes.Buffer)
+			buf.Reset()
+			defer config.pool.Put(buf)
+
+			output := config.Output
+			if output == nil {
+				output = c.Logger().Output()
+			}
+
+			if _, err = config.template.ExecuteFunc(buf, func(w io.Writer, tag string) (int, error) {
+				switch tag {
+				case "time_unix":
+					return buf.WriteString(strconv.FormatInt(time.Now().Unix(), 10))
+				case "time_unix_nano":
+					return buf.WriteString(strconv.FormatInt(time.Now().UnixNano(), 10))
+				case "time_rfc3339":
+					return buf.WriteString(time.Now().Format(time.RFC3339))
+				case "time_rfc3339_nano":
+					return buf.WriteString(time.Now().Format(time.RFC3339Nano))
+				case "time_custom":
+					return buf.WriteString(time.Now().Format(config.CustomTimeFormat))
+				case "id":
+					id := req.Header.Get(routing.HeaderXRequestID)
+					if id == "" {
+						id = res.Header().Get(routing.HeaderXRequestID)
+					}
+					return writeString(buf, id)
+				case "remote_ip":
+					return writeString(buf, c.RealIP())
+				case "host":
+					return writeString(buf, req.Host)
+				case "uri":
+					return writeString(buf, req.RequestURI)
+				case "method":
+					return writeString(buf, req.Method)
+				case "path":
+					p := req.URL.Path
+					if p == "" {
+						p = "/"
+					}
+					return writeString(buf, p)
+				case "protocol":
+					return writeString(buf, req.Proto)
+				case "referer":
+					return writeString(buf, req.Referer())
+				case "user_agent":
+					return writeString(buf, req.UserAgent())
+				case "status":
+					n := res.Status
+					s := config.colorer.Green(n)
+					switch {
+					case n >= 500:
+						s = config.colorer.Red(n)
+					case n >= 400:
+						s = config.colorer.Yellow(n)
+					case n >= 300:
+						s = config.colorer.Cyan(n)
+					}
+					return buf.WriteString(s)
+				case "error":
+					if err != nil {
+						return writeString(buf, err.Error())
+					}
+				case "latency":
+					l := stop.Sub(start)
+					return buf.WriteString(strconv.FormatInt(int64(l), 10))
+				case "latency_human":
+					return buf.WriteString(stop.Sub(start).String())
+				case "bytes_in":
+					cl := req.Header.Get(routing.HeaderContentLength)
+					if cl == "" {
+						cl = "0"
+					}
+					return writeString(buf, cl)
+				case "bytes_out":
+					return buf.WriteString(strconv.FormatInt(res.Size, 10))
+				default:
+					switch {
+					case strings.HasPrefix(tag, "header:"):
+						return writeString(buf, c.Request().Header.Get(tag[7:]))
+					case strings.HasPrefix(tag, "query:"):
+						return writeString(buf, c.QueryParam(tag[6:]))
+					case strings.HasPrefix(tag, "form:"):
+						return writeString(buf, c.FormValue(tag[5:]))
+					case strings.HasPrefix(tag, "cookie:"):
+						cookie, err := c.Cookie(tag[7:])
+						if err == nil {
+							return writeString(buf, cookie.Value)
+						}
+					}
+				}
+				return 0, nil
+			}); err != nil {
+				return
+			}
+
+			_, err = output.Write(buf.Bytes())
+			return
+		}
+	}
+}
+
+// writeString writes s escaped for JSON strings, so client supplied values
+// cannot break the log format.
+func writeString(buf *bytes.Buffer, s string) (int, error) {
+	b, err := json.Marshal(s)
+	if err != nil {
+		return 0, err
+	}
+	return buf.Write(b[1 : len(b)-1])
+}
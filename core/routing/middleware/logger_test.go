@@ -0,0 +1,81 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"goplugins/core/routing"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogger(t *testing.T) {
+	m := routing.New()
+	buf := new(bytes.Buffer)
+	m.Logger.SetOutput(buf)
+	mw := Logger()
+
+	for _, tt := range []struct {
+		handler routing.HandlerFunc
+		status  string
+	}{
+		{func(c routing.Context) error { return c.String(http.StatusOK, "test") }, `"status":200`},
+		{func(c routing.Context) error { return c.String(http.StatusMovedPermanently, "test") }, `"status":301`},
+		{func(c routing.Context) error { return c.String(http.StatusNotFound, "test") }, `"status":404`},
+		{func(c routing.Context) error { return errors.New("error") }, `"status":500`},
+	} {
+		buf.Reset()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		rec := httptest.NewRecorder()
+		mw(tt.handler)(m.NewContext(req, rec))
+		assert.Contains(t, buf.String(), tt.status)
+	}
+}
+
+func TestLoggerTemplate(t *testing.T) {
+	buf := new(bytes.Buffer)
+
+	m := routing.New()
+	m.Use(RequestID(), LoggerWithConfig(LoggerConfig{
+		Format: `{"id":"${id}","remote_ip":"${remote_ip}","host":"${host}","user_agent":"${user_agent}",` +
+			`"method":"${method}","uri":"${uri}","path":"${path}","status":${status},` +
+			`"latency_human":"${latency_human}","bytes_in":${bytes_in},"bytes_out":${bytes_out},` +
+			`"referer":"${referer}","protocol":"${protocol}","ch":"${header:X-Custom-Header}",` +
+			`"us":"${query:username}","cf":"${form:username}","session":"${cookie:session}"}`,
+		Output: buf,
+	}))
+
+	m.GET("/", func(c routing.Context) error {
+		return c.String(http.StatusOK, "Header Logged")
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/?username=apagano-param&password=secret", nil)
+	req.RequestURI = "/"
+	req.Header.Add(routing.HeaderXRealIP, "127.0.0.1")
+	req.Header.Add("Referer", "google.com")
+	req.Header.Add("User-Agent", `echo-tests "quoted"`)
+	req.Header.Add("Authorization", "Default")
+	req.Header.Add("X-Custom-Header", "AAA-CUSTOM-VALUE")
+	req.Header.Add(routing.HeaderXRequestID, "6ba7b810-9dad-11d1-80b4-00c04fd430c8")
+	req.Header.Add("Cookie", "_ga=GA1.2.000000000.0000000000; session=ac08034cd216a647fc2eb62f2bcf7b810")
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, req)
+
+	var entry map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &entry), buf.String())
+	assert.Equal(t, "6ba7b810-9dad-11d1-80b4-00c04fd430c8", entry["id"])
+	assert.Equal(t, "127.0.0.1", entry["remote_ip"])
+	assert.Equal(t, `echo-tests "quoted"`, entry["user_agent"])
+	assert.Equal(t, "AAA-CUSTOM-VALUE", entry["ch"])
+	assert.Equal(t, "apagano-param", entry["us"])
+	assert.Equal(t, "ac08034cd216a647fc2eb62f2bcf7b810", entry["session"])
+	assert.Equal(t, "google.com", entry["referer"])
+	assert.Equal(t, float64(200), entry["status"])
+	assert.Equal(t, float64(len("Header Logged")), entry["bytes_out"])
+	assert.False(t, strings.Contains(buf.String(), "secret"))
+}
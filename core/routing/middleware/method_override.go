@@ -0,0 +1,103 @@
+package middleware
+
+import (
+	"goplugins/core/routing"
+	"net/http"
+	"strings"
+)
+
+type (
+	// MethodOverrideConfig defines the config for MethodOverride middleware.
+	MethodOverrideConfig struct {
+		// Skipper defines a function to skip middleware.
+		Skipper Skipper
+
+		// Getter is a function that gets overridden method from the request.
+		// Optional. Default values MethodFromHeader(routing.HeaderXHTTPMethodOverride).
+		Getter MethodOverrideGetter
+
+		// AllowedMethods are the methods a POST request may be overridden with.
+		// Optional. Default value []string{"PUT", "PATCH", "DELETE"}.
+		AllowedMethods []string
+	}
+
+	// MethodOverrideGetter is a function that gets overridden method from the request
+	MethodOverrideGetter func(routing.Context) string
+)
+
+// DefaultMethodOverrideConfig is the default MethodOverride middleware config.
+var DefaultMethodOverrideConfig = MethodOverrideConfig{
+	Skipper:        DefaultSkipper,
+	Getter:         MethodFromHeader(routing.HeaderXHTTPMethodOverride),
+	AllowedMethods: []string{http.MethodPut, http.MethodPatch, http.MethodDelete},
+}
+
+// MethodOverride returns a MethodOverride middleware.
+// MethodOverride middleware checks for the overridden method from the request and
+// uses it instead of the original method, so clients and HTML forms limited to
+// POST can reach PUT, PATCH and DELETE routes.
+//
+// For security reasons, only POST requests can be overridden. Register it
+// using `Mux#Pre()`, so the route is found using the overridden method.
+func MethodOverride() routing.MiddlewareFunc {
+	return MethodOverrideWithConfig(DefaultMethodOverrideConfig)
+}
+
+// MethodOverrideWithConfig returns a MethodOverride middleware with config.
+// See: `MethodOverride()`.
+func MethodOverrideWithConfig(config MethodOverrideConfig) routing.MiddlewareFunc {
+	// Defaults
+	if config.Skipper == nil {
+		config.Skipper = DefaultMethodOverrideConfig.Skipper
+	}
+	if config.Getter == nil {
+		config.Getter = DefaultMethodOverrideConfig.Getter
+	}
+	if len(config.AllowedMethods) == 0 {
+		config.AllowedMethods = DefaultMethodOverrideConfig.AllowedMethods
+	}
+	allowed := map[string]bool{}
+	for _, m := range config.AllowedMethods {
+		allowed[strings.ToUpper(m)] = true
+	}
+
+	return func(next routing.HandlerFunc) routing.HandlerFunc {
+		return func(c routing.Context) error {
+			if config.Skipper(c) {
+				return next(c)
+			}
+
+			req := c.Request()
+			if req.Method == http.MethodPost {
+				if m := strings.ToUpper(config.Getter(c)); allowed[m] {
+					req.Method = m
+				}
+			}
+			return next(c)
+		}
+	}
+}
+
+// MethodFromHeader is a `MethodOverrideGetter` that gets overridden method from
+// the request header.
+func MethodFromHeader(header string) MethodOverrideGetter {
+	return func(c routing.Context) string {
+		return c.Request().Header.Get(header)
+	}
+}
+
+// MethodFromForm is a `MethodOverrideGetter` that gets overridden method from the
+// form parameter, e.g. "_method".
+func MethodFromForm(param string) MethodOverrideGetter {
+	return func(c routing.Context) string {
+		return c.FormValue(param)
+	}
+}
+
+// MethodFromQuery is a `MethodOverrideGetter` that gets overridden method from
+// the query parameter.
+func MethodFromQuery(param string) MethodOverrideGetter {
+	return func(c routing.Context) string {
+		return c.QueryParam(param)
+	}
+}
@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"bytes"
+	"goplugins/core/routing"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMethodOverride(t *testing.T) {
+	m := routing.New()
+	m.Pre(MethodOverride())
+	m.DELETE("/", func(c routing.Context) error {
+		return c.String(http.StatusOK, "deleted")
+	})
+
+	// Override with http header
+	req := httptest.NewRequest(http.MethodPost, "/", nil)
+	req.Header.Set(routing.HeaderXHTTPMethodOverride, "delete")
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "deleted", rec.Body.String())
+
+	// Only POST is overridden
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(routing.HeaderXHTTPMethodOverride, http.MethodDelete)
+	rec = httptest.NewRecorder()
+	m.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+
+	// Only allowed methods
+	req = httptest.NewRequest(http.MethodPost, "/", nil)
+	req.Header.Set(routing.HeaderXHTTPMethodOverride, http.MethodGet)
+	c := m.NewContext(req, httptest.NewRecorder())
+	MethodOverride()(func(c routing.Context) error { return nil })(c)
+	assert.Equal(t, http.MethodPost, req.Method)
+}
+
+func TestMethodOverrideGetters(t *testing.T) {
+	m := routing.New()
+	h := func(c routing.Context) error { return nil }
+
+	// Override with form parameter
+	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader([]byte("_method="+http.MethodPatch)))
+	req.Header.Set(routing.HeaderContentType, routing.MIMEApplicationForm)
+	c := m.NewContext(req, httptest.NewRecorder())
+	MethodOverrideWithConfig(MethodOverrideConfig{Getter: MethodFromForm("_method")})(h)(c)
+	assert.Equal(t, http.MethodPatch, req.Method)
+
+	// Override with query parameter
+	req = httptest.NewRequest(http.MethodPost, "/?_method="+http.MethodPut, nil)
+	c = m.NewContext(req, httptest.NewRecorder())
+	MethodOverrideWithConfig(MethodOverrideConfig{Getter: MethodFromQuery("_method")})(h)(c)
+	assert.Equal(t, http.MethodPut, req.Method)
+
+	// Custom allowed methods
+	req = httptest.NewRequest(http.MethodPost, "/?_method=PURGE", nil)
+	c = m.NewContext(req, httptest.NewRecorder())
+	MethodOverrideWithConfig(MethodOverrideConfig{
+		Getter:         MethodFromQuery("_method"),
+		AllowedMethods: []string{"PURGE"},
+	})(h)(c)
+	assert.Equal(t, "PURGE", req.Method)
+}
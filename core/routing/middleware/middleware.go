@@ -0,0 +1,19 @@
+package middleware
+
+import (
+	"goplugins/core/routing"
+)
+
+type (
+	// Skipper defines a function to skip middleware. Returning true skips processing
+	// the middleware.
+	Skipper func(c routing.Context) bool
+
+	// BeforeFunc defines a function which is executed just before the middleware.
+	BeforeFunc func(c routing.Context)
+)
+
+// DefaultSkipper returns false which processes the middleware.
+func DefaultSkipper(routing.Context) bool {
+	return false
+}
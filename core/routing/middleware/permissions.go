@@ -0,0 +1,77 @@
+package middleware
+
+import (
+	"goplugins/core/routing"
+)
+
+type (
+	// PermissionsConfig defines the config for Permissions middleware.
+	PermissionsConfig struct {
+		// Skipper defines a function to skip middleware.
+		Skipper Skipper
+
+		// Authorize reports whether the request may access a route requiring
+		// permissions, e.g. by checking the permissions of the signed in user.
+		// Errors are returned as they are, e.g. routing.ErrUnauthorized if no
+		// user is signed in.
+		// Required.
+		Authorize func(c routing.Context, permissions []string) (bool, error)
+	}
+)
+
+var (
+	// DefaultPermissionsConfig is the default Permissions middleware config.
+	DefaultPermissionsConfig = PermissionsConfig{
+		Skipper: DefaultSkipper,
+	}
+)
+
+// Permissions returns a middleware which checks the permissions required by
+// the matched route, see `Route#RequirePermissions()`.
+//
+//	m.Use(middleware.Permissions(func(c routing.Context, permissions []string) (bool, error) {
+//		return user(c).HasAll(permissions), nil
+//	}))
+//	m.DELETE("/users/:id", deleteUser).RequirePermissions("users.delete")
+func Permissions(authorize func(c routing.Context, permissions []string) (bool, error)) routing.MiddlewareFunc {
+	c := DefaultPermissionsConfig
+	c.Authorize = authorize
+	return PermissionsWithConfig(c)
+}
+
+// PermissionsWithConfig returns a Permissions middleware with config.
+// See: `Permissions()`.
+//
+// Requests to routes without required permissions are passed on, requests
+// which are not authorized are rejected with routing.ErrForbidden. The route
+// is only known after routing, so the middleware can't be used with `Pre()`.
+func PermissionsWithConfig(config PermissionsConfig) routing.MiddlewareFunc {
+	// Defaults
+	if config.Skipper == nil {
+		config.Skipper = DefaultPermissionsConfig.Skipper
+	}
+	if config.Authorize == nil {
+		panic("routing: permissions middleware requires authorize function")
+	}
+
+	return func(next routing.HandlerFunc) routing.HandlerFunc {
+		return func(c routing.Context) error {
+			if config.Skipper(c) {
+				return next(c)
+			}
+
+			route := c.Route()
+			if route == nil || len(route.Permissions) == 0 {
+				return next(c)
+			}
+			ok, err := config.Authorize(c, route.Permissions)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				return routing.ErrForbidden
+			}
+			return next(c)
+		}
+	}
+}
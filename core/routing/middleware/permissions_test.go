@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"errors"
+	"goplugins/core/routing"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPermissions(t *testing.T) {
+	granted := map[string]bool{"users.read": true}
+	var checked []string
+	m := routing.New()
+	m.Use(Permissions(func(c routing.Context, permissions []string) (bool, error) {
+		checked = permissions
+		if c.QueryParam("anonymous") != "" {
+			return false, routing.ErrUnauthorized
+		}
+		for _, p := range permissions {
+			if !granted[p] {
+				return false, nil
+			}
+		}
+		return true, nil
+	}))
+	h := func(c routing.Context) error {
+		return c.NoContent(http.StatusOK)
+	}
+	m.GET("/public", h)
+	m.GET("/users", h).RequirePermissions("users.read")
+	m.DELETE("/users", h).RequirePermissions("users.read", "users.delete")
+
+	code, _ := requestCode(m, http.MethodGet, "/public")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Nil(t, checked)
+	code, _ = requestCode(m, http.MethodGet, "/users")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, []string{"users.read"}, checked)
+	code, _ = requestCode(m, http.MethodDelete, "/users")
+	assert.Equal(t, http.StatusForbidden, code)
+	code, _ = requestCode(m, http.MethodGet, "/users?anonymous=1")
+	assert.Equal(t, http.StatusUnauthorized, code)
+	code, _ = requestCode(m, http.MethodGet, "/unknown")
+	assert.Equal(t, http.StatusNotFound, code)
+}
+
+func TestPermissionsError(t *testing.T) {
+	err := errors.New("lookup failed")
+	m := routing.New()
+	m.Use(Permissions(func(c routing.Context, permissions []string) (bool, error) {
+		return false, err
+	}))
+	m.GET("/", func(c routing.Context) error { return nil }).RequirePermissions("read")
+
+	code, _ := requestCode(m, http.MethodGet, "/")
+	assert.Equal(t, http.StatusInternalServerError, code)
+	assert.Panics(t, func() {
+		Permissions(nil)
+	})
+}
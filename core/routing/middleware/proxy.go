@@ -0,0 +1,390 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"goplugins/core/routing"
+	"math/rand"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type (
+	// ProxyConfig defines the config for Proxy middleware.
+	ProxyConfig struct {
+		// Skipper defines a function to skip middleware.
+		Skipper Skipper
+
+		// Balancer defines a load balancing technique.
+		// Required.
+		Balancer ProxyBalancer
+
+		// Rewrite defines URL path rewrite rules applied to the proxied
+		// request, see RewriteConfig.
+		// Examples:
+		// "/old":              "/new",
+		// "/api/*":            "/$1",
+		// "/users/*/orders/*": "/user/$1/order/$2",
+		Rewrite map[string]string
+
+		// RegexRewrite defines rewrite rules using regexp.Regexp with captures.
+		RegexRewrite map[*regexp.Regexp]string
+
+		// ContextKey defines the key that will be used to store the selected
+		// target in the context.
+		// Optional. Default value "target".
+		ContextKey string
+
+		// Transport is used to send requests to the targets.
+		// Optional. Default value http.DefaultTransport.
+		Transport http.RoundTripper
+
+		// ModifyResponse defines function to modify the response from the
+		// target.
+		// Optional.
+		ModifyResponse func(*http.Response) error
+	}
+
+	// ProxyTarget defines the upstream target.
+	ProxyTarget struct {
+		Name string
+		URL  *url.URL
+		Meta routing.Map
+
+		down int32
+	}
+
+	// ProxyBalancer defines an interface to implement a load balancing
+	// technique.
+	ProxyBalancer interface {
+		// AddTarget adds target unless a target with the same name exists.
+		AddTarget(*ProxyTarget) bool
+		// RemoveTarget removes the target with the given name.
+		RemoveTarget(string) bool
+		// Targets returns all targets, healthy or not.
+		Targets() []*ProxyTarget
+		// Next returns the target for the request, or nil if no target is
+		// healthy.
+		Next(routing.Context) *ProxyTarget
+	}
+
+	// ProxyHealthCheckConfig defines the config of active health checks.
+	ProxyHealthCheckConfig struct {
+		// Path is requested on every target, which is healthy if it responds
+		// with a 2xx or 3xx status.
+		// Optional. Default value "/".
+		Path string
+
+		// Interval between two checks.
+		// Optional. Default value 10 seconds.
+		Interval time.Duration
+
+		// Timeout of a check.
+		// Optional. Default value 2 seconds.
+		Timeout time.Duration
+
+		// Client used to send the checks.
+		// Optional. Default value http.DefaultClient.
+		Client *http.Client
+	}
+
+	commonBalancer struct {
+		targets []*ProxyTarget
+		mutex   sync.RWMutex
+	}
+
+	// randomBalancer implements a random load balancing technique.
+	randomBalancer struct {
+		*commonBalancer
+		random *rand.Rand
+		mutex  sync.Mutex
+	}
+
+	// roundRobinBalancer implements a round-robin load balancing technique.
+	roundRobinBalancer struct {
+		*commonBalancer
+		i uint32
+	}
+)
+
+// StatusCodeContextCanceled is used for requests canceled by the client
+// before the target responded.
+const StatusCodeContextCanceled = 499
+
+var (
+	// DefaultProxyConfig is the default Proxy middleware config.
+	DefaultProxyConfig = ProxyConfig{
+		Skipper:    DefaultSkipper,
+		ContextKey: "target",
+	}
+
+	// DefaultProxyHealthCheckConfig is the default config of health checks.
+	DefaultProxyHealthCheckConfig = ProxyHealthCheckConfig{
+		Path:     "/",
+		Interval: 10 * time.Second,
+		Timeout:  2 * time.Second,
+	}
+
+	// ErrNoHealthyTarget is returned if all targets are down.
+	ErrNoHealthyTarget = routing.NewHTTPError(http.StatusServiceUnavailable, "no healthy upstream")
+)
+
+// Healthy reports whether the target is up, i.e. it passed the last health
+// check. Targets are healthy until checked.
+func (t *ProxyTarget) Healthy() bool {
+	return atomic.LoadInt32(&t.down) == 0
+}
+
+// SetHealthy marks the target as up or down.
+func (t *ProxyTarget) SetHealthy(healthy bool) {
+	var down int32
+	if !healthy {
+		down = 1
+	}
+	atomic.StoreInt32(&t.down, down)
+}
+
+// NewRandomBalancer returns a random proxy balancer.
+func NewRandomBalancer(targets []*ProxyTarget) ProxyBalancer {
+	b := &randomBalancer{commonBalancer: new(commonBalancer)}
+	b.targets = targets
+	b.random = rand.New(rand.NewSource(time.Now().UnixNano()))
+	return b
+}
+
+// NewRoundRobinBalancer returns a round-robin proxy balancer.
+func NewRoundRobinBalancer(targets []*ProxyTarget) ProxyBalancer {
+	b := &roundRobinBalancer{commonBalancer: new(commonBalancer)}
+	b.targets = targets
+	return b
+}
+
+// AddTarget adds an upstream target to the list.
+func (b *commonBalancer) AddTarget(target *ProxyTarget) bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	for _, t := range b.targets {
+		if t.Name == target.Name {
+			return false
+		}
+	}
+	b.targets = append(b.targets, target)
+	return true
+}
+
+// RemoveTarget removes an upstream target from the list.
+func (b *commonBalancer) RemoveTarget(name string) bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	for i, t := range b.targets {
+		if t.Name == name {
+			b.targets = append(b.targets[:i:i], b.targets[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Targets returns the upstream targets.
+func (b *commonBalancer) Targets() []*ProxyTarget {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+	return append([]*ProxyTarget(nil), b.targets...)
+}
+
+// healthy returns the healthy targets.
+func (b *commonBalancer) healthy() []*ProxyTarget {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+	targets := make([]*ProxyTarget, 0, len(b.targets))
+	for _, t := range b.targets {
+		if t.Healthy() {
+			targets = append(targets, t)
+		}
+	}
+	return targets
+}
+
+// Next randomly returns a healthy upstream target.
+func (b *randomBalancer) Next(c routing.Context) *ProxyTarget {
+	targets := b.healthy()
+	if len(targets) == 0 {
+		return nil
+	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return targets[b.random.Intn(len(targets))]
+}
+
+// Next returns the next healthy upstream target in round-robin order.
+func (b *roundRobinBalancer) Next(c routing.Context) *ProxyTarget {
+	targets := b.healthy()
+	if len(targets) == 0 {
+		return nil
+	}
+	i := atomic.AddUint32(&b.i, 1) - 1
+	return targets[i%uint32(len(targets))]
+}
+
+// Proxy returns a Proxy middleware which forwards requests to the targets of
+// balancer.
+//
+//	targets := []*middleware.ProxyTarget{{Name: "legacy", URL: legacyURL}}
+//	g := m.Group("/legacy", middleware.Proxy(middleware.NewRoundRobinBalancer(targets)))
+//
+// Proxy middleware forwards the request to upstream server using a configured
+// load balancing technique.
+func Proxy(balancer ProxyBalancer) routing.MiddlewareFunc {
+	c := DefaultProxyConfig
+	c.Balancer = balancer
+	return ProxyWithConfig(c)
+}
+
+// ProxyWithConfig returns a Proxy middleware with config.
+// See: `Proxy()`.
+//
+// The X-Forwarded-For header is extended by the client IP and the
+// X-Real-IP, X-Forwarded-Proto and X-Forwarded-Host headers are set unless
+// present already. WebSocket upgrades are passed through, the connection is
+// tunneled to the target once it accepts the upgrade.
+func ProxyWithConfig(config ProxyConfig) routing.MiddlewareFunc {
+	// Defaults
+	if config.Skipper == nil {
+		config.Skipper = DefaultProxyConfig.Skipper
+	}
+	if config.ContextKey == "" {
+		config.ContextKey = DefaultProxyConfig.ContextKey
+	}
+	if config.Balancer == nil {
+		panic("routing: proxy middleware requires balancer")
+	}
+	rules := compileRewriteRules(config.Rewrite, config.RegexRewrite)
+
+	return func(next routing.HandlerFunc) routing.HandlerFunc {
+		return func(c routing.Context) error {
+			if config.Skipper(c) {
+				return next(c)
+			}
+
+			tgt := config.Balancer.Next(c)
+			if tgt == nil {
+				return ErrNoHealthyTarget
+			}
+			c.Set(config.ContextKey, tgt)
+
+			req := c.Request()
+			if req.Header.Get(routing.HeaderXRealIP) == "" {
+				req.Header.Set(routing.HeaderXRealIP, c.RealIP())
+			}
+			if req.Header.Get(routing.HeaderXForwardedProto) == "" {
+				req.Header.Set(routing.HeaderXForwardedProto, c.Scheme())
+			}
+			if req.Header.Get(routing.HeaderXForwardedHost) == "" {
+				req.Header.Set(routing.HeaderXForwardedHost, req.Host)
+			}
+
+			var proxyErr error
+			proxy := httputil.NewSingleHostReverseProxy(tgt.URL)
+			director := proxy.Director
+			proxy.Director = func(r *http.Request) {
+				// Rewrite the path relative to the target URL.
+				rewriteURL(rules, r.URL)
+				director(r)
+				r.Host = tgt.URL.Host
+			}
+			proxy.Transport = config.Transport
+			proxy.ModifyResponse = config.ModifyResponse
+			proxy.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+				proxyErr = err
+			}
+			proxy.ServeHTTP(c.Response(), req)
+
+			if proxyErr == nil {
+				return nil
+			}
+			if errors.Is(proxyErr, context.Canceled) {
+				return routing.NewHTTPError(StatusCodeContextCanceled, "client closed request").SetInternal(proxyErr)
+			}
+			return routing.NewHTTPError(http.StatusBadGateway,
+				fmt.Sprintf("remote %s unreachable, could not forward", tgt.URL)).SetInternal(proxyErr)
+		}
+	}
+}
+
+// StartProxyHealthCheck checks the targets of balancer periodically and marks
+// those failing as down, so balancers skip them until they pass again. The
+// first check is run right away. Call stop to end the checks.
+func StartProxyHealthCheck(balancer ProxyBalancer, config ProxyHealthCheckConfig) (stop func()) {
+	// Defaults
+	if config.Path == "" {
+		config.Path = DefaultProxyHealthCheckConfig.Path
+	}
+	if config.Interval == 0 {
+		config.Interval = DefaultProxyHealthCheckConfig.Interval
+	}
+	if config.Timeout == 0 {
+		config.Timeout = DefaultProxyHealthCheckConfig.Timeout
+	}
+	if config.Client == nil {
+		config.Client = http.DefaultClient
+	}
+
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(config.Interval)
+		defer ticker.Stop()
+		for {
+			checkTargets(balancer.Targets(), config)
+			select {
+			case <-ticker.C:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(done)
+		})
+	}
+}
+
+func checkTargets(targets []*ProxyTarget, config ProxyHealthCheckConfig) {
+	var wg sync.WaitGroup
+	for _, t := range targets {
+		wg.Add(1)
+		go func(t *ProxyTarget) {
+			defer wg.Done()
+			t.SetHealthy(checkTarget(t, config))
+		}(t)
+	}
+	wg.Wait()
+}
+
+func checkTarget(t *ProxyTarget, config ProxyHealthCheckConfig) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), config.Timeout)
+	defer cancel()
+
+	u := *t.URL
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/" + strings.TrimPrefix(config.Path, "/")
+	u.RawPath = ""
+	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	if err != nil {
+		return false
+	}
+	res, err := config.Client.Do(req.WithContext(ctx))
+	if err != nil {
+		return false
+	}
+	res.Body.Close()
+	return res.StatusCode >= 200 && res.StatusCode < 400
+}
@@ -0,0 +1,184 @@
+package middleware
+
+import (
+	"bufio"
+	"goplugins/core/routing"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newProxyTarget(t *testing.T, name string, h http.HandlerFunc) (*ProxyTarget, *httptest.Server) {
+	srv := httptest.NewServer(h)
+	u, err := url.Parse(srv.URL)
+	require.NoError(t, err)
+	return &ProxyTarget{Name: name, URL: u}, srv
+}
+
+func TestProxy(t *testing.T) {
+	t1, s1 := newProxyTarget(t, "target 1", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "target 1")
+	})
+	defer s1.Close()
+	t2, s2 := newProxyTarget(t, "target 2", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "target 2")
+	})
+	defer s2.Close()
+	targets := []*ProxyTarget{t1, t2}
+
+	// Random
+	m := routing.New()
+	m.Use(Proxy(NewRandomBalancer(targets)))
+	code, body := requestCode(m, http.MethodGet, "/")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Contains(t, []string{"target 1", "target 2"}, body)
+
+	// Round-robin
+	m = routing.New()
+	m.Use(Proxy(NewRoundRobinBalancer(targets)))
+	_, body = requestCode(m, http.MethodGet, "/")
+	assert.Equal(t, "target 1", body)
+	_, body = requestCode(m, http.MethodGet, "/")
+	assert.Equal(t, "target 2", body)
+	_, body = requestCode(m, http.MethodGet, "/")
+	assert.Equal(t, "target 1", body)
+
+	// Unhealthy targets are skipped
+	t1.SetHealthy(false)
+	for i := 0; i < 2; i++ {
+		_, body = requestCode(m, http.MethodGet, "/")
+		assert.Equal(t, "target 2", body)
+	}
+	t2.SetHealthy(false)
+	code, _ = requestCode(m, http.MethodGet, "/")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+}
+
+func TestProxyHeaders(t *testing.T) {
+	var received *http.Request
+	target, srv := newProxyTarget(t, "target", func(w http.ResponseWriter, r *http.Request) {
+		received = r
+		w.Header().Set("X-Upstream", "test")
+		w.WriteHeader(http.StatusCreated)
+	})
+	defer srv.Close()
+	target.URL.Path = "/base"
+
+	m := routing.New()
+	m.Use(ProxyWithConfig(ProxyConfig{
+		Balancer: NewRoundRobinBalancer([]*ProxyTarget{target}),
+		Rewrite: map[string]string{
+			"/api/*": "/v2/$1",
+		},
+	}))
+	req := httptest.NewRequest(http.MethodGet, "http://example.com/api/users?page=2", nil)
+	req.Header.Set(routing.HeaderXForwardedFor, "203.0.113.1")
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, req)
+	require.NotNil(t, received)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Equal(t, "test", rec.Header().Get("X-Upstream"))
+	assert.Equal(t, "/base/v2/users", received.URL.Path)
+	assert.Equal(t, "page=2", received.URL.RawQuery)
+	assert.Equal(t, target.URL.Host, received.Host)
+	assert.Equal(t, "example.com", received.Header.Get(routing.HeaderXForwardedHost))
+	assert.Equal(t, "http", received.Header.Get(routing.HeaderXForwardedProto))
+	assert.Equal(t, "203.0.113.1", received.Header.Get(routing.HeaderXRealIP))
+	assert.Equal(t, "203.0.113.1, 192.0.2.1", received.Header.Get(routing.HeaderXForwardedFor))
+}
+
+func TestProxyError(t *testing.T) {
+	target, srv := newProxyTarget(t, "target", func(w http.ResponseWriter, r *http.Request) {})
+	srv.Close()
+
+	m := routing.New()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	c := m.NewContext(req, httptest.NewRecorder())
+	err := Proxy(NewRandomBalancer([]*ProxyTarget{target}))(nil)(c)
+	if assert.IsType(t, &routing.HTTPError{}, err) {
+		assert.Equal(t, http.StatusBadGateway, err.(*routing.HTTPError).Code)
+	}
+	assert.Equal(t, target, c.Get("target"))
+}
+
+func TestProxyBalancerTargets(t *testing.T) {
+	u, _ := url.Parse("http://localhost")
+	b := NewRoundRobinBalancer(nil)
+	assert.Nil(t, b.Next(nil))
+	assert.True(t, b.AddTarget(&ProxyTarget{Name: "a", URL: u}))
+	assert.False(t, b.AddTarget(&ProxyTarget{Name: "a", URL: u}))
+	assert.True(t, b.AddTarget(&ProxyTarget{Name: "b", URL: u}))
+	assert.Len(t, b.Targets(), 2)
+	assert.True(t, b.RemoveTarget("a"))
+	assert.False(t, b.RemoveTarget("a"))
+	assert.Equal(t, "b", b.Next(nil).Name)
+}
+
+func TestProxyHealthCheck(t *testing.T) {
+	healthy := make(chan bool, 1)
+	healthy <- false
+	target, srv := newProxyTarget(t, "target", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/base/health", r.URL.Path)
+		ok := <-healthy
+		healthy <- ok
+		if !ok {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	})
+	defer srv.Close()
+	target.URL.Path = "/base/"
+	b := NewRoundRobinBalancer([]*ProxyTarget{target})
+
+	stop := StartProxyHealthCheck(b, ProxyHealthCheckConfig{Path: "/health", Interval: 10 * time.Millisecond})
+	defer stop()
+	assert.Eventually(t, func() bool { return !target.Healthy() }, time.Second, 5*time.Millisecond)
+	<-healthy
+	healthy <- true
+	assert.Eventually(t, target.Healthy, time.Second, 5*time.Millisecond)
+	stop()
+	stop()
+}
+
+func TestProxyWebSocket(t *testing.T) {
+	target, srv := newProxyTarget(t, "target", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "websocket", r.Header.Get(routing.HeaderUpgrade))
+		conn, rw, err := w.(http.Hijacker).Hijack()
+		if !assert.NoError(t, err) {
+			return
+		}
+		defer conn.Close()
+		rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n\r\n")
+		rw.Flush()
+		// Echo a line
+		line, _ := rw.ReadString('\n')
+		rw.WriteString("echo " + line)
+		rw.Flush()
+	})
+	defer srv.Close()
+
+	m := routing.New()
+	m.Use(Proxy(NewRoundRobinBalancer([]*ProxyTarget{target})))
+	front := httptest.NewServer(m)
+	defer front.Close()
+
+	conn, err := net.Dial("tcp", front.Listener.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	io.WriteString(conn, "GET /ws HTTP/1.1\r\nHost: example.com\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n\r\n")
+	r := bufio.NewReader(conn)
+	res, err := http.ReadResponse(r, nil)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusSwitchingProtocols, res.StatusCode)
+
+	io.WriteString(conn, "hello\n")
+	line, err := r.ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, "echo hello\n", line)
+}
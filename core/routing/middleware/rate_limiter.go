@@ -0,0 +1,371 @@
+package middleware
+
+import (
+	"fmt"
+	"goplugins/core/routing"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+type (
+	// RateLimiterStore keeps the state of a rate limit and decides whether a
+	// request is allowed. Implement it on top of a shared backend, e.g. Redis,
+	// to enforce limits across several instances.
+	RateLimiterStore interface {
+		// Allow counts a request for identifier and returns the resulting
+		// state of its limit.
+		Allow(identifier string) (RateLimitResult, error)
+	}
+
+	// RateLimitResult is the state of a limit after a request.
+	RateLimitResult struct {
+		// Allowed reports whether the request is allowed.
+		Allowed bool
+		// Limit is the number of requests allowed within the window.
+		Limit int
+		// Remaining is the number of requests left.
+		Remaining int
+		// Reset is the time until the limit is fully available again.
+		Reset time.Duration
+		// RetryAfter is the time until the next request is allowed, if the
+		// request was denied.
+		RetryAfter time.Duration
+	}
+
+	// RateLimitAlgorithm selects how RateLimiterMemoryStore counts requests.
+	RateLimitAlgorithm int
+
+	// Extractor is used to extract data from routing.Context
+	Extractor func(context routing.Context) (string, error)
+
+	// RateLimiterConfig defines the configuration for the rate limiter
+	RateLimiterConfig struct {
+		Skipper    Skipper
+		BeforeFunc BeforeFunc
+		// IdentifierExtractor uses routing.Context to extract the identifier for a visitor
+		// Optional. Default value RateLimitByIP.
+		IdentifierExtractor Extractor
+		// Store defines a store for the rate limiter
+		// Required.
+		Store RateLimiterStore
+		// ErrorHandler provides a handler to be called when IdentifierExtractor returns an error
+		ErrorHandler func(context routing.Context, err error) error
+		// DenyHandler provides a handler to be called when RateLimiter denies access
+		DenyHandler func(context routing.Context, identifier string, err error) error
+	}
+
+	// RateLimiterMemoryStoreConfig defines the config of RateLimiterMemoryStore.
+	RateLimiterMemoryStoreConfig struct {
+		// Algorithm used to count requests.
+		// Optional. Default value TokenBucket.
+		Algorithm RateLimitAlgorithm
+		// Limit is the number of requests allowed within Window. Using
+		// TokenBucket it is the size of the bucket, i.e. the burst allowed.
+		// Required.
+		Limit int
+		// Window is the period Limit applies to.
+		// Optional. Default value 1 minute.
+		Window time.Duration
+		// ExpiresIn is the duration after which idle visitors are removed.
+		// Optional. Default value 3 minutes, but at least twice the Window.
+		ExpiresIn time.Duration
+	}
+
+	// RateLimiterMemoryStore is the built-in store keeping the limits in
+	// memory. It is safe for concurrent use.
+	RateLimiterMemoryStore struct {
+		RateLimiterMemoryStoreConfig
+
+		mutex       sync.Mutex
+		visitors    map[string]*visitor
+		lastCleanup time.Time
+		timeNow     func() time.Time
+	}
+
+	// visitor is the state of the limit of an identifier.
+	visitor struct {
+		// token bucket
+		tokens float64
+		// sliding window
+		windowStart time.Time
+		current     int
+		previous    int
+
+		lastSeen time.Time
+	}
+)
+
+// Algorithms of RateLimiterMemoryStore
+const (
+	// TokenBucket refills the allowed requests continuously, Limit per
+	// Window, and allows bursts of up to Limit requests.
+	TokenBucket RateLimitAlgorithm = iota
+	// SlidingWindow allows Limit requests within any period of Window. It
+	// approximates the window from the counts of the current and the previous
+	// fixed window.
+	SlidingWindow
+)
+
+// errors
+var (
+	// ErrRateLimitExceeded denotes an error raised when rate limit is exceeded
+	ErrRateLimitExceeded = routing.NewHTTPError(http.StatusTooManyRequests, "rate limit exceeded")
+	// ErrExtractorError denotes an error raised when extractor function is unsuccessful
+	ErrExtractorError = routing.NewHTTPError(http.StatusForbidden, "error while extracting identifier")
+)
+
+// DefaultRateLimiterConfig defines default values for RateLimiterConfig
+var DefaultRateLimiterConfig = RateLimiterConfig{
+	Skipper:             DefaultSkipper,
+	IdentifierExtractor: RateLimitByIP,
+	ErrorHandler: func(context routing.Context, err error) error {
+		return routing.NewHTTPError(ErrExtractorError.Code, ErrExtractorError.Message).SetInternal(err)
+	},
+	DenyHandler: func(context routing.Context, identifier string, err error) error {
+		return ErrRateLimitExceeded
+	},
+}
+
+// DefaultRateLimiterMemoryStoreConfig provides default configuration values for RateLimiterMemoryStore
+var DefaultRateLimiterMemoryStoreConfig = RateLimiterMemoryStoreConfig{
+	Algorithm: TokenBucket,
+	Window:    time.Minute,
+	ExpiresIn: 3 * time.Minute,
+}
+
+// RateLimitByIP identifies visitors by their IP, see `Context#RealIP()`.
+func RateLimitByIP(c routing.Context) (string, error) {
+	return c.RealIP(), nil
+}
+
+// RateLimitByContextKey returns an Extractor identifying visitors by the
+// value stored under key in the context, e.g. the ID of the authenticated
+// user. Visitors without one are identified by their IP.
+func RateLimitByContextKey(key string) Extractor {
+	return func(c routing.Context) (string, error) {
+		if v := c.Get(key); v != nil {
+			return key + ":" + fmt.Sprint(v), nil
+		}
+		return RateLimitByIP(c)
+	}
+}
+
+// RateLimiter returns a rate limiting middleware using store, which
+// identifies visitors by their IP.
+//
+//	limiterStore := middleware.NewRateLimiterMemoryStore(20, time.Minute)
+//	m.POST("/user", h, middleware.RateLimiter(limiterStore))
+//
+// Add it to a group to apply the limit to all routes of the group together.
+func RateLimiter(store RateLimiterStore) routing.MiddlewareFunc {
+	config := DefaultRateLimiterConfig
+	config.Store = store
+
+	return RateLimiterWithConfig(config)
+}
+
+// RateLimiterWithConfig returns a rate limiting middleware with config.
+// See: `RateLimiter()`.
+//
+// Every response carries the RateLimit-Limit, RateLimit-Remaining and
+// RateLimit-Reset headers. Denied requests are answered by the DenyHandler,
+// by default with 429 Too Many Requests and a Retry-After header.
+func RateLimiterWithConfig(config RateLimiterConfig) routing.MiddlewareFunc {
+	if config.Skipper == nil {
+		config.Skipper = DefaultRateLimiterConfig.Skipper
+	}
+	if config.IdentifierExtractor == nil {
+		config.IdentifierExtractor = DefaultRateLimiterConfig.IdentifierExtractor
+	}
+	if config.ErrorHandler == nil {
+		config.ErrorHandler = DefaultRateLimiterConfig.ErrorHandler
+	}
+	if config.DenyHandler == nil {
+		config.DenyHandler = DefaultRateLimiterConfig.DenyHandler
+	}
+	if config.Store == nil {
+		panic("routing: rate limiter middleware requires a store")
+	}
+
+	return func(next routing.HandlerFunc) routing.HandlerFunc {
+		return func(c routing.Context) error {
+			if config.Skipper(c) {
+				return next(c)
+			}
+			if config.BeforeFunc != nil {
+				config.BeforeFunc(c)
+			}
+
+			identifier, err := config.IdentifierExtractor(c)
+			if err != nil {
+				return config.ErrorHandler(c, err)
+			}
+
+			result, err := config.Store.Allow(identifier)
+			if err != nil {
+				return err
+			}
+
+			header := c.Response().Header()
+			header.Set(routing.HeaderRateLimitLimit, strconv.Itoa(result.Limit))
+			header.Set(routing.HeaderRateLimitRemaining, strconv.Itoa(result.Remaining))
+			header.Set(routing.HeaderRateLimitReset, strconv.Itoa(seconds(result.Reset)))
+			if !result.Allowed {
+				header.Set(routing.HeaderRetryAfter, strconv.Itoa(seconds(result.RetryAfter)))
+				return config.DenyHandler(c, identifier, nil)
+			}
+			return next(c)
+		}
+	}
+}
+
+// NewRateLimiterMemoryStore returns a memory store allowing limit requests
+// per window using the TokenBucket algorithm.
+func NewRateLimiterMemoryStore(limit int, window time.Duration) *RateLimiterMemoryStore {
+	return NewRateLimiterMemoryStoreWithConfig(RateLimiterMemoryStoreConfig{
+		Limit:  limit,
+		Window: window,
+	})
+}
+
+// NewRateLimiterMemoryStoreWithConfig returns a memory store with config.
+func NewRateLimiterMemoryStoreWithConfig(config RateLimiterMemoryStoreConfig) *RateLimiterMemoryStore {
+	if config.Limit <= 0 {
+		panic("routing: rate limiter store requires a positive limit")
+	}
+	if config.Window <= 0 {
+		config.Window = DefaultRateLimiterMemoryStoreConfig.Window
+	}
+	if config.ExpiresIn <= 0 {
+		config.ExpiresIn = DefaultRateLimiterMemoryStoreConfig.ExpiresIn
+	}
+	if config.ExpiresIn < 2*config.Window {
+		// The sliding window needs the count of the previous window.
+		config.ExpiresIn = 2 * config.Window
+	}
+
+	return &RateLimiterMemoryStore{
+		RateLimiterMemoryStoreConfig: config,
+		visitors:                     map[string]*visitor{},
+		timeNow:                      time.Now,
+	}
+}
+
+// Allow implements RateLimiterStore.Allow.
+func (store *RateLimiterMemoryStore) Allow(identifier string) (RateLimitResult, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	now := store.timeNow()
+	v, ok := store.visitors[identifier]
+	if !ok {
+		v = &visitor{tokens: float64(store.Limit), windowStart: now}
+		store.visitors[identifier] = v
+	}
+	v.lastSeen = now
+	if now.Sub(store.lastCleanup) > store.ExpiresIn {
+		store.cleanupStaleVisitors(now)
+	}
+
+	if store.Algorithm == SlidingWindow {
+		return store.slidingWindow(v, now), nil
+	}
+	return store.tokenBucket(v, now), nil
+}
+
+func (store *RateLimiterMemoryStore) tokenBucket(v *visitor, now time.Time) RateLimitResult {
+	limit := float64(store.Limit)
+	rate := limit / float64(store.Window) // tokens per nanosecond
+
+	v.tokens = math.Min(limit, v.tokens+float64(now.Sub(v.windowStart))*rate)
+	v.windowStart = now
+
+	result := RateLimitResult{Limit: store.Limit}
+	if v.tokens >= 1 {
+		v.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = ceilDuration((1 - v.tokens) / rate)
+	}
+	result.Remaining = int(v.tokens)
+	result.Reset = ceilDuration((limit - v.tokens) / rate)
+	return result
+}
+
+func (store *RateLimiterMemoryStore) slidingWindow(v *visitor, now time.Time) RateLimitResult {
+	window := store.Window
+	if elapsed := now.Sub(v.windowStart); elapsed >= window {
+		windows := elapsed / window
+		if windows == 1 {
+			v.previous = v.current
+		} else {
+			v.previous = 0
+		}
+		v.current = 0
+		v.windowStart = v.windowStart.Add(windows * window)
+	}
+	elapsed := now.Sub(v.windowStart)
+
+	// weight of the previous window still overlapping the sliding window
+	weight := 1 - float64(elapsed)/float64(window)
+	count := func() float64 {
+		return float64(v.previous)*weight + float64(v.current)
+	}
+
+	result := RateLimitResult{Limit: store.Limit}
+	if count()+1 <= float64(store.Limit) {
+		v.current++
+		result.Allowed = true
+	} else {
+		result.RetryAfter = store.retryAfter(v, elapsed)
+	}
+	result.Remaining = int(math.Max(0, math.Floor(float64(store.Limit)-count())))
+	result.Reset = window - elapsed
+	if v.current > 0 {
+		// until the requests of the current window have left the sliding window
+		result.Reset += window
+	}
+	return result
+}
+
+// retryAfter returns the time until the sliding window of v has room for
+// another request.
+func (store *RateLimiterMemoryStore) retryAfter(v *visitor, elapsed time.Duration) time.Duration {
+	window := float64(store.Window)
+	room := float64(store.Limit - 1)
+
+	if float64(v.current) <= room && v.previous > 0 {
+		// previous*(1-(elapsed+d)/window) + current <= room
+		d := window*(1-(room-float64(v.current))/float64(v.previous)) - float64(elapsed)
+		if d >= 0 && d <= window-float64(elapsed) {
+			return ceilDuration(d)
+		}
+	}
+	// wait for the next window, where current becomes the previous count:
+	// current*(1-d/window) <= room
+	d := window * (1 - room/float64(v.current))
+	return store.Window - elapsed + ceilDuration(math.Max(0, d))
+}
+
+func (store *RateLimiterMemoryStore) cleanupStaleVisitors(now time.Time) {
+	for id, v := range store.visitors {
+		if now.Sub(v.lastSeen) > store.ExpiresIn {
+			delete(store.visitors, id)
+		}
+	}
+	store.lastCleanup = now
+}
+
+// ceilDuration converts nanoseconds to a duration rounded up to full
+// milliseconds, which absorbs floating point errors.
+func ceilDuration(ns float64) time.Duration {
+	return time.Duration(math.Ceil(ns/float64(time.Millisecond))) * time.Millisecond
+}
+
+// seconds rounds d up to full seconds.
+func seconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
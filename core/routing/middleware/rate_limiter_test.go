@@ -0,0 +1,215 @@
+package middleware
+
+import (
+	"errors"
+	"goplugins/core/routing"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type clock struct {
+	now time.Time
+}
+
+func (c *clock) Now() time.Time {
+	return c.now
+}
+
+func (c *clock) Add(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+func newTestStore(algorithm RateLimitAlgorithm, limit int, window time.Duration) (*RateLimiterMemoryStore, *clock) {
+	clk := &clock{now: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)}
+	store := NewRateLimiterMemoryStoreWithConfig(RateLimiterMemoryStoreConfig{
+		Algorithm: algorithm,
+		Limit:     limit,
+		Window:    window,
+	})
+	store.timeNow = clk.Now
+	return store, clk
+}
+
+func allowed(t *testing.T, store RateLimiterStore, id string, n int) int {
+	count := 0
+	for i := 0; i < n; i++ {
+		result, err := store.Allow(id)
+		require.NoError(t, err)
+		if result.Allowed {
+			count++
+		}
+	}
+	return count
+}
+
+func TestRateLimiter(t *testing.T) {
+	store, _ := newTestStore(TokenBucket, 3, time.Minute)
+	mw := RateLimiter(store)
+	h := mw(func(c routing.Context) error {
+		return c.String(http.StatusOK, "test")
+	})
+	m := routing.New()
+
+	for i, want := range []int{200, 200, 200, 429} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(routing.HeaderXRealIP, "127.0.0.1")
+		rec := httptest.NewRecorder()
+		c := m.NewContext(req, rec)
+		err := h(c)
+		if want == http.StatusOK {
+			require.NoError(t, err)
+			assert.Equal(t, "3", rec.Header().Get(routing.HeaderRateLimitLimit))
+			assert.Equal(t, []string{"2", "1", "0"}[i], rec.Header().Get(routing.HeaderRateLimitRemaining))
+			continue
+		}
+		assert.Equal(t, ErrRateLimitExceeded, err)
+		assert.Equal(t, "0", rec.Header().Get(routing.HeaderRateLimitRemaining))
+		assert.Equal(t, "20", rec.Header().Get(routing.HeaderRetryAfter))
+		assert.Equal(t, "60", rec.Header().Get(routing.HeaderRateLimitReset))
+	}
+
+	// other visitors have their own limit
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(routing.HeaderXRealIP, "127.0.0.2")
+	assert.NoError(t, h(m.NewContext(req, httptest.NewRecorder())))
+}
+
+func TestRateLimiterWithConfig(t *testing.T) {
+	store, _ := newTestStore(TokenBucket, 1, time.Minute)
+	mw := RateLimiterWithConfig(RateLimiterConfig{
+		IdentifierExtractor: func(c routing.Context) (string, error) {
+			id := c.Request().Header.Get("X-API-Key")
+			if id == "" {
+				return "", errors.New("missing api key")
+			}
+			return id, nil
+		},
+		DenyHandler: func(c routing.Context, identifier string, err error) error {
+			return c.JSON(http.StatusTooManyRequests, map[string]string{"key": identifier})
+		},
+		Store: store,
+	})
+	m := routing.New()
+	h := mw(func(c routing.Context) error {
+		return c.NoContent(http.StatusNoContent)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	err := h(m.NewContext(req, httptest.NewRecorder()))
+	he, ok := err.(*routing.HTTPError)
+	require.True(t, ok)
+	assert.Equal(t, http.StatusForbidden, he.Code)
+
+	req.Header.Set("X-API-Key", "abc")
+	assert.NoError(t, h(m.NewContext(req, httptest.NewRecorder())))
+	rec := httptest.NewRecorder()
+	assert.NoError(t, h(m.NewContext(req, rec)))
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.JSONEq(t, `{"key":"abc"}`, rec.Body.String())
+
+	assert.Panics(t, func() {
+		RateLimiterWithConfig(RateLimiterConfig{})
+	})
+}
+
+func TestRateLimiterGroup(t *testing.T) {
+	store, _ := newTestStore(TokenBucket, 2, time.Minute)
+	m := routing.New()
+	h := func(c routing.Context) error {
+		return c.NoContent(http.StatusNoContent)
+	}
+	g := m.Group("/api", RateLimiter(store))
+	g.GET("/a", h)
+	g.GET("/b", h)
+	m.GET("/free", h)
+
+	code, _ := requestCode(m, http.MethodGet, "/api/a")
+	assert.Equal(t, http.StatusNoContent, code)
+	code, _ = requestCode(m, http.MethodGet, "/api/b")
+	assert.Equal(t, http.StatusNoContent, code)
+	code, _ = requestCode(m, http.MethodGet, "/api/a")
+	assert.Equal(t, http.StatusTooManyRequests, code)
+	code, _ = requestCode(m, http.MethodGet, "/free")
+	assert.Equal(t, http.StatusNoContent, code)
+}
+
+func TestRateLimitByContextKey(t *testing.T) {
+	m := routing.New()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(routing.HeaderXRealIP, "10.0.0.1")
+	c := m.NewContext(req, httptest.NewRecorder())
+
+	extract := RateLimitByContextKey("user")
+	id, err := extract(c)
+	require.NoError(t, err)
+	assert.Equal(t, "10.0.0.1", id)
+
+	c.Set("user", 42)
+	id, err = extract(c)
+	require.NoError(t, err)
+	assert.Equal(t, "user:42", id)
+}
+
+func TestTokenBucket(t *testing.T) {
+	store, clk := newTestStore(TokenBucket, 10, 10*time.Second)
+
+	assert.Equal(t, 10, allowed(t, store, "a", 15))
+	// one token per second is refilled
+	clk.Add(3 * time.Second)
+	assert.Equal(t, 3, allowed(t, store, "a", 5))
+	clk.Add(500 * time.Millisecond)
+	result, err := store.Allow("a")
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, 500*time.Millisecond, result.RetryAfter)
+	assert.Equal(t, 9500*time.Millisecond, result.Reset)
+
+	// the bucket never holds more than the limit
+	clk.Add(time.Hour)
+	assert.Equal(t, 10, allowed(t, store, "a", 15))
+}
+
+func TestSlidingWindow(t *testing.T) {
+	store, clk := newTestStore(SlidingWindow, 10, time.Minute)
+
+	assert.Equal(t, 10, allowed(t, store, "a", 15))
+	result, err := store.Allow("a")
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, 0, result.Remaining)
+	// the next window starts in a minute, a tenth of the previous requests
+	// has left the sliding window 6 seconds later
+	assert.Equal(t, 66*time.Second, result.RetryAfter)
+
+	// 30s into the next window half of the previous requests still count
+	clk.Add(90 * time.Second)
+	assert.Equal(t, 5, allowed(t, store, "a", 10))
+
+	clk.Add(45 * time.Second)
+	// previous window: 5 requests, 15s in: 5*0.75 = 3.75 count
+	result, err = store.Allow("a")
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, 5, result.Remaining)
+
+	// after two idle windows nothing counts anymore
+	clk.Add(3 * time.Minute)
+	assert.Equal(t, 10, allowed(t, store, "a", 10))
+}
+
+func TestRateLimiterMemoryStoreCleanup(t *testing.T) {
+	store, clk := newTestStore(TokenBucket, 1, time.Minute)
+	allowed(t, store, "a", 1)
+	clk.Add(time.Minute)
+	allowed(t, store, "b", 1)
+	assert.Len(t, store.visitors, 2)
+
+	clk.Add(4 * time.Minute)
+	allowed(t, store, "c", 1)
+	assert.Len(t, store.visitors, 1)
+}
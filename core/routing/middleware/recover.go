@@ -0,0 +1,109 @@
+package middleware
+
+import (
+	"fmt"
+	"goplugins/core/routing"
+	"net/http"
+	"runtime"
+)
+
+type (
+	// RecoverConfig defines the config for Recover middleware.
+	RecoverConfig struct {
+		// Skipper defines a function to skip middleware.
+		Skipper Skipper
+
+		// Size of the stack to be printed.
+		// Optional. Default value 4KB.
+		StackSize int
+
+		// DisableStackAll disables formatting stack traces of all other goroutines
+		// into buffer after the trace for the current goroutine.
+		// Optional. Default value false.
+		DisableStackAll bool
+
+		// DisablePrintStack disables printing stack trace.
+		// Optional. Default value as false.
+		DisablePrintStack bool
+	}
+
+	// panicError is a recovered panic along with the stack it was raised on.
+	panicError struct {
+		err   error
+		stack []byte
+	}
+)
+
+var (
+	// DefaultRecoverConfig is the default Recover middleware config.
+	DefaultRecoverConfig = RecoverConfig{
+		Skipper:           DefaultSkipper,
+		StackSize:         4 << 10, // 4 KB
+		DisableStackAll:   false,
+		DisablePrintStack: false,
+	}
+)
+
+// Recover returns a middleware which recovers from panics anywhere in the chain
+// and handles the control to the centralized HTTPErrorHandler.
+func Recover() routing.MiddlewareFunc {
+	return RecoverWithConfig(DefaultRecoverConfig)
+}
+
+// RecoverWithConfig returns a Recover middleware with config.
+// See: `Recover()`.
+//
+// The stack trace of the panic is logged unless DisablePrintStack is set. In
+// debug mode it is also passed on to the HTTPErrorHandler, which includes it in
+// the response.
+func RecoverWithConfig(config RecoverConfig) routing.MiddlewareFunc {
+	// Defaults
+	if config.Skipper == nil {
+		config.Skipper = DefaultRecoverConfig.Skipper
+	}
+	if config.StackSize == 0 {
+		config.StackSize = DefaultRecoverConfig.StackSize
+	}
+
+	return func(next routing.HandlerFunc) routing.HandlerFunc {
+		return func(c routing.Context) error {
+			if config.Skipper(c) {
+				return next(c)
+			}
+
+			defer func() {
+				if r := recover(); r != nil {
+					if r == http.ErrAbortHandler {
+						// The server aborts the response silently.
+						panic(r)
+					}
+					err, ok := r.(error)
+					if !ok {
+						err = fmt.Errorf("%v", r)
+					}
+					stack := make([]byte, config.StackSize)
+					length := runtime.Stack(stack, !config.DisableStackAll)
+					stack = stack[:length]
+					if !config.DisablePrintStack {
+						c.Logger().Printf("[PANIC RECOVER] %v %s\n", err, stack)
+					}
+					if c.Mux().Debug {
+						err = routing.NewHTTPError(http.StatusInternalServerError).SetInternal(&panicError{err: err, stack: stack})
+					}
+					c.Error(err)
+				}
+			}()
+			return next(c)
+		}
+	}
+}
+
+// Error makes it compatible with `error` interface.
+func (e *panicError) Error() string {
+	return fmt.Sprintf("%v\n%s", e.err, e.stack)
+}
+
+// Unwrap returns the recovered error.
+func (e *panicError) Unwrap() error {
+	return e.err
+}
@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"bytes"
+	"errors"
+	"goplugins/core/routing"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecover(t *testing.T) {
+	m := routing.New()
+	buf := new(bytes.Buffer)
+	m.Logger.SetOutput(buf)
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rec := httptest.NewRecorder()
+	c := m.NewContext(req, rec)
+	h := Recover()(routing.HandlerFunc(func(c routing.Context) error {
+		panic("test")
+	}))
+	h(c)
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.Contains(t, buf.String(), "PANIC RECOVER")
+	assert.NotContains(t, rec.Body.String(), "goroutine")
+}
+
+func TestRecoverDebug(t *testing.T) {
+	m := routing.New()
+	m.Debug = true
+	m.Logger.SetOutput(new(bytes.Buffer))
+	var handled error
+	m.HTTPErrorHandler = func(err error, c routing.Context) {
+		handled = err
+		m.DefaultHTTPErrorHandler(err, c)
+	}
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rec := httptest.NewRecorder()
+	c := m.NewContext(req, rec)
+	boom := errors.New("boom")
+	h := RecoverWithConfig(RecoverConfig{DisablePrintStack: true})(func(c routing.Context) error {
+		panic(boom)
+	})
+	h(c)
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.True(t, errors.Is(handled, boom))
+	assert.Contains(t, rec.Body.String(), "boom")
+	assert.Contains(t, rec.Body.String(), "goroutine")
+}
+
+func TestRecoverAbortHandler(t *testing.T) {
+	m := routing.New()
+	c := m.NewContext(httptest.NewRequest(http.MethodGet, "/", nil), httptest.NewRecorder())
+	h := Recover()(func(c routing.Context) error {
+		panic(http.ErrAbortHandler)
+	})
+	assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+		h(c)
+	})
+}
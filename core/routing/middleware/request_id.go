@@ -0,0 +1,87 @@
+package middleware
+
+import (
+	"goplugins/core/framework/crypto"
+	"goplugins/core/routing"
+)
+
+type (
+	// RequestIDConfig defines the config for RequestID middleware.
+	RequestIDConfig struct {
+		// Skipper defines a function to skip middleware.
+		Skipper Skipper
+
+		// Generator defines a function to generate an ID.
+		// Optional. Default value crypto.SecureToken.
+		Generator func() string
+
+		// RequestIDHandler defines a function which is executed for a request id.
+		RequestIDHandler func(routing.Context, string)
+	}
+)
+
+// maxRequestIDLength limits the length of request IDs taken from the request.
+const maxRequestIDLength = 128
+
+var (
+	// DefaultRequestIDConfig is the default RequestID middleware config.
+	DefaultRequestIDConfig = RequestIDConfig{
+		Skipper:   DefaultSkipper,
+		Generator: crypto.SecureToken,
+	}
+)
+
+// RequestID returns a X-Request-ID middleware.
+func RequestID() routing.MiddlewareFunc {
+	return RequestIDWithConfig(DefaultRequestIDConfig)
+}
+
+// RequestIDWithConfig returns a X-Request-ID middleware with config.
+//
+// An ID sent by the client, e.g. by a proxy in front of the application, is
+// kept as long as it is made of at most 128 printable ASCII characters,
+// otherwise a new one is generated. The ID is sent back in the response
+// header and can be logged using the `${id}` tag of the Logger middleware.
+func RequestIDWithConfig(config RequestIDConfig) routing.MiddlewareFunc {
+	// Defaults
+	if config.Skipper == nil {
+		config.Skipper = DefaultRequestIDConfig.Skipper
+	}
+	if config.Generator == nil {
+		config.Generator = DefaultRequestIDConfig.Generator
+	}
+
+	return func(next routing.HandlerFunc) routing.HandlerFunc {
+		return func(c routing.Context) error {
+			if config.Skipper(c) {
+				return next(c)
+			}
+
+			req := c.Request()
+			res := c.Response()
+			rid := req.Header.Get(routing.HeaderXRequestID)
+			if !validRequestID(rid) {
+				rid = config.Generator()
+				req.Header.Set(routing.HeaderXRequestID, rid)
+			}
+			res.Header().Set(routing.HeaderXRequestID, rid)
+			if config.RequestIDHandler != nil {
+				config.RequestIDHandler(c, rid)
+			}
+
+			return next(c)
+		}
+	}
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"goplugins/core/routing"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestID(t *testing.T) {
+	m := routing.New()
+	handler := func(c routing.Context) error {
+		return c.String(http.StatusOK, "test")
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rec := httptest.NewRecorder()
+	c := m.NewContext(req, rec)
+	rid := RequestIDWithConfig(RequestIDConfig{})
+	h := rid(handler)
+	h(c)
+	assert.Len(t, rec.Header().Get(routing.HeaderXRequestID), 22)
+	assert.Equal(t, rec.Header().Get(routing.HeaderXRequestID), req.Header.Get(routing.HeaderXRequestID))
+
+	// Custom generator and handler
+	var handled string
+	rid = RequestIDWithConfig(RequestIDConfig{
+		Generator:        func() string { return "customGenerator" },
+		RequestIDHandler: func(c routing.Context, id string) { handled = id },
+	})
+	rec = httptest.NewRecorder()
+	h = rid(handler)
+	h(m.NewContext(httptest.NewRequest(http.MethodGet, "/", nil), rec))
+	assert.Equal(t, "customGenerator", rec.Header().Get(routing.HeaderXRequestID))
+	assert.Equal(t, "customGenerator", handled)
+}
+
+func TestRequestIDFromRequest(t *testing.T) {
+	m := routing.New()
+	handler := func(c routing.Context) error {
+		return c.String(http.StatusOK, "test")
+	}
+	gen := RequestIDWithConfig(RequestIDConfig{Generator: func() string { return "generated" }})
+
+	for id, want := range map[string]string{
+		"upstream-1":             "upstream-1",
+		"bad id":                 "generated",
+		"bad\nid":                "generated",
+		strings.Repeat("x", 129): "generated",
+		strings.Repeat("x", 128): strings.Repeat("x", 128),
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(routing.HeaderXRequestID, id)
+		rec := httptest.NewRecorder()
+		gen(handler)(m.NewContext(req, rec))
+		assert.Equal(t, want, rec.Header().Get(routing.HeaderXRequestID))
+	}
+}
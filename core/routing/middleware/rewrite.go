@@ -0,0 +1,141 @@
+package middleware
+
+import (
+	"goplugins/core/routing"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// RewriteConfig defines the config for Rewrite middleware.
+type RewriteConfig struct {
+	// Skipper defines a function to skip middleware.
+	Skipper Skipper
+
+	// Rules defines the URL path rewrite rules. The values captured in
+	// asterisks can be retrieved by index e.g. $1, $2 and so on.
+	// Example:
+	// "/old":              "/new",
+	// "/api/*":            "/$1",
+	// "/js/*":             "/public/javascripts/$1",
+	// "/users/*/orders/*": "/user/$1/order/$2",
+	Rules map[string]string
+
+	// RegexRules defines the URL path rewrite rules using regexp.Regexp with
+	// captures. Every capture group in the values can be retrieved by index
+	// e.g. $1, $2 and so on.
+	// Example:
+	// "^/old/[0-9]+/":     "/new",
+	// "^/api/.+?/(.*)":    "/v2/$1",
+	RegexRules map[*regexp.Regexp]string
+}
+
+type rewriteRule struct {
+	pattern     *regexp.Regexp
+	replacement string
+}
+
+// DefaultRewriteConfig is the default Rewrite middleware config.
+var DefaultRewriteConfig = RewriteConfig{
+	Skipper: DefaultSkipper,
+}
+
+// Rewrite returns a root level (before router) middleware which rewrites the
+// request path according to the provided rules.
+//
+// Usage `Mux#Pre(Rewrite(rules))`
+func Rewrite(rules map[string]string) routing.MiddlewareFunc {
+	c := DefaultRewriteConfig
+	c.Rules = rules
+	return RewriteWithConfig(c)
+}
+
+// RewriteWithConfig returns a Rewrite middleware with config.
+// See: `Rewrite()`.
+//
+// The first matching rule is applied, the rules with the longest patterns
+// are tried first. A replacement containing "?" replaces the query as well.
+func RewriteWithConfig(config RewriteConfig) routing.MiddlewareFunc {
+	// Defaults
+	if config.Skipper == nil {
+		config.Skipper = DefaultRewriteConfig.Skipper
+	}
+	if config.Rules == nil && config.RegexRules == nil {
+		panic("routing: rewrite middleware requires url path rewrite rules or regex rules")
+	}
+
+	rules := compileRewriteRules(config.Rules, config.RegexRules)
+
+	return func(next routing.HandlerFunc) routing.HandlerFunc {
+		return func(c routing.Context) error {
+			if config.Skipper(c) {
+				return next(c)
+			}
+
+			req := c.Request()
+			if rewriteURL(rules, req.URL) {
+				req.RequestURI = req.URL.RequestURI()
+			}
+			return next(c)
+		}
+	}
+}
+
+// compileRewriteRules compiles rules and regexRules into the order they are
+// tried in, longest patterns first.
+func compileRewriteRules(rules map[string]string, regexRules map[*regexp.Regexp]string) []rewriteRule {
+	compiled := make([]rewriteRule, 0, len(rules)+len(regexRules))
+	for k, v := range rules {
+		k = regexp.QuoteMeta(k)
+		k = strings.Replace(k, `\*`, "(.*?)", -1)
+		if strings.HasPrefix(k, `\^`) {
+			k = strings.Replace(k, `\^`, "^", 1)
+		}
+		compiled = append(compiled, rewriteRule{regexp.MustCompile("^" + strings.TrimPrefix(k, "^") + "$"), v})
+	}
+	for k, v := range regexRules {
+		compiled = append(compiled, rewriteRule{k, v})
+	}
+	sort.SliceStable(compiled, func(i, j int) bool {
+		a, b := compiled[i].pattern.String(), compiled[j].pattern.String()
+		if len(a) != len(b) {
+			return len(a) > len(b)
+		}
+		return a < b
+	})
+	return compiled
+}
+
+// rewriteURL applies the first matching rule to u and reports whether one
+// matched. The escaped path is rewritten, so escaped slashes stay intact.
+func rewriteURL(rules []rewriteRule, u *url.URL) bool {
+	path := u.EscapedPath()
+	for _, rule := range rules {
+		match := rule.pattern.FindStringSubmatchIndex(path)
+		if match == nil {
+			continue
+		}
+		setURL(u, string(rule.pattern.ExpandString(nil, rule.replacement, path, match)))
+		return true
+	}
+	return false
+}
+
+// setURL sets the escaped path and, if given, the query of u.
+func setURL(u *url.URL, rewritten string) {
+	query := ""
+	if i := strings.IndexByte(rewritten, '?'); i >= 0 {
+		rewritten, query = rewritten[:i], rewritten[i+1:]
+		u.RawQuery = query
+	}
+	path, err := url.PathUnescape(rewritten)
+	if err != nil {
+		path = rewritten
+	}
+	u.Path = path
+	u.RawPath = ""
+	if u.EscapedPath() != rewritten {
+		u.RawPath = rewritten
+	}
+}
@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"goplugins/core/routing"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRewrite(t *testing.T) {
+	m := routing.New()
+	m.Pre(Rewrite(map[string]string{
+		"/old":              "/new",
+		"/api/*":            "/$1",
+		"/js/*":             "/public/javascripts/$1",
+		"/users/*/orders/*": "/user/$1/order/$2",
+		"/search/*":         "/find?q=$1",
+	}))
+	m.GET("/*", func(c routing.Context) error {
+		return c.String(http.StatusOK, c.Request().URL.RequestURI())
+	})
+
+	for target, want := range map[string]string{
+		"/old":                    "/new",
+		"/old/":                   "/old/",
+		"/api/users":              "/users",
+		"/js/main.js":             "/public/javascripts/main.js",
+		"/users/jack/orders/1":    "/user/jack/order/1",
+		"/search/go":              "/find?q=go",
+		"/api/new%2Fusers":        "/new%2Fusers",
+		"/unknown/api/users?page": "/unknown/api/users?page",
+	} {
+		_, body := requestCode(m, http.MethodGet, target)
+		assert.Equal(t, want, body, target)
+	}
+}
+
+func TestRewriteRegex(t *testing.T) {
+	m := routing.New()
+	m.Pre(RewriteWithConfig(RewriteConfig{
+		RegexRules: map[*regexp.Regexp]string{
+			regexp.MustCompile(`^/a/(\d+)/b/?$`): "/b/$1",
+			regexp.MustCompile(`^/a/(\d+)/.*$`):  "/c/$1",
+			regexp.MustCompile(`^/v1/(.*)$`):     "/v2/${1}",
+		},
+	}))
+	var path string
+	m.GET("/*", func(c routing.Context) error {
+		path = c.Request().URL.Path
+		return nil
+	})
+
+	for target, want := range map[string]string{
+		"/a/1/b":      "/b/1",
+		"/a/1/c":      "/c/1",
+		"/a/x/b":      "/a/x/b",
+		"/v1/users/1": "/v2/users/1",
+	} {
+		rec := httptest.NewRecorder()
+		m.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		assert.Equal(t, want, path, target)
+	}
+
+	assert.Panics(t, func() {
+		RewriteWithConfig(RewriteConfig{})
+	})
+}
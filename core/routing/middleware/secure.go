@@ -0,0 +1,332 @@
+package middleware
+
+import (
+	"encoding/json"
+	"fmt"
+	"goplugins/core/framework/crypto"
+	"goplugins/core/routing"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+type (
+	// SecureConfig defines the config for Secure middleware.
+	SecureConfig struct {
+		// Skipper defines a function to skip middleware.
+		Skipper Skipper
+
+		// XSSProtection provides protection against cross-site scripting attack (XSS)
+		// by setting the `X-XSS-Protection` header.
+		// Optional. Default value "0", which disables the filter of legacy
+		// browsers; it is a source of vulnerabilities itself. Rely on
+		// ContentSecurityPolicy instead.
+		XSSProtection string
+
+		// ContentTypeNosniff provides protection against overriding Content-Type
+		// header by setting the `X-Content-Type-Options` header.
+		// Optional. Default value "nosniff".
+		ContentTypeNosniff string
+
+		// XFrameOptions can be used to indicate whether or not a browser should
+		// be allowed to render a page in a <frame>, <iframe> or <object> .
+		// Sites can use this to avoid clickjacking attacks, by ensuring that their
+		// content is not embedded into other sites.
+		// Optional. Default value "SAMEORIGIN".
+		// Possible values:
+		// - "SAMEORIGIN" - The page can only be displayed in a frame on the same origin as the page itself.
+		// - "DENY" - The page cannot be displayed in a frame, regardless of the site attempting to do so.
+		XFrameOptions string
+
+		// HSTSMaxAge sets the `Strict-Transport-Security` header to indicate how
+		// long (in seconds) browsers should remember that this site is only to
+		// be accessed using HTTPS. The header is only sent on HTTPS requests.
+		// Optional. Default value 31536000 (one year), 0 disables the header.
+		HSTSMaxAge int
+
+		// HSTSExcludeSubdomains won't include subdomains tag in the `Strict Transport Security`
+		// header, excluding all subdomains from security policy. It has no effect
+		// unless HSTSMaxAge is set to a non-zero value.
+		// Optional. Default value false.
+		HSTSExcludeSubdomains bool
+
+		// HSTSPreloadEnabled will add the preload tag in the `Strict Transport Security`
+		// header, which enables the domain to be included in the HSTS preload list
+		// maintained by Chrome (and used by Firefox and Safari): https://hstspreload.org/
+		// Optional. Default value false.
+		HSTSPreloadEnabled bool
+
+		// ContentSecurityPolicy sets the `Content-Security-Policy` header providing
+		// security against cross-site scripting (XSS), clickjacking and other code
+		// injection attacks resulting from execution of malicious content in the
+		// trusted web page context. Every "${nonce}" is replaced by the nonce of
+		// the request, see CSPNonce.
+		// Optional. Default value "".
+		ContentSecurityPolicy string
+
+		// CSPReportOnly sends the policy using the `Content-Security-Policy-Report-Only`
+		// header, so violations are reported but not enforced. Use it to try out
+		// a policy before enforcing it.
+		// Optional. Default value false.
+		CSPReportOnly bool
+
+		// CSPReportURI is appended to the policy as `report-uri` directive.
+		// Browsers post violations to it, see CSPReportHandler.
+		// Optional. Default value "".
+		CSPReportURI string
+
+		// ReferrerPolicy sets the `Referrer-Policy` header providing security against
+		// leaking potentially sensitive request paths to third parties.
+		// Optional. Default value "strict-origin-when-cross-origin".
+		ReferrerPolicy string
+	}
+
+	// CSPReport is a violation of the Content-Security-Policy reported by a
+	// browser.
+	CSPReport struct {
+		DocumentURI        string `json:"document-uri"`
+		Referrer           string `json:"referrer"`
+		BlockedURI         string `json:"blocked-uri"`
+		ViolatedDirective  string `json:"violated-directive"`
+		EffectiveDirective string `json:"effective-directive"`
+		OriginalPolicy     string `json:"original-policy"`
+		Disposition        string `json:"disposition"`
+		SourceFile         string `json:"source-file"`
+		LineNumber         int    `json:"line-number"`
+		ColumnNumber       int    `json:"column-number"`
+		StatusCode         int    `json:"status-code"`
+		ScriptSample       string `json:"script-sample"`
+	}
+
+	// reportingAPIReport is a report sent using the Reporting API, which
+	// replaces the report-uri directive in newer browsers.
+	reportingAPIReport struct {
+		Type string `json:"type"`
+		Body struct {
+			DocumentURL        string `json:"documentURL"`
+			Referrer           string `json:"referrer"`
+			BlockedURL         string `json:"blockedURL"`
+			EffectiveDirective string `json:"effectiveDirective"`
+			OriginalPolicy     string `json:"originalPolicy"`
+			Disposition        string `json:"disposition"`
+			SourceFile         string `json:"sourceFile"`
+			LineNumber         int    `json:"lineNumber"`
+			ColumnNumber       int    `json:"columnNumber"`
+			StatusCode         int    `json:"statusCode"`
+			Sample             string `json:"sample"`
+		} `json:"body"`
+	}
+)
+
+const (
+	// CSPNonceKey is the key the CSP nonce of the request is stored under in
+	// the context.
+	CSPNonceKey = "csp_nonce"
+
+	secureConfigKey  = "secure_config"
+	cspNonceTag      = "${nonce}"
+	maxCSPReportSize = 64 << 10 // 64 KB
+)
+
+var (
+	// DefaultSecureConfig is the default Secure middleware config.
+	DefaultSecureConfig = SecureConfig{
+		Skipper:            DefaultSkipper,
+		XSSProtection:      "0",
+		ContentTypeNosniff: "nosniff",
+		XFrameOptions:      "SAMEORIGIN",
+		HSTSMaxAge:         31536000,
+		ReferrerPolicy:     "strict-origin-when-cross-origin",
+	}
+)
+
+// Secure returns a Secure middleware.
+// Secure middleware provides protection against cross-site scripting (XSS) attack,
+// content type sniffing, clickjacking, insecure connection and other code injection
+// attacks.
+func Secure() routing.MiddlewareFunc {
+	return SecureWithConfig(DefaultSecureConfig)
+}
+
+// SecureWithConfig returns a Secure middleware with config.
+// See: `Secure()`.
+//
+// The headers are written just before the response, so they can be changed
+// for single routes or groups using SecureOverride.
+func SecureWithConfig(config SecureConfig) routing.MiddlewareFunc {
+	// Defaults
+	if config.Skipper == nil {
+		config.Skipper = DefaultSecureConfig.Skipper
+	}
+
+	return func(next routing.HandlerFunc) routing.HandlerFunc {
+		return func(c routing.Context) error {
+			if config.Skipper(c) {
+				return next(c)
+			}
+
+			cfg := config
+			c.Set(secureConfigKey, &cfg)
+			if strings.Contains(cfg.ContentSecurityPolicy, cspNonceTag) {
+				CSPNonce(c)
+			}
+			c.Response().Before(func() {
+				writeSecureHeaders(c, &cfg)
+			})
+			return next(c)
+		}
+	}
+}
+
+// SecureOverride returns a middleware changing the config of the Secure
+// middleware for the routes it is added to, e.g.
+//
+//	g.GET("/embed", h, middleware.SecureOverride(func(cfg *middleware.SecureConfig) {
+//		cfg.XFrameOptions = ""
+//	}))
+//
+// It has no effect unless the Secure middleware runs before it.
+func SecureOverride(fn func(*SecureConfig)) routing.MiddlewareFunc {
+	return func(next routing.HandlerFunc) routing.HandlerFunc {
+		return func(c routing.Context) error {
+			if cfg, ok := c.Get(secureConfigKey).(*SecureConfig); ok {
+				fn(cfg)
+			}
+			return next(c)
+		}
+	}
+}
+
+// CSPNonce returns the nonce of the request to be used in the nonce attribute
+// of inline scripts and styles. It is generated on first use and also
+// available as `c.Get(middleware.CSPNonceKey)`, e.g. to templates.
+func CSPNonce(c routing.Context) string {
+	if nonce, ok := c.Get(CSPNonceKey).(string); ok {
+		return nonce
+	}
+	nonce := crypto.SecureToken()
+	c.Set(CSPNonceKey, nonce)
+	return nonce
+}
+
+// CSPReportHandler returns a handler collecting the violations of the
+// Content-Security-Policy reported by browsers, in the `report-uri` format as
+// well as the Reporting API format. Every report is passed to fn; if fn is
+// nil reports are logged as warnings.
+//
+//	m.POST("/csp-report", middleware.CSPReportHandler(nil))
+func CSPReportHandler(fn func(routing.Context, CSPReport) error) routing.HandlerFunc {
+	if fn == nil {
+		fn = logCSPReport
+	}
+	return func(c routing.Context) error {
+		body, err := ioutil.ReadAll(io.LimitReader(c.Request().Body, maxCSPReportSize+1))
+		if err != nil {
+			return err
+		}
+		if len(body) > maxCSPReportSize {
+			return routing.ErrStatusRequestEntityTooLarge
+		}
+
+		reports, err := parseCSPReports(body)
+		if err != nil {
+			return routing.NewHTTPError(http.StatusBadRequest, "invalid CSP report").SetInternal(err)
+		}
+		for _, report := range reports {
+			if err := fn(c, report); err != nil {
+				return err
+			}
+		}
+		return c.NoContent(http.StatusNoContent)
+	}
+}
+
+func parseCSPReports(body []byte) ([]CSPReport, error) {
+	trimmed := strings.TrimSpace(string(body))
+	if strings.HasPrefix(trimmed, "[") {
+		var list []reportingAPIReport
+		if err := json.Unmarshal(body, &list); err != nil {
+			return nil, err
+		}
+		reports := make([]CSPReport, 0, len(list))
+		for _, r := range list {
+			if r.Type != "csp-violation" {
+				continue
+			}
+			reports = append(reports, CSPReport{
+				DocumentURI:        r.Body.DocumentURL,
+				Referrer:           r.Body.Referrer,
+				BlockedURI:         r.Body.BlockedURL,
+				ViolatedDirective:  r.Body.EffectiveDirective,
+				EffectiveDirective: r.Body.EffectiveDirective,
+				OriginalPolicy:     r.Body.OriginalPolicy,
+				Disposition:        r.Body.Disposition,
+				SourceFile:         r.Body.SourceFile,
+				LineNumber:         r.Body.LineNumber,
+				ColumnNumber:       r.Body.ColumnNumber,
+				StatusCode:         r.Body.StatusCode,
+				ScriptSample:       r.Body.Sample,
+			})
+		}
+		return reports, nil
+	}
+
+	var wrapper struct {
+		Report *CSPReport `json:"csp-report"`
+	}
+	if err := json.Unmarshal(body, &wrapper); err != nil {
+		return nil, err
+	}
+	if wrapper.Report == nil {
+		return nil, fmt.Errorf("missing csp-report")
+	}
+	return []CSPReport{*wrapper.Report}, nil
+}
+
+func logCSPReport(c routing.Context, r CSPReport) error {
+	c.Logger().Warnf("CSP violation: %s blocked %q on %s", r.EffectiveDirective, r.BlockedURI, r.DocumentURI)
+	return nil
+}
+
+func writeSecureHeaders(c routing.Context, config *SecureConfig) {
+	req := c.Request()
+	res := c.Response()
+
+	if config.XSSProtection != "" {
+		res.Header().Set(routing.HeaderXXSSProtection, config.XSSProtection)
+	}
+	if config.ContentTypeNosniff != "" {
+		res.Header().Set(routing.HeaderXContentTypeOptions, config.ContentTypeNosniff)
+	}
+	if config.XFrameOptions != "" {
+		res.Header().Set(routing.HeaderXFrameOptions, config.XFrameOptions)
+	}
+	if (c.IsTLS() || (req.Header.Get(routing.HeaderXForwardedProto) == "https")) && config.HSTSMaxAge != 0 {
+		subdomains := ""
+		if !config.HSTSExcludeSubdomains {
+			subdomains = "; includeSubdomains"
+		}
+		if config.HSTSPreloadEnabled {
+			subdomains = fmt.Sprintf("%s; preload", subdomains)
+		}
+		res.Header().Set(routing.HeaderStrictTransportSecurity, fmt.Sprintf("max-age=%d%s", config.HSTSMaxAge, subdomains))
+	}
+	if config.ContentSecurityPolicy != "" {
+		policy := config.ContentSecurityPolicy
+		if strings.Contains(policy, cspNonceTag) {
+			policy = strings.Replace(policy, cspNonceTag, CSPNonce(c), -1)
+		}
+		if config.CSPReportURI != "" {
+			policy = fmt.Sprintf("%s; report-uri %s", strings.TrimRight(policy, "; "), config.CSPReportURI)
+		}
+		if config.CSPReportOnly {
+			res.Header().Set(routing.HeaderContentSecurityPolicyReportOnly, policy)
+		} else {
+			res.Header().Set(routing.HeaderContentSecurityPolicy, policy)
+		}
+	}
+	if config.ReferrerPolicy != "" {
+		res.Header().Set(routing.HeaderReferrerPolicy, config.ReferrerPolicy)
+	}
+}
@@ -0,0 +1,148 @@
+package middleware
+
+import (
+	"bytes"
+	"goplugins/core/routing"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSecure(t *testing.T) {
+	m := routing.New()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rec := httptest.NewRecorder()
+	c := m.NewContext(req, rec)
+	h := func(c routing.Context) error {
+		return c.String(http.StatusOK, "test")
+	}
+
+	// Default
+	Secure()(h)(c)
+	assert.Equal(t, "0", rec.Header().Get(routing.HeaderXXSSProtection))
+	assert.Equal(t, "nosniff", rec.Header().Get(routing.HeaderXContentTypeOptions))
+	assert.Equal(t, "SAMEORIGIN", rec.Header().Get(routing.HeaderXFrameOptions))
+	assert.Equal(t, "strict-origin-when-cross-origin", rec.Header().Get(routing.HeaderReferrerPolicy))
+	assert.Equal(t, "", rec.Header().Get(routing.HeaderStrictTransportSecurity))
+	assert.Equal(t, "", rec.Header().Get(routing.HeaderContentSecurityPolicy))
+
+	// Custom
+	req.Header.Set(routing.HeaderXForwardedProto, "https")
+	rec = httptest.NewRecorder()
+	c = m.NewContext(req, rec)
+	SecureWithConfig(SecureConfig{
+		XSSProtection:         "",
+		ContentTypeNosniff:    "",
+		XFrameOptions:         "",
+		HSTSMaxAge:            3600,
+		HSTSPreloadEnabled:    true,
+		ContentSecurityPolicy: "default-src 'self'",
+	})(h)(c)
+	assert.Equal(t, "", rec.Header().Get(routing.HeaderXXSSProtection))
+	assert.Equal(t, "", rec.Header().Get(routing.HeaderXFrameOptions))
+	assert.Equal(t, "max-age=3600; includeSubdomains; preload", rec.Header().Get(routing.HeaderStrictTransportSecurity))
+	assert.Equal(t, "default-src 'self'", rec.Header().Get(routing.HeaderContentSecurityPolicy))
+	assert.Equal(t, "", rec.Header().Get(routing.HeaderContentSecurityPolicyReportOnly))
+
+	// Report only
+	rec = httptest.NewRecorder()
+	c = m.NewContext(req, rec)
+	SecureWithConfig(SecureConfig{
+		ContentSecurityPolicy: "default-src 'self';",
+		CSPReportOnly:         true,
+		CSPReportURI:          "/csp-report",
+		HSTSExcludeSubdomains: true,
+		HSTSMaxAge:            3600,
+	})(h)(c)
+	assert.Equal(t, "max-age=3600", rec.Header().Get(routing.HeaderStrictTransportSecurity))
+	assert.Equal(t, "", rec.Header().Get(routing.HeaderContentSecurityPolicy))
+	assert.Equal(t, "default-src 'self'; report-uri /csp-report", rec.Header().Get(routing.HeaderContentSecurityPolicyReportOnly))
+}
+
+func TestSecureNonce(t *testing.T) {
+	m := routing.New()
+	cfg := DefaultSecureConfig
+	cfg.ContentSecurityPolicy = "script-src 'self' 'nonce-${nonce}'"
+	m.Use(SecureWithConfig(cfg))
+	var nonce string
+	m.GET("/", func(c routing.Context) error {
+		nonce = c.Get(CSPNonceKey).(string)
+		assert.Equal(t, nonce, CSPNonce(c))
+		return c.HTML(http.StatusOK, `<script nonce="`+nonce+`"></script>`)
+	})
+
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.NotEmpty(t, nonce)
+	assert.Equal(t, "script-src 'self' 'nonce-"+nonce+"'", rec.Header().Get(routing.HeaderContentSecurityPolicy))
+
+	first := nonce
+	m.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.NotEqual(t, first, nonce)
+}
+
+func TestSecureOverride(t *testing.T) {
+	m := routing.New()
+	m.Use(Secure())
+	h := func(c routing.Context) error {
+		return c.String(http.StatusOK, "test")
+	}
+	m.GET("/", h)
+	m.GET("/embed", h, SecureOverride(func(cfg *SecureConfig) {
+		cfg.XFrameOptions = ""
+		cfg.ContentSecurityPolicy = "frame-ancestors https://partner.example.com"
+	}))
+
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/embed", nil))
+	assert.Equal(t, "", rec.Header().Get(routing.HeaderXFrameOptions))
+	assert.Equal(t, "frame-ancestors https://partner.example.com", rec.Header().Get(routing.HeaderContentSecurityPolicy))
+	assert.Equal(t, "nosniff", rec.Header().Get(routing.HeaderXContentTypeOptions))
+
+	// other routes are not affected
+	rec = httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, "SAMEORIGIN", rec.Header().Get(routing.HeaderXFrameOptions))
+}
+
+func TestCSPReportHandler(t *testing.T) {
+	m := routing.New()
+	var reports []CSPReport
+	m.POST("/csp-report", CSPReportHandler(func(c routing.Context, r CSPReport) error {
+		reports = append(reports, r)
+		return nil
+	}))
+
+	post := func(contentType, body string) int {
+		req := httptest.NewRequest(http.MethodPost, "/csp-report", strings.NewReader(body))
+		req.Header.Set(routing.HeaderContentType, contentType)
+		rec := httptest.NewRecorder()
+		m.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	assert.Equal(t, http.StatusNoContent, post("application/csp-report",
+		`{"csp-report":{"document-uri":"https://example.com/","blocked-uri":"https://evil.com/x.js","effective-directive":"script-src"}}`))
+	assert.Equal(t, http.StatusNoContent, post("application/reports+json",
+		`[{"type":"csp-violation","body":{"documentURL":"https://example.com/a","blockedURL":"inline","effectiveDirective":"style-src"}},{"type":"deprecation","body":{}}]`))
+	assert.Equal(t, http.StatusBadRequest, post("application/csp-report", `{}`))
+	assert.Equal(t, http.StatusBadRequest, post("application/csp-report", `nope`))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, post("application/csp-report", strings.Repeat(" ", maxCSPReportSize+1)))
+
+	if assert.Len(t, reports, 2) {
+		assert.Equal(t, "https://evil.com/x.js", reports[0].BlockedURI)
+		assert.Equal(t, "style-src", reports[1].EffectiveDirective)
+		assert.Equal(t, "https://example.com/a", reports[1].DocumentURI)
+	}
+
+	// reports are logged by default
+	buf := new(bytes.Buffer)
+	m.Logger.SetOutput(buf)
+	m.POST("/log", CSPReportHandler(nil))
+	req := httptest.NewRequest(http.MethodPost, "/log", strings.NewReader(`{"csp-report":{"blocked-uri":"https://evil.com/x.js"}}`))
+	m.ServeHTTP(httptest.NewRecorder(), req)
+	assert.Contains(t, buf.String(), "evil.com")
+}
@@ -0,0 +1,114 @@
+package middleware
+
+import (
+	"goplugins/core/routing"
+	"net/url"
+	"strings"
+)
+
+// TrailingSlashConfig defines the config for TrailingSlash middleware.
+type TrailingSlashConfig struct {
+	// Skipper defines a function to skip middleware.
+	Skipper Skipper
+
+	// RedirectCode is the status code used when redirecting the request.
+	// Optional, but when provided the request is redirected using this code
+	// instead of being rewritten in place.
+	RedirectCode int
+}
+
+// DefaultTrailingSlashConfig is the default TrailingSlash middleware config.
+var DefaultTrailingSlashConfig = TrailingSlashConfig{
+	Skipper: DefaultSkipper,
+}
+
+// AddTrailingSlash returns a root level (before router) middleware which adds a
+// trailing slash to the request `URL#Path`.
+//
+// Usage `Mux#Pre(AddTrailingSlash())`
+func AddTrailingSlash() routing.MiddlewareFunc {
+	return AddTrailingSlashWithConfig(DefaultTrailingSlashConfig)
+}
+
+// AddTrailingSlashWithConfig returns an AddTrailingSlash middleware with config.
+// See `AddTrailingSlash()`.
+func AddTrailingSlashWithConfig(config TrailingSlashConfig) routing.MiddlewareFunc {
+	return trailingSlash(config, func(path string) string {
+		if strings.HasSuffix(path, "/") {
+			return path
+		}
+		return path + "/"
+	})
+}
+
+// RemoveTrailingSlash returns a root level (before router) middleware which removes
+// a trailing slash from the request URI.
+//
+// Usage `Mux#Pre(RemoveTrailingSlash())`
+func RemoveTrailingSlash() routing.MiddlewareFunc {
+	return RemoveTrailingSlashWithConfig(DefaultTrailingSlashConfig)
+}
+
+// RemoveTrailingSlashWithConfig returns a RemoveTrailingSlash middleware with config.
+// See `RemoveTrailingSlash()`.
+func RemoveTrailingSlashWithConfig(config TrailingSlashConfig) routing.MiddlewareFunc {
+	return trailingSlash(config, func(path string) string {
+		if len(path) > 1 && strings.HasSuffix(path, "/") {
+			return strings.TrimRight(path, "/")
+		}
+		return path
+	})
+}
+
+func trailingSlash(config TrailingSlashConfig, fix func(string) string) routing.MiddlewareFunc {
+	// Defaults
+	if config.Skipper == nil {
+		config.Skipper = DefaultTrailingSlashConfig.Skipper
+	}
+
+	return func(next routing.HandlerFunc) routing.HandlerFunc {
+		return func(c routing.Context) error {
+			if config.Skipper(c) {
+				return next(c)
+			}
+
+			req := c.Request()
+			u := req.URL
+			path := u.Path
+			if u.RawPath != "" {
+				path = u.RawPath
+			}
+			fixed := fix(path)
+			if fixed == path {
+				return next(c)
+			}
+			if fixed == "" {
+				fixed = "/"
+			}
+
+			if config.RedirectCode != 0 {
+				// Escape the path and collapse leading slashes, browsers
+				// follow "//example.com" and "/\example.com" to another host
+				// and drop tabs and line breaks.
+				location := fixed
+				if u.RawPath == "" {
+					location = (&url.URL{Path: fixed}).EscapedPath()
+				}
+				location = "/" + strings.TrimLeft(location, "/\\")
+				if u.RawQuery != "" {
+					location += "?" + u.RawQuery
+				}
+				return c.Redirect(config.RedirectCode, location)
+			}
+
+			if u.RawPath != "" {
+				u.RawPath = fixed
+				u.Path = fix(u.Path)
+			} else {
+				u.Path = fixed
+			}
+			req.RequestURI = u.RequestURI()
+			return next(c)
+		}
+	}
+}
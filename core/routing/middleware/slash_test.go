@@ -0,0 +1,94 @@
+package middleware
+
+import (
+	"goplugins/core/routing"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddTrailingSlash(t *testing.T) {
+	m := routing.New()
+	h := func(c routing.Context) error { return nil }
+
+	req := httptest.NewRequest(http.MethodGet, "/add-slash", nil)
+	c := m.NewContext(req, httptest.NewRecorder())
+	AddTrailingSlash()(h)(c)
+	assert.Equal(t, "/add-slash/", req.URL.Path)
+	assert.Equal(t, "/add-slash/", req.RequestURI)
+
+	req = httptest.NewRequest(http.MethodGet, "/add-slash?key=value", nil)
+	c = m.NewContext(req, httptest.NewRecorder())
+	AddTrailingSlash()(h)(c)
+	assert.Equal(t, "/add-slash/?key=value", req.RequestURI)
+
+	// With redirect
+	req = httptest.NewRequest(http.MethodGet, "/add-slash?key=value", nil)
+	rec := httptest.NewRecorder()
+	c = m.NewContext(req, rec)
+	AddTrailingSlashWithConfig(TrailingSlashConfig{RedirectCode: http.StatusMovedPermanently})(h)(c)
+	assert.Equal(t, http.StatusMovedPermanently, rec.Code)
+	assert.Equal(t, "/add-slash/?key=value", rec.Header().Get(routing.HeaderLocation))
+
+	// No open redirect
+	req = httptest.NewRequest(http.MethodGet, "http://localhost//example.com", nil)
+	rec = httptest.NewRecorder()
+	c = m.NewContext(req, rec)
+	AddTrailingSlashWithConfig(TrailingSlashConfig{RedirectCode: http.StatusMovedPermanently})(h)(c)
+	assert.Equal(t, "/example.com/", rec.Header().Get(routing.HeaderLocation))
+}
+
+func TestRemoveTrailingSlash(t *testing.T) {
+	m := routing.New()
+	h := func(c routing.Context) error { return nil }
+
+	req := httptest.NewRequest(http.MethodGet, "/remove-slash/", nil)
+	c := m.NewContext(req, httptest.NewRecorder())
+	RemoveTrailingSlash()(h)(c)
+	assert.Equal(t, "/remove-slash", req.URL.Path)
+	assert.Equal(t, "/remove-slash", req.RequestURI)
+
+	// Root is left alone
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	c = m.NewContext(req, httptest.NewRecorder())
+	RemoveTrailingSlash()(h)(c)
+	assert.Equal(t, "/", req.URL.Path)
+
+	// With redirect
+	req = httptest.NewRequest(http.MethodGet, "/remove-slash/?key=value", nil)
+	rec := httptest.NewRecorder()
+	c = m.NewContext(req, rec)
+	RemoveTrailingSlashWithConfig(TrailingSlashConfig{RedirectCode: http.StatusMovedPermanently})(h)(c)
+	assert.Equal(t, http.StatusMovedPermanently, rec.Code)
+	assert.Equal(t, "/remove-slash?key=value", rec.Header().Get(routing.HeaderLocation))
+
+	// No open redirect
+	for target, location := range map[string]string{
+		"http://localhost//example.com/":  "/example.com",
+		"/%5Cexample.com/":                "/%5Cexample.com",
+		"/%5C%5Cexample.com/":             "/%5C%5Cexample.com",
+		"/%2F%5Cexample.com/":             "/%2F%5Cexample.com",
+		"/%09/example.com/":               "/%09/example.com",
+		"http://localhost/\\example.com/": "/example.com",
+	} {
+		req = httptest.NewRequest(http.MethodGet, target, nil)
+		rec = httptest.NewRecorder()
+		c = m.NewContext(req, rec)
+		RemoveTrailingSlashWithConfig(TrailingSlashConfig{RedirectCode: http.StatusMovedPermanently})(h)(c)
+		assert.Equal(t, location, rec.Header().Get(routing.HeaderLocation), target)
+	}
+}
+
+func TestTrailingSlashRouting(t *testing.T) {
+	m := routing.New()
+	m.Pre(RemoveTrailingSlash())
+	m.GET("/users", func(c routing.Context) error {
+		return c.String(http.StatusOK, "users")
+	})
+
+	code, body := requestCode(m, http.MethodGet, "/users/")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "users", body)
+}
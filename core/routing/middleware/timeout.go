@@ -0,0 +1,223 @@
+package middleware
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"goplugins/core/routing"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+type (
+	// TimeoutConfig defines the config for Timeout middleware.
+	TimeoutConfig struct {
+		// Skipper defines a function to skip middleware.
+		Skipper Skipper
+
+		// Timeout is the time the handler may take to complete the response.
+		// Required.
+		Timeout time.Duration
+
+		// StatusCode is sent when the handler overruns. Use
+		// http.StatusGatewayTimeout for handlers waiting on upstream services.
+		// Optional. Default value http.StatusServiceUnavailable.
+		StatusCode int
+
+		// ErrorMessage is sent when the handler overruns.
+		// Optional. Default value is the status text of StatusCode.
+		ErrorMessage string
+
+		// OnTimeoutRouteErrorHandler is called with the error returned by a
+		// handler which has overrun, once it completes. Its response is
+		// discarded at that point, so this is mostly useful for logging.
+		// Optional.
+		OnTimeoutRouteErrorHandler func(err error, c routing.Context)
+	}
+
+	// timeoutWriter buffers the response of the handler, which is only sent
+	// if the handler completes in time. Writes after the timeout fail with
+	// http.ErrHandlerTimeout.
+	timeoutWriter struct {
+		ctx         context.Context
+		mu          sync.Mutex
+		header      http.Header
+		buf         bytes.Buffer
+		code        int
+		wroteHeader bool
+		timedOut    bool
+	}
+)
+
+// DefaultTimeoutConfig is the default Timeout middleware config.
+var DefaultTimeoutConfig = TimeoutConfig{
+	Skipper:    DefaultSkipper,
+	StatusCode: http.StatusServiceUnavailable,
+}
+
+// Timeout returns a middleware which limits the time a handler may take.
+func Timeout(timeout time.Duration) routing.MiddlewareFunc {
+	c := DefaultTimeoutConfig
+	c.Timeout = timeout
+	return TimeoutWithConfig(c)
+}
+
+// TimeoutWithConfig returns a Timeout middleware with config.
+// See: `Timeout()`.
+//
+// The handler runs in its own goroutine on a copy of the context, whose
+// request context is canceled once the timeout expires. Its response is
+// buffered and only sent if the handler completes in time; otherwise an error
+// with StatusCode is returned and anything the handler writes later is
+// discarded. Handlers should stop working once `c.Request().Context()` is
+// done. Streaming responses and hijacking the connection are not supported.
+func TimeoutWithConfig(config TimeoutConfig) routing.MiddlewareFunc {
+	// Defaults
+	if config.Skipper == nil {
+		config.Skipper = DefaultTimeoutConfig.Skipper
+	}
+	if config.StatusCode == 0 {
+		config.StatusCode = DefaultTimeoutConfig.StatusCode
+	}
+	if config.Timeout <= 0 {
+		panic("routing: timeout middleware requires a timeout")
+	}
+
+	return func(next routing.HandlerFunc) routing.HandlerFunc {
+		return func(c routing.Context) error {
+			if config.Skipper(c) {
+				return next(c)
+			}
+
+			ctx, cancel := context.WithTimeout(c.Request().Context(), config.Timeout)
+			defer cancel()
+
+			res := c.Response()
+			tw := &timeoutWriter{ctx: ctx, header: res.Header().Clone()}
+			tc := c.Mux().CopyContext(c, tw)
+			tc.SetRequest(c.Request().WithContext(ctx))
+
+			done := make(chan error, 1)
+			panicked := make(chan interface{}, 1)
+			go func() {
+				defer func() {
+					if p := recover(); p != nil {
+						panicked <- p
+					}
+				}()
+				done <- next(tc)
+			}()
+
+			select {
+			case p := <-panicked:
+				panic(p)
+			case err := <-done:
+				tw.mu.Lock()
+				defer tw.mu.Unlock()
+				if tw.timedOut {
+					// Some of the response was written too late.
+					return timeoutError(config, ctx.Err())
+				}
+				if err != nil && !tw.wroteHeader {
+					if errors.Is(err, context.DeadlineExceeded) && ctx.Err() != nil {
+						return timeoutError(config, ctx.Err())
+					}
+					return err
+				}
+				tw.writeTo(res)
+				return err
+			case <-ctx.Done():
+				tw.mu.Lock()
+				tw.timedOut = true
+				tw.mu.Unlock()
+				if config.OnTimeoutRouteErrorHandler != nil {
+					go func() {
+						select {
+						case err := <-done:
+							if err != nil {
+								config.OnTimeoutRouteErrorHandler(err, tc)
+							}
+						case <-panicked:
+						}
+					}()
+				}
+				return timeoutError(config, ctx.Err())
+			}
+		}
+	}
+}
+
+func timeoutError(config TimeoutConfig, err error) *routing.HTTPError {
+	if config.ErrorMessage != "" {
+		return routing.NewHTTPError(config.StatusCode, config.ErrorMessage).SetInternal(err)
+	}
+	return routing.NewHTTPError(config.StatusCode).SetInternal(err)
+}
+
+func (tw *timeoutWriter) Header() http.Header {
+	return tw.header
+}
+
+func (tw *timeoutWriter) WriteHeader(code int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.expired() || tw.wroteHeader {
+		return
+	}
+	tw.wroteHeader = true
+	tw.code = code
+}
+
+func (tw *timeoutWriter) Write(b []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.expired() {
+		return 0, http.ErrHandlerTimeout
+	}
+	if !tw.wroteHeader {
+		tw.wroteHeader = true
+		tw.code = http.StatusOK
+	}
+	return tw.buf.Write(b)
+}
+
+// expired reports whether the response may no longer be written. The context
+// is checked as well, since the handler may notice that it is done before the
+// middleware does.
+func (tw *timeoutWriter) expired() bool {
+	if !tw.timedOut && tw.ctx.Err() != nil {
+		tw.timedOut = true
+	}
+	return tw.timedOut
+}
+
+// Flush does nothing, the response is sent once the handler completes.
+func (tw *timeoutWriter) Flush() {}
+
+func (tw *timeoutWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return nil, nil, errors.New("routing: hijacking is not supported within the timeout middleware")
+}
+
+// writeTo sends the buffered response using the original response, so its
+// before and after hooks run. Nothing is sent if the handler wrote nothing.
+func (tw *timeoutWriter) writeTo(res *routing.Response) {
+	header := res.Header()
+	for k := range header {
+		if _, ok := tw.header[k]; !ok {
+			delete(header, k)
+		}
+	}
+	for k, v := range tw.header {
+		header[k] = v
+	}
+	if !tw.wroteHeader {
+		return
+	}
+	res.WriteHeader(tw.code)
+	if tw.buf.Len() > 0 {
+		res.Write(tw.buf.Bytes())
+	}
+}
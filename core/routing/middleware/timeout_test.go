@@ -0,0 +1,117 @@
+package middleware
+
+import (
+	"errors"
+	"goplugins/core/routing"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimeout(t *testing.T) {
+	m := routing.New()
+	m.Use(Timeout(time.Second))
+	m.GET("/:id", func(c routing.Context) error {
+		c.Response().Header().Set("X-Test", "test")
+		return c.String(http.StatusCreated, c.Param("id")+c.Get("user").(string))
+	}, func(next routing.HandlerFunc) routing.HandlerFunc {
+		return func(c routing.Context) error {
+			c.Set("user", "jon")
+			return next(c)
+		}
+	})
+
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/1", nil))
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Equal(t, "test", rec.Header().Get("X-Test"))
+	assert.Equal(t, "1jon", rec.Body.String())
+}
+
+func TestTimeoutOverrun(t *testing.T) {
+	var wg sync.WaitGroup
+	wg.Add(1)
+	lateErr := make(chan error, 1)
+	m := routing.New()
+	m.Use(TimeoutWithConfig(TimeoutConfig{
+		Timeout:    20 * time.Millisecond,
+		StatusCode: http.StatusGatewayTimeout,
+		OnTimeoutRouteErrorHandler: func(err error, c routing.Context) {
+			lateErr <- err
+		},
+	}))
+	m.GET("/", func(c routing.Context) error {
+		defer wg.Done()
+		<-c.Request().Context().Done()
+		// Late writes are discarded.
+		c.Response().Header().Set("X-Late", "late")
+		_, err := c.Response().Write([]byte("late"))
+		assert.Equal(t, http.ErrHandlerTimeout, err)
+		return errors.New("late")
+	})
+
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	wg.Wait()
+	assert.Equal(t, http.StatusGatewayTimeout, rec.Code)
+	assert.Equal(t, "", rec.Header().Get("X-Late"))
+	assert.NotContains(t, rec.Body.String(), "late")
+	select {
+	case err := <-lateErr:
+		assert.EqualError(t, err, "late")
+	case <-time.After(time.Second):
+		t.Fatal("late error not reported")
+	}
+}
+
+func TestTimeoutDefaults(t *testing.T) {
+	m := routing.New()
+	m.Use(Timeout(20 * time.Millisecond))
+	m.GET("/", func(c routing.Context) error {
+		select {
+		case <-c.Request().Context().Done():
+			return c.Request().Context().Err()
+		case <-time.After(time.Second):
+			return c.String(http.StatusOK, "test")
+		}
+	})
+	m.GET("/error", func(c routing.Context) error {
+		c.Response().Header().Set("X-Test", "test")
+		return routing.ErrForbidden
+	})
+	m.GET("/empty", func(c routing.Context) error {
+		c.Response().Header().Set("X-Test", "test")
+		return nil
+	})
+
+	code, _ := requestCode(m, http.MethodGet, "/")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/error", nil))
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.Equal(t, "", rec.Header().Get("X-Test"))
+
+	rec = httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/empty", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "test", rec.Header().Get("X-Test"))
+
+	assert.Panics(t, func() {
+		TimeoutWithConfig(TimeoutConfig{})
+	})
+}
+
+func TestTimeoutPanic(t *testing.T) {
+	m := routing.New()
+	m.Use(Recover(), Timeout(time.Second))
+	m.GET("/", func(c routing.Context) error {
+		panic("test")
+	})
+	code, _ := requestCode(m, http.MethodGet, "/")
+	assert.Equal(t, http.StatusInternalServerError, code)
+}
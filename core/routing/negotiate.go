@@ -0,0 +1,202 @@
+package routing
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type (
+	// Encoder encodes response data in a media type, see
+	// `Mux#RegisterEncoder()`.
+	Encoder interface {
+		// CanEncode reports whether data can be encoded for the request,
+		// e.g. the template encoder needs a template name.
+		CanEncode(c Context, data interface{}) bool
+
+		// Encode writes data with status code and sets the Content-Type
+		// header.
+		Encode(c Context, code int, data interface{}) error
+	}
+
+	// EncoderFunc adapts a function to an Encoder encoding all data.
+	EncoderFunc func(c Context, code int, data interface{}) error
+
+	encoderEntry struct {
+		mediaType string
+		encoder   Encoder
+	}
+
+	// acceptRange is a media range of the Accept header.
+	acceptRange struct {
+		typ, subtype string
+		q            float64
+	}
+
+	textEncoder     struct{}
+	templateEncoder struct{}
+)
+
+// TemplateKey is the context and route meta key of the template name used by
+// the text/html encoder of `Context#Negotiate()`.
+const TemplateKey = "template"
+
+// CanEncode implements `Encoder#CanEncode()`.
+func (f EncoderFunc) CanEncode(Context, interface{}) bool {
+	return true
+}
+
+// Encode implements `Encoder#Encode()`.
+func (f EncoderFunc) Encode(c Context, code int, data interface{}) error {
+	return f(c, code, data)
+}
+
+// RegisterEncoder registers the encoder used by `Context#Negotiate()` for
+// mediaType, e.g. "text/csv", replacing an encoder registered before. If the
+// client accepts several media types equally, the one registered first is
+// used. JSON, XML, plain text and HTML templates are registered by default.
+func (m *Mux) RegisterEncoder(mediaType string, e Encoder) {
+	mediaType = strings.ToLower(mediaType)
+	for i, entry := range m.encoders {
+		if entry.mediaType == mediaType {
+			m.encoders[i].encoder = e
+			return
+		}
+	}
+	m.encoders = append(m.encoders, encoderEntry{mediaType: mediaType, encoder: e})
+}
+
+// registerDefaultEncoders registers the encoders of the built-in formats.
+func (m *Mux) registerDefaultEncoders() {
+	m.RegisterEncoder(MIMEApplicationJSON, EncoderFunc(func(c Context, code int, data interface{}) error {
+		return c.JSON(code, data)
+	}))
+	m.RegisterEncoder(MIMEApplicationXML, EncoderFunc(func(c Context, code int, data interface{}) error {
+		return c.XML(code, data)
+	}))
+	m.RegisterEncoder(MIMETextXML, EncoderFunc(func(c Context, code int, data interface{}) error {
+		c.Response().Header().Set(HeaderContentType, MIMETextXMLCharsetUTF8)
+		return c.XML(code, data)
+	}))
+	m.RegisterEncoder(MIMETextHTML, templateEncoder{})
+	m.RegisterEncoder(MIMETextPlain, textEncoder{})
+}
+
+// Negotiate implements `Context#Negotiate()`.
+func (c *context) Negotiate(code int, data interface{}) error {
+	c.response.Header().Add(HeaderVary, HeaderAccept)
+
+	var ranges []acceptRange
+	if accept := c.request.Header.Get(HeaderAccept); accept != "" {
+		ranges = parseAccept(accept)
+	}
+	var (
+		best  Encoder
+		bestQ float64
+	)
+	for _, entry := range c.mux.encoders {
+		q := 1.0
+		if ranges != nil {
+			q = acceptQuality(ranges, entry.mediaType)
+		}
+		if q > bestQ && entry.encoder.CanEncode(c, data) {
+			best, bestQ = entry.encoder, q
+		}
+		if ranges == nil && best != nil {
+			break
+		}
+	}
+	if best == nil {
+		return ErrNotAcceptable
+	}
+	return best.Encode(c, code, data)
+}
+
+// parseAccept parses the media ranges of an Accept header.
+func parseAccept(header string) []acceptRange {
+	var ranges []acceptRange
+	for _, part := range strings.Split(header, ",") {
+		params := strings.Split(part, ";")
+		mediaType := strings.ToLower(strings.TrimSpace(params[0]))
+		i := strings.IndexByte(mediaType, '/')
+		if i <= 0 || i == len(mediaType)-1 {
+			continue
+		}
+		r := acceptRange{typ: mediaType[:i], subtype: mediaType[i+1:], q: 1}
+		for _, p := range params[1:] {
+			p = strings.TrimSpace(p)
+			if strings.HasPrefix(p, "q=") {
+				if q, err := strconv.ParseFloat(p[2:], 64); err == nil {
+					r.q = q
+				}
+			}
+		}
+		ranges = append(ranges, r)
+	}
+	return ranges
+}
+
+// acceptQuality returns the quality of the most specific range matching
+// mediaType, e.g. "text/html" is matched by "text/html", "text/*" and "*/*".
+func acceptQuality(ranges []acceptRange, mediaType string) float64 {
+	i := strings.IndexByte(mediaType, '/')
+	typ, subtype := mediaType[:i], mediaType[i+1:]
+	q, specificity := 0.0, -1
+	for _, r := range ranges {
+		s := -1
+		switch {
+		case r.typ == typ && r.subtype == subtype:
+			s = 2
+		case r.typ == typ && r.subtype == "*":
+			s = 1
+		case r.typ == "*" && r.subtype == "*":
+			s = 0
+		}
+		if s > specificity {
+			q, specificity = r.q, s
+		}
+	}
+	return q
+}
+
+// CanEncode reports whether data is a string, bytes, a fmt.Stringer or an
+// error.
+func (textEncoder) CanEncode(c Context, data interface{}) bool {
+	switch data.(type) {
+	case string, []byte, fmt.Stringer, error:
+		return true
+	}
+	return false
+}
+
+func (textEncoder) Encode(c Context, code int, data interface{}) error {
+	switch v := data.(type) {
+	case []byte:
+		return c.Blob(code, MIMETextPlainCharsetUTF8, v)
+	case error:
+		return c.String(code, v.Error())
+	}
+	return c.String(code, fmt.Sprint(data))
+}
+
+// CanEncode reports whether a renderer is registered and a template is set as
+// context value or route meta, see TemplateKey.
+func (templateEncoder) CanEncode(c Context, data interface{}) bool {
+	return c.Mux().Renderer != nil && templateName(c) != ""
+}
+
+func (templateEncoder) Encode(c Context, code int, data interface{}) error {
+	return c.Render(code, templateName(c), data)
+}
+
+func templateName(c Context) string {
+	if name, ok := c.Get(TemplateKey).(string); ok && name != "" {
+		return name
+	}
+	if r := c.Route(); r != nil {
+		if name, ok := r.GetMeta(TemplateKey).(string); ok {
+			return name
+		}
+	}
+	return ""
+}
@@ -0,0 +1,76 @@
+package routing
+
+import (
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"text/template"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContextNegotiate(t *testing.T) {
+	m := New()
+	m.Renderer = &Template{
+		templates: template.Must(template.New("user").Parse("<p>{{.Name}}</p>")),
+	}
+	m.RegisterEncoder("text/csv", EncoderFunc(func(c Context, code int, data interface{}) error {
+		u := data.(user)
+		c.Response().Header().Set(HeaderContentType, "text/csv")
+		c.Response().WriteHeader(code)
+		w := csv.NewWriter(c.Response())
+		w.Write([]string{"1", u.Name})
+		w.Flush()
+		return w.Error()
+	}))
+	m.GET("/users/1", func(c Context) error {
+		return c.Negotiate(http.StatusOK, user{1, "Jon Snow"})
+	}).SetMeta(TemplateKey, "user")
+	m.GET("/plain", func(c Context) error {
+		return c.Negotiate(http.StatusOK, errors.New("failed"))
+	})
+
+	tests := []struct {
+		name, path, accept string
+		code               int
+		contentType, body  string
+	}{
+		{"no accept header", "/users/1", "", http.StatusOK, MIMEApplicationJSONCharsetUTF8, userJSON + "\n"},
+		{"exact", "/users/1", "application/xml", http.StatusOK, MIMEApplicationXMLCharsetUTF8, xml.Header + userXML},
+		{"text xml", "/users/1", "text/xml", http.StatusOK, MIMETextXMLCharsetUTF8, xml.Header + userXML},
+		{"q-values", "/users/1", "application/json;q=0.5, text/csv;q=0.9", http.StatusOK, "text/csv", "1,Jon Snow\n"},
+		{"browser", "/users/1", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", http.StatusOK, MIMETextHTMLCharsetUTF8, "<p>Jon Snow</p>"},
+		{"wildcard prefers first registered", "/users/1", "*/*", http.StatusOK, MIMEApplicationJSONCharsetUTF8, userJSON + "\n"},
+		{"specific range wins", "/users/1", "application/*;q=0.1, application/xml", http.StatusOK, MIMEApplicationXMLCharsetUTF8, xml.Header + userXML},
+		{"excluded", "/users/1", "application/json;q=0, */*", http.StatusOK, MIMEApplicationXMLCharsetUTF8, xml.Header + userXML},
+		{"not acceptable", "/users/1", "image/png", http.StatusNotAcceptable, MIMEApplicationJSONCharsetUTF8, `{"message":"Not Acceptable"}` + "\n"},
+		{"text only for text data", "/plain", "text/plain", http.StatusOK, MIMETextPlainCharsetUTF8, "failed"},
+		{"no template for other data", "/plain", "text/html", http.StatusNotAcceptable, MIMEApplicationJSONCharsetUTF8, `{"message":"Not Acceptable"}` + "\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.accept != "" {
+				req.Header.Set(HeaderAccept, tt.accept)
+			}
+			rec := httptest.NewRecorder()
+			m.ServeHTTP(rec, req)
+			assert.Equal(t, tt.code, rec.Code)
+			assert.Equal(t, tt.contentType, rec.Header().Get(HeaderContentType))
+			assert.Equal(t, tt.body, rec.Body.String())
+			assert.Equal(t, HeaderAccept, rec.Header().Get(HeaderVary))
+		})
+	}
+}
+
+func TestAcceptQuality(t *testing.T) {
+	ranges := parseAccept("text/*;q=0.3, text/html;q=0.7, text/html;level=1, */*;q=0.5, invalid")
+	assert.Len(t, ranges, 4)
+	assert.Equal(t, 0.7, acceptQuality(ranges, "text/html"))
+	assert.Equal(t, 0.3, acceptQuality(ranges, "text/plain"))
+	assert.Equal(t, 0.5, acceptQuality(ranges, "image/jpeg"))
+	assert.Nil(t, parseAccept("invalid"))
+}
@@ -0,0 +1,381 @@
+// Package openapi generates OpenAPI 3 documents describing the routes of a
+// routing.Mux. Request and response types are registered on the routes using
+// `Route#SetRequest()` and `Route#AddResponse()`; their `json`, `param`,
+// `query`, `header` and `form` tags and `validate` rules are mapped to
+// parameters and JSON Schema constraints.
+package openapi
+
+import (
+	"goplugins/core/routing"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Version is the version of the OpenAPI specification of the documents.
+const Version = "3.0.3"
+
+type (
+	// Config defines the config of the document and the endpoint serving it.
+	Config struct {
+		// Path the document is served at.
+		// Optional. Default value "/openapi.json".
+		Path string
+
+		// Info describes the API.
+		// Optional. Default title "API" and version "1.0.0".
+		Info Info
+
+		// Servers are the base URLs of the API.
+		// Optional.
+		Servers []Server
+
+		// Skip excludes routes from the document. Internal routes are always
+		// excluded.
+		// Optional.
+		Skip func(*routing.Route) bool
+	}
+
+	// Document is the root of an OpenAPI document.
+	Document struct {
+		OpenAPI    string               `json:"openapi"`
+		Info       Info                 `json:"info"`
+		Servers    []Server             `json:"servers,omitempty"`
+		Tags       []Tag                `json:"tags,omitempty"`
+		Paths      map[string]*PathItem `json:"paths"`
+		Components *Components          `json:"components,omitempty"`
+	}
+
+	// Info describes the API.
+	Info struct {
+		Title       string `json:"title"`
+		Description string `json:"description,omitempty"`
+		Version     string `json:"version"`
+	}
+
+	// Server is a base URL of the API.
+	Server struct {
+		URL         string `json:"url"`
+		Description string `json:"description,omitempty"`
+	}
+
+	// Tag groups operations.
+	Tag struct {
+		Name string `json:"name"`
+	}
+
+	// PathItem holds the operations of a path by lower case method.
+	PathItem map[string]*Operation
+
+	// Operation describes a route.
+	Operation struct {
+		OperationID string               `json:"operationId,omitempty"`
+		Summary     string               `json:"summary,omitempty"`
+		Tags        []string             `json:"tags,omitempty"`
+		Parameters  []*Parameter         `json:"parameters,omitempty"`
+		RequestBody *RequestBody         `json:"requestBody,omitempty"`
+		Responses   map[string]*Response `json:"responses"`
+		Permissions []string             `json:"x-permissions,omitempty"`
+	}
+
+	// Parameter is a path, query or header parameter.
+	Parameter struct {
+		Name     string  `json:"name"`
+		In       string  `json:"in"`
+		Required bool    `json:"required,omitempty"`
+		Schema   *Schema `json:"schema"`
+	}
+
+	// RequestBody describes the body of requests by content type.
+	RequestBody struct {
+		Required bool                  `json:"required,omitempty"`
+		Content  map[string]*MediaType `json:"content"`
+	}
+
+	// Response describes a response.
+	Response struct {
+		Description string                `json:"description"`
+		Content     map[string]*MediaType `json:"content,omitempty"`
+	}
+
+	// MediaType holds the schema of a body.
+	MediaType struct {
+		Schema *Schema `json:"schema"`
+	}
+
+	// Components holds the schemas of the named types.
+	Components struct {
+		Schemas map[string]*Schema `json:"schemas,omitempty"`
+	}
+)
+
+var (
+	// DefaultConfig is the default config.
+	DefaultConfig = Config{
+		Path: "/openapi.json",
+		Info: Info{
+			Title:   "API",
+			Version: "1.0.0",
+		},
+	}
+
+	bodylessMethods = map[string]bool{
+		http.MethodGet:     true,
+		http.MethodHead:    true,
+		http.MethodDelete:  true,
+		http.MethodOptions: true,
+		http.MethodTrace:   true,
+	}
+
+	operationIDPattern = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+)
+
+// Register registers a route serving the document of all routes of m. The
+// document is generated on the first request, so routes registered later,
+// e.g. by plugins, are included.
+func Register(m *routing.Mux, config Config) *routing.Route {
+	config = withDefaults(config)
+
+	var (
+		once  sync.Once
+		doc   *Document
+		route *routing.Route
+	)
+	skip := config.Skip
+	config.Skip = func(r *routing.Route) bool {
+		return r == route || skip != nil && skip(r)
+	}
+	route = m.GET(config.Path, func(c routing.Context) error {
+		once.Do(func() {
+			doc = Generate(m.Routes(), config)
+		})
+		return c.JSON(http.StatusOK, doc)
+	})
+	return route.SetDescription("OpenAPI document")
+}
+
+// Generate returns the document describing routes.
+func Generate(routes []*routing.Route, config Config) *Document {
+	config = withDefaults(config)
+	g := newGenerator()
+	doc := &Document{
+		OpenAPI: Version,
+		Info:    config.Info,
+		Servers: config.Servers,
+		Paths:   map[string]*PathItem{},
+	}
+
+	names := map[string]int{}
+	for _, r := range routes {
+		names[r.Name]++
+	}
+
+	tags := map[string]bool{}
+	for _, r := range routes {
+		if r.Internal || config.Skip != nil && config.Skip(r) {
+			continue
+		}
+		path, params := convertPath(r.Path)
+		op := &Operation{
+			Summary:     r.Description,
+			Tags:        r.Tags,
+			Permissions: r.Permissions,
+			Responses:   map[string]*Response{},
+		}
+		if names[r.Name] == 1 && operationIDPattern.MatchString(r.Name) {
+			op.OperationID = r.Name
+		}
+		g.request(op, r, params)
+		g.responses(op, r)
+
+		item := doc.Paths[path]
+		if item == nil {
+			item = &PathItem{}
+			doc.Paths[path] = item
+		}
+		(*item)[strings.ToLower(r.Method)] = op
+		for _, t := range r.Tags {
+			tags[t] = true
+		}
+	}
+
+	for t := range tags {
+		doc.Tags = append(doc.Tags, Tag{Name: t})
+	}
+	sort.Slice(doc.Tags, func(i, j int) bool {
+		return doc.Tags[i].Name < doc.Tags[j].Name
+	})
+	if len(g.schemas) > 0 {
+		doc.Components = &Components{Schemas: g.schemas}
+	}
+	return doc
+}
+
+func withDefaults(config Config) Config {
+	if config.Path == "" {
+		config.Path = DefaultConfig.Path
+	}
+	if config.Info.Title == "" {
+		config.Info.Title = DefaultConfig.Info.Title
+	}
+	if config.Info.Version == "" {
+		config.Info.Version = DefaultConfig.Info.Version
+	}
+	return config
+}
+
+// request adds the parameters and the body of the request to op. Params of
+// the path not described by the request type are added using the schema of
+// their constraint.
+func (g *generator) request(op *Operation, r *routing.Route, params []*Parameter) {
+	described := map[string]bool{}
+	if r.Request != nil {
+		t := indirect(reflect.TypeOf(r.Request))
+		if t.Kind() == reflect.Struct {
+			var body []reflect.StructField
+			var form bool
+			for _, f := range fields(t) {
+				if p := g.parameter(f); p != nil {
+					op.Parameters = append(op.Parameters, p)
+					if p.In == "path" {
+						described[p.Name] = true
+					}
+					continue
+				}
+				if _, ok := f.Tag.Lookup("form"); ok {
+					form = true
+				}
+				body = append(body, f)
+			}
+			if len(body) > 0 && !bodylessMethods[r.Method] {
+				op.RequestBody = g.requestBody(t, body, form)
+			}
+		} else if !bodylessMethods[r.Method] {
+			op.RequestBody = &RequestBody{
+				Required: true,
+				Content: map[string]*MediaType{
+					routing.MIMEApplicationJSON: {Schema: g.schema(t, "")},
+				},
+			}
+		}
+	}
+
+	var pathParams []*Parameter
+	for _, p := range params {
+		if !described[p.Name] {
+			pathParams = append(pathParams, p)
+		}
+	}
+	op.Parameters = append(pathParams, op.Parameters...)
+}
+
+// parameter returns the parameter bound to f, or nil if f is bound from the
+// body.
+func (g *generator) parameter(f reflect.StructField) *Parameter {
+	for _, in := range []struct{ tag, in string }{{"param", "path"}, {"query", "query"}, {"header", "header"}} {
+		name := f.Tag.Get(in.tag)
+		if name == "" || name == "-" {
+			continue
+		}
+		s, required := g.field(f.Type, f.Tag.Get(validateTag))
+		return &Parameter{
+			Name:     name,
+			In:       in.in,
+			Required: required || in.in == "path",
+			Schema:   s,
+		}
+	}
+	return nil
+}
+
+func (g *generator) requestBody(t reflect.Type, body []reflect.StructField, form bool) *RequestBody {
+	var s *Schema
+	if len(body) == len(fields(t)) {
+		s = g.schema(t, "")
+	} else {
+		s = g.object(body, jsonName)
+	}
+	rb := &RequestBody{
+		Required: true,
+		Content: map[string]*MediaType{
+			routing.MIMEApplicationJSON: {Schema: s},
+		},
+	}
+	if form {
+		rb.Content[routing.MIMEApplicationForm] = &MediaType{Schema: g.object(body, formName)}
+	}
+	return rb
+}
+
+func (g *generator) responses(op *Operation, r *routing.Route) {
+	if len(r.Responses) == 0 {
+		op.Responses["200"] = &Response{Description: http.StatusText(http.StatusOK)}
+		return
+	}
+	for code, v := range r.Responses {
+		res := &Response{Description: http.StatusText(code)}
+		if v != nil {
+			res.Content = map[string]*MediaType{
+				routing.MIMEApplicationJSON: {Schema: g.schema(reflect.TypeOf(v), "")},
+			}
+		}
+		op.Responses[strconv.Itoa(code)] = res
+	}
+}
+
+// convertPath converts a route path to an OpenAPI path template and returns
+// the parameters of the path, e.g. "/users/{id}" for "/users/:id<uuid>".
+func convertPath(path string) (string, []*Parameter) {
+	var (
+		b      strings.Builder
+		params []*Parameter
+	)
+	for i, l := 0, len(path); i < l; i++ {
+		switch path[i] {
+		case ':':
+			j := i + 1
+			for ; i < l && path[i] != '/' && path[i] != '<'; i++ {
+			}
+			p := &Parameter{Name: path[j:i], In: "path", Required: true, Schema: &Schema{Type: "string"}}
+			if i < l && path[i] == '<' {
+				k := strings.IndexByte(path[i:], '/')
+				if k == -1 {
+					k = l - i
+				}
+				p.Schema = constraintSchema(path[i+1 : i+k-1])
+				i += k
+			}
+			params = append(params, p)
+			b.WriteString("{" + p.Name + "}")
+			if i < l {
+				b.WriteByte(path[i])
+			}
+		case '*':
+			params = append(params, &Parameter{Name: "*", In: "path", Required: true, Schema: &Schema{Type: "string"}})
+			b.WriteString("{*}")
+		default:
+			b.WriteByte(path[i])
+		}
+	}
+	return b.String(), params
+}
+
+// constraintSchema returns the schema of a route param constraint.
+func constraintSchema(constraint string) *Schema {
+	switch constraint {
+	case "int":
+		return &Schema{Type: "integer"}
+	case "uuid":
+		return &Schema{Type: "string", Format: "uuid"}
+	case "alpha":
+		return &Schema{Type: "string", Pattern: "^[a-zA-Z]+$"}
+	case "alnum":
+		return &Schema{Type: "string", Pattern: "^[a-zA-Z0-9]+$"}
+	default:
+		return &Schema{Type: "string", Pattern: "^(?:" + constraint + ")$"}
+	}
+}
@@ -0,0 +1,131 @@
+package openapi
+
+import (
+	"encoding/json"
+	"goplugins/core/routing"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type (
+	createUserRequest struct {
+		TenantID string `param:"tenant"`
+		Notify   bool   `query:"notify"`
+		Email    string `json:"email" form:"email" validate:"format=email"`
+		Name     string `json:"name" form:"name" validate:"empty=false & lte=64"`
+	}
+
+	listUsersRequest struct {
+		Limit  int    `query:"limit" validate:"gte=1 & lte=100"`
+		Search string `query:"q"`
+	}
+
+	userResponse struct {
+		ID    int    `json:"id"`
+		Email string `json:"email"`
+	}
+)
+
+func TestGenerate(t *testing.T) {
+	m := routing.New()
+	h := func(c routing.Context) error { return nil }
+	m.POST("/tenants/:tenant/users", h).
+		SetName("users.create").
+		SetDescription("Create a user").
+		AddTags("users").
+		RequirePermissions("users.create").
+		SetRequest(createUserRequest{}).
+		AddResponse(http.StatusCreated, userResponse{}).
+		AddResponse(http.StatusConflict, nil)
+	m.GET("/tenants/:tenant/users", h).
+		AddTags("users").
+		SetRequest(&listUsersRequest{}).
+		AddResponse(http.StatusOK, []userResponse{})
+	m.GET("/users/:id<uuid>", h).SetName("users.show")
+	m.GET("/files/*", h)
+	m.Group("/admin", func(next routing.HandlerFunc) routing.HandlerFunc { return next })
+
+	doc := Generate(m.Routes(), Config{Info: Info{Title: "Users"}})
+	assert.Equal(t, Version, doc.OpenAPI)
+	assert.Equal(t, Info{Title: "Users", Version: "1.0.0"}, doc.Info)
+	assert.Equal(t, []Tag{{Name: "users"}}, doc.Tags)
+	assert.Len(t, doc.Paths, 3, "internal routes are excluded")
+
+	create := (*doc.Paths["/tenants/{tenant}/users"])["post"]
+	if assert.NotNil(t, create) {
+		assert.Equal(t, "users.create", create.OperationID)
+		assert.Equal(t, "Create a user", create.Summary)
+		assert.Equal(t, []string{"users"}, create.Tags)
+		assert.Equal(t, []string{"users.create"}, create.Permissions)
+		assert.Equal(t, []*Parameter{
+			{Name: "tenant", In: "path", Required: true, Schema: &Schema{Type: "string"}},
+			{Name: "notify", In: "query", Schema: &Schema{Type: "boolean"}},
+		}, create.Parameters)
+
+		body := create.RequestBody.Content[routing.MIMEApplicationJSON].Schema
+		assert.Equal(t, []string{"name"}, body.Required)
+		assert.Equal(t, "email", body.Properties["email"].Format)
+		assert.Equal(t, 64, *body.Properties["name"].MaxLength)
+		assert.NotContains(t, body.Properties, "TenantID")
+		assert.Contains(t, create.RequestBody.Content, routing.MIMEApplicationForm)
+
+		assert.Equal(t, "#/components/schemas/userResponse", create.Responses["201"].Content[routing.MIMEApplicationJSON].Schema.Ref)
+		assert.Equal(t, &Response{Description: "Conflict"}, create.Responses["409"])
+	}
+
+	list := (*doc.Paths["/tenants/{tenant}/users"])["get"]
+	if assert.NotNil(t, list) {
+		assert.Nil(t, list.RequestBody)
+		assert.Empty(t, list.OperationID, "generated handler names are not used")
+		assert.Len(t, list.Parameters, 3)
+		limit := list.Parameters[1].Schema
+		assert.Equal(t, 1.0, *limit.Minimum)
+		assert.Equal(t, 100.0, *limit.Maximum)
+		assert.Equal(t, "array", list.Responses["200"].Content[routing.MIMEApplicationJSON].Schema.Type)
+	}
+
+	show := (*doc.Paths["/users/{id}"])["get"]
+	if assert.NotNil(t, show) {
+		assert.Equal(t, &Schema{Type: "string", Format: "uuid"}, show.Parameters[0].Schema)
+		assert.Equal(t, &Response{Description: "OK"}, show.Responses["200"])
+	}
+	assert.NotNil(t, doc.Paths["/files/{*}"])
+
+	assert.Contains(t, doc.Components.Schemas, "userResponse")
+}
+
+func TestRegister(t *testing.T) {
+	m := routing.New()
+	Register(m, Config{Path: "/docs/openapi.json", Skip: func(r *routing.Route) bool {
+		return r.Path == "/hidden"
+	}})
+	m.GET("/users", func(c routing.Context) error { return nil })
+	m.GET("/hidden", func(c routing.Context) error { return nil })
+
+	req := httptest.NewRequest(http.MethodGet, "/docs/openapi.json", nil)
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var doc map[string]interface{}
+	if assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &doc)) {
+		assert.Equal(t, "3.0.3", doc["openapi"])
+		paths := doc["paths"].(map[string]interface{})
+		assert.Contains(t, paths, "/users")
+		assert.NotContains(t, paths, "/hidden")
+		assert.NotContains(t, paths, "/docs/openapi.json")
+	}
+}
+
+func TestConvertPath(t *testing.T) {
+	path, params := convertPath("/posts/:year<int>/:slug<[a-z0-9-]+>/comments/:id")
+	assert.Equal(t, "/posts/{year}/{slug}/comments/{id}", path)
+	if assert.Len(t, params, 3) {
+		assert.Equal(t, "integer", params[0].Schema.Type)
+		assert.Equal(t, "^(?:[a-z0-9-]+)$", params[1].Schema.Pattern)
+		assert.Equal(t, "id", params[2].Name)
+	}
+}
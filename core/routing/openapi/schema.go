@@ -0,0 +1,347 @@
+package openapi
+
+import (
+	"encoding"
+	"goplugins/core/framework/validation"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const validateTag = validation.MasterTag
+
+type (
+	// Schema is a JSON Schema as used by OpenAPI 3.0.
+	Schema struct {
+		Ref                  string             `json:"$ref,omitempty"`
+		Type                 string             `json:"type,omitempty"`
+		Format               string             `json:"format,omitempty"`
+		Nullable             bool               `json:"nullable,omitempty"`
+		Enum                 []interface{}      `json:"enum,omitempty"`
+		Minimum              *float64           `json:"minimum,omitempty"`
+		ExclusiveMinimum     bool               `json:"exclusiveMinimum,omitempty"`
+		Maximum              *float64           `json:"maximum,omitempty"`
+		ExclusiveMaximum     bool               `json:"exclusiveMaximum,omitempty"`
+		MinLength            *int               `json:"minLength,omitempty"`
+		MaxLength            *int               `json:"maxLength,omitempty"`
+		Pattern              string             `json:"pattern,omitempty"`
+		MinItems             *int               `json:"minItems,omitempty"`
+		MaxItems             *int               `json:"maxItems,omitempty"`
+		MinProperties        *int               `json:"minProperties,omitempty"`
+		MaxProperties        *int               `json:"maxProperties,omitempty"`
+		Items                *Schema            `json:"items,omitempty"`
+		Properties           map[string]*Schema `json:"properties,omitempty"`
+		AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+		Required             []string           `json:"required,omitempty"`
+	}
+
+	// generator builds schemas, named struct types are added to the
+	// components and referenced.
+	generator struct {
+		schemas map[string]*Schema
+		names   map[reflect.Type]string
+	}
+)
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+
+	// formats maps the formats of the validation package to OpenAPI formats,
+	// others are used as they are.
+	formats = map[string]string{
+		"url":              "uri",
+		"uuid3":            "uuid",
+		"uuid4":            "uuid",
+		"uuid5":            "uuid",
+		"base64":           "byte",
+		"hostname_rfc1123": "hostname",
+	}
+)
+
+func newGenerator() *generator {
+	return &generator{
+		schemas: map[string]*Schema{},
+		names:   map[reflect.Type]string{},
+	}
+}
+
+// schema returns the schema of t constrained by the validation tag.
+func (g *generator) schema(t reflect.Type, tag string) *Schema {
+	s, _ := g.field(t, tag)
+	return s
+}
+
+// field returns the schema of a value of type t constrained by the validation
+// tag, and whether the tag requires the value to be set.
+func (g *generator) field(t reflect.Type, tag string) (*Schema, bool) {
+	rules, _, elements, err := validation.ParseTag(tag)
+	if err != nil || len(rules) > 1 {
+		// Alternatives can't be expressed as constraints.
+		rules = nil
+	}
+	var and []validation.Rule
+	if len(rules) == 1 {
+		and = rules[0]
+	}
+	required := false
+	for _, r := range and {
+		if (r.Type == validation.ValidatorEmpty || r.Type == validation.ValidatorNil) && r.Value == "false" {
+			required = true
+		}
+	}
+
+	switch {
+	case t.Kind() == reflect.Ptr:
+		s, elemRequired := g.field(t.Elem(), elements)
+		return s, required || elemRequired
+	case t == timeType:
+		return &Schema{Type: "string", Format: "date-time"}, required
+	case t.Implements(textMarshalerType) || reflect.PtrTo(t).Implements(textMarshalerType):
+		s := &Schema{Type: "string"}
+		if t.PkgPath() == "github.com/google/uuid" && t.Name() == "UUID" {
+			s.Format = "uuid"
+		}
+		applyString(s, and)
+		return s, required
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}, required
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		s := &Schema{Type: "integer"}
+		switch t.Kind() {
+		case reflect.Int32, reflect.Uint32:
+			s.Format = "int32"
+		case reflect.Int64, reflect.Uint64:
+			s.Format = "int64"
+		}
+		if t.Kind() >= reflect.Uint && t.Kind() <= reflect.Uint64 {
+			zero := 0.0
+			s.Minimum = &zero
+		}
+		applyNumber(s, and, true)
+		return s, required
+	case reflect.Float32, reflect.Float64:
+		s := &Schema{Type: "number", Format: "double"}
+		if t.Kind() == reflect.Float32 {
+			s.Format = "float"
+		}
+		applyNumber(s, and, false)
+		return s, required
+	case reflect.String:
+		s := &Schema{Type: "string"}
+		applyString(s, and)
+		return s, required
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			// Encoded as base64 by encoding/json.
+			return &Schema{Type: "string", Format: "byte"}, required
+		}
+		s := &Schema{Type: "array", Items: g.schema(t.Elem(), elements)}
+		s.MinItems, s.MaxItems = lengths(and)
+		return s, required
+	case reflect.Map:
+		s := &Schema{Type: "object", AdditionalProperties: g.schema(t.Elem(), elements)}
+		s.MinProperties, s.MaxProperties = lengths(and)
+		return s, required
+	case reflect.Struct:
+		return g.ref(t), required
+	}
+	// Interfaces and other types may hold any value.
+	return &Schema{}, required
+}
+
+// ref returns a reference to the schema of the struct type t, which is added
+// to the components. Anonymous structs are inlined.
+func (g *generator) ref(t reflect.Type) *Schema {
+	if t.Name() == "" {
+		return g.object(fields(t), jsonName)
+	}
+	name, ok := g.names[t]
+	if !ok {
+		name = t.Name()
+		if _, taken := g.schemas[name]; taken {
+			pkg := t.PkgPath()
+			name = strings.Title(pkg[strings.LastIndexByte(pkg, '/')+1:]) + name
+		}
+		for i := 2; g.schemas[name] != nil; i++ {
+			name = t.Name() + strconv.Itoa(i)
+		}
+		g.names[t] = name
+		// Register the name first to support recursive types.
+		g.schemas[name] = &Schema{}
+		*g.schemas[name] = *g.object(fields(t), jsonName)
+	}
+	return &Schema{Ref: "#/components/schemas/" + name}
+}
+
+// object returns the schema of an object with the fields named by name.
+func (g *generator) object(fs []reflect.StructField, name func(reflect.StructField) string) *Schema {
+	s := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	for _, f := range fs {
+		n := name(f)
+		if n == "" {
+			continue
+		}
+		p, required := g.field(f.Type, f.Tag.Get(validateTag))
+		s.Properties[n] = p
+		if required {
+			s.Required = append(s.Required, n)
+		}
+	}
+	return s
+}
+
+// fields returns the exported fields of the struct type t including those of
+// embedded structs without name.
+func fields(t reflect.Type) []reflect.StructField {
+	var fs []reflect.StructField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Anonymous && f.Tag.Get("json") == "" {
+			et := indirect(f.Type)
+			if et.Kind() == reflect.Struct && et != timeType {
+				fs = append(fs, fields(et)...)
+				continue
+			}
+		}
+		if f.PkgPath != "" {
+			continue
+		}
+		fs = append(fs, f)
+	}
+	return fs
+}
+
+// jsonName returns the name of f in JSON bodies, or "" if it is omitted.
+func jsonName(f reflect.StructField) string {
+	name := strings.Split(f.Tag.Get("json"), ",")[0]
+	if name == "-" {
+		return ""
+	}
+	if name == "" {
+		return f.Name
+	}
+	return name
+}
+
+// formName returns the name of f in form bodies, or "" if it is omitted.
+func formName(f reflect.StructField) string {
+	name := f.Tag.Get("form")
+	if name == "-" {
+		return ""
+	}
+	if name == "" {
+		return f.Name
+	}
+	return name
+}
+
+func indirect(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
+
+// applyNumber maps validation rules of numbers to constraints.
+func applyNumber(s *Schema, rules []validation.Rule, integer bool) {
+	for _, r := range rules {
+		if r.Type == validation.ValidatorOneOf {
+			s.Enum = enum(r.Value, func(token string) (interface{}, bool) {
+				if integer {
+					v, err := strconv.ParseInt(token, 10, 64)
+					return v, err == nil
+				}
+				v, err := strconv.ParseFloat(token, 64)
+				return v, err == nil
+			})
+			continue
+		}
+		v, err := strconv.ParseFloat(r.Value, 64)
+		if err != nil {
+			// E.g. durations like "1s".
+			continue
+		}
+		switch r.Type {
+		case validation.ValidatorGte:
+			s.Minimum = &v
+		case validation.ValidatorGt:
+			s.Minimum, s.ExclusiveMinimum = &v, true
+		case validation.ValidatorLte:
+			s.Maximum = &v
+		case validation.ValidatorLt:
+			s.Maximum, s.ExclusiveMaximum = &v, true
+		case validation.ValidatorEq:
+			s.Enum = []interface{}{v}
+			if integer {
+				s.Enum = []interface{}{int64(v)}
+			}
+		}
+	}
+}
+
+// applyString maps validation rules of strings to constraints.
+func applyString(s *Schema, rules []validation.Rule) {
+	s.MinLength, s.MaxLength = lengths(rules)
+	for _, r := range rules {
+		switch r.Type {
+		case validation.ValidatorFormat:
+			s.Format = r.Value
+			if f, ok := formats[r.Value]; ok {
+				s.Format = f
+			}
+		case validation.ValidatorOneOf:
+			s.Enum = enum(r.Value, func(token string) (interface{}, bool) {
+				return token, true
+			})
+		}
+	}
+}
+
+// lengths maps validation rules of strings, slices and maps to minimum and
+// maximum lengths.
+func lengths(rules []validation.Rule) (min, max *int) {
+	for _, r := range rules {
+		if r.Type == validation.ValidatorEmpty {
+			if r.Value == "false" {
+				one := 1
+				min = &one
+			}
+			continue
+		}
+		v, err := strconv.Atoi(r.Value)
+		if err != nil {
+			continue
+		}
+		switch r.Type {
+		case validation.ValidatorGte:
+			min = &v
+		case validation.ValidatorGt:
+			v++
+			min = &v
+		case validation.ValidatorLte:
+			max = &v
+		case validation.ValidatorLt:
+			v--
+			max = &v
+		case validation.ValidatorEq:
+			min, max = &v, &v
+		}
+	}
+	return min, max
+}
+
+func enum(tokens string, parse func(string) (interface{}, bool)) []interface{} {
+	var values []interface{}
+	for _, token := range strings.Split(tokens, ",") {
+		if v, ok := parse(strings.TrimSpace(token)); ok {
+			values = append(values, v)
+		}
+	}
+	return values
+}
@@ -0,0 +1,86 @@
+package openapi
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+type (
+	node struct {
+		Name     string  `json:"name"`
+		Children []*node `json:"children,omitempty"`
+	}
+
+	base struct {
+		ID        uuid.UUID `json:"id"`
+		CreatedAt time.Time `json:"createdAt"`
+	}
+
+	product struct {
+		base
+		Name     string            `json:"name" validate:"gte=3 & lt=100"`
+		Price    float64           `json:"price" validate:"gt=0"`
+		Stock    uint              `json:"stock"`
+		Status   string            `json:"status" validate:"one_of=draft,published"`
+		Rating   int               `json:"rating" validate:"one_of=1,2,3"`
+		Website  string            `json:"website" validate:"format=url"`
+		Tags     []string          `json:"tags" validate:"empty=false > empty=false & lte=20"`
+		Labels   map[string]string `json:"labels" validate:"lte=5"`
+		Parent   *product          `json:"parent" validate:"nil=false"`
+		Image    []byte            `json:"image"`
+		Extra    interface{}       `json:"extra"`
+		Timeout  time.Duration     `json:"timeout" validate:"gte=1s"`
+		Either   int               `json:"either" validate:"eq=1 | eq=2"`
+		Internal string            `json:"-"`
+		private  string
+	}
+)
+
+func TestSchema(t *testing.T) {
+	g := newGenerator()
+	s := g.schema(reflect.TypeOf(product{}), "")
+	assert.Equal(t, "#/components/schemas/product", s.Ref)
+
+	p := g.schemas["product"]
+	assert.Equal(t, "object", p.Type)
+	assert.Equal(t, []string{"tags", "parent"}, p.Required)
+	assert.NotContains(t, p.Properties, "Internal")
+	assert.NotContains(t, p.Properties, "private")
+
+	props := p.Properties
+	assert.Equal(t, &Schema{Type: "string", Format: "uuid"}, props["id"])
+	assert.Equal(t, &Schema{Type: "string", Format: "date-time"}, props["createdAt"])
+
+	assert.Equal(t, 3, *props["name"].MinLength)
+	assert.Equal(t, 99, *props["name"].MaxLength)
+	assert.Equal(t, 0.0, *props["price"].Minimum)
+	assert.True(t, props["price"].ExclusiveMinimum)
+	assert.Equal(t, 0.0, *props["stock"].Minimum)
+	assert.Equal(t, []interface{}{"draft", "published"}, props["status"].Enum)
+	assert.Equal(t, []interface{}{int64(1), int64(2), int64(3)}, props["rating"].Enum)
+	assert.Equal(t, "uri", props["website"].Format)
+
+	tags := props["tags"]
+	assert.Equal(t, 1, *tags.MinItems)
+	assert.Equal(t, 1, *tags.Items.MinLength)
+	assert.Equal(t, 20, *tags.Items.MaxLength)
+
+	assert.Equal(t, 5, *props["labels"].MaxProperties)
+	assert.Equal(t, "string", props["labels"].AdditionalProperties.Type)
+	assert.Equal(t, "#/components/schemas/product", props["parent"].Ref)
+	assert.Equal(t, &Schema{Type: "string", Format: "byte"}, props["image"])
+	assert.Equal(t, &Schema{}, props["extra"])
+	assert.Equal(t, &Schema{Type: "integer", Format: "int64"}, props["timeout"])
+	assert.Equal(t, &Schema{Type: "integer"}, props["either"])
+}
+
+func TestSchemaRecursive(t *testing.T) {
+	g := newGenerator()
+	s := g.schema(reflect.TypeOf(node{}), "")
+	assert.Equal(t, "#/components/schemas/node", s.Ref)
+	assert.Equal(t, "#/components/schemas/node", g.schemas["node"].Properties["children"].Items.Ref)
+}
@@ -2,9 +2,164 @@ package routing
 
 type (
 	// Route contains a handler and information for matching against requests.
+	// The metadata is available to middleware and handlers using
+	// `Context#Route()`.
 	Route struct {
-		Method string `json:"method"`
-		Path   string `json:"path"`
-		Name   string `json:"name"`
+		Method      string   `json:"method"`
+		Path        string   `json:"path"`
+		Name        string   `json:"name"`
+		Description string   `json:"description,omitempty"`
+		Tags        []string `json:"tags,omitempty"`
+		Permissions []string `json:"permissions,omitempty"`
+		Meta        Map      `json:"meta,omitempty"`
+
+		// Request is a value of the type bound from the request, see
+		// `SetRequest()`.
+		Request interface{} `json:"-"`
+		// Responses maps status codes to values of the types of the response
+		// bodies, see `AddResponse()`.
+		Responses map[int]interface{} `json:"-"`
+		// Internal marks routes registered by the router itself, e.g. the
+		// catch-all routes of groups with middleware.
+		Internal bool `json:"-"`
 	}
+
+	// Routes is a set of routes registered at once, e.g. by `Mux#Any()`.
+	Routes []*Route
 )
+
+// SetName sets the name of the route used by `Mux#Reverse()`.
+func (r *Route) SetName(name string) *Route {
+	r.Name = name
+	return r
+}
+
+// SetDescription sets the description of the route.
+func (r *Route) SetDescription(description string) *Route {
+	r.Description = description
+	return r
+}
+
+// AddTags adds tags to the route, e.g. to group routes in documentation.
+func (r *Route) AddTags(tags ...string) *Route {
+	r.Tags = appendUnique(r.Tags, tags...)
+	return r
+}
+
+// RequirePermissions adds permissions required to access the route. They are
+// not checked by the router, see `middleware.Permissions()`.
+func (r *Route) RequirePermissions(permissions ...string) *Route {
+	r.Permissions = appendUnique(r.Permissions, permissions...)
+	return r
+}
+
+// SetMeta stores arbitrary metadata on the route.
+func (r *Route) SetMeta(key string, value interface{}) *Route {
+	if r.Meta == nil {
+		r.Meta = Map{}
+	}
+	r.Meta[key] = value
+	return r
+}
+
+// SetRequest sets the type bound from the request, e.g. for API documentation.
+// Pass a value of the type, e.g. `CreateUserRequest{}`.
+func (r *Route) SetRequest(v interface{}) *Route {
+	r.Request = v
+	return r
+}
+
+// AddResponse adds the type of the response body sent with status code. Pass
+// nil for responses without body.
+func (r *Route) AddResponse(code int, v interface{}) *Route {
+	if r.Responses == nil {
+		r.Responses = map[int]interface{}{}
+	}
+	r.Responses[code] = v
+	return r
+}
+
+// GetMeta returns the metadata stored for key.
+func (r *Route) GetMeta(key string) interface{} {
+	return r.Meta[key]
+}
+
+// HasTag reports whether the route is tagged with tag.
+func (r *Route) HasTag(tag string) bool {
+	return contains(r.Tags, tag)
+}
+
+// SetName sets the name of all routes.
+func (rs Routes) SetName(name string) Routes {
+	for _, r := range rs {
+		r.SetName(name)
+	}
+	return rs
+}
+
+// SetDescription sets the description of all routes.
+func (rs Routes) SetDescription(description string) Routes {
+	for _, r := range rs {
+		r.SetDescription(description)
+	}
+	return rs
+}
+
+// AddTags adds tags to all routes.
+func (rs Routes) AddTags(tags ...string) Routes {
+	for _, r := range rs {
+		r.AddTags(tags...)
+	}
+	return rs
+}
+
+// RequirePermissions adds permissions required to access all routes.
+func (rs Routes) RequirePermissions(permissions ...string) Routes {
+	for _, r := range rs {
+		r.RequirePermissions(permissions...)
+	}
+	return rs
+}
+
+// SetMeta stores arbitrary metadata on all routes.
+func (rs Routes) SetMeta(key string, value interface{}) Routes {
+	for _, r := range rs {
+		r.SetMeta(key, value)
+	}
+	return rs
+}
+
+// SetRequest sets the type bound from the request of all routes.
+func (rs Routes) SetRequest(v interface{}) Routes {
+	for _, r := range rs {
+		r.SetRequest(v)
+	}
+	return rs
+}
+
+// AddResponse adds the type of the response body sent with status code to all
+// routes.
+func (rs Routes) AddResponse(code int, v interface{}) Routes {
+	for _, r := range rs {
+		r.AddResponse(code, v)
+	}
+	return rs
+}
+
+func appendUnique(s []string, values ...string) []string {
+	for _, v := range values {
+		if !contains(s, v) {
+			s = append(s, v)
+		}
+	}
+	return s
+}
+
+func contains(s []string, v string) bool {
+	for _, e := range s {
+		if e == v {
+			return true
+		}
+	}
+	return false
+}
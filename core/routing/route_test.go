@@ -0,0 +1,110 @@
+package routing
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRouteMetadata(t *testing.T) {
+	m := New()
+	var route *Route
+	m.Use(func(next HandlerFunc) HandlerFunc {
+		return func(c Context) error {
+			route = c.Route()
+			return next(c)
+		}
+	})
+	r := m.GET("/users/:id", func(c Context) error {
+		return c.NoContent(http.StatusOK)
+	}).
+		SetName("users.show").
+		SetDescription("Show a user").
+		AddTags("users", "users").
+		RequirePermissions("users.read").
+		SetMeta("deprecated", true)
+
+	assert.Equal(t, "users.show", r.Name)
+	assert.Equal(t, "Show a user", r.Description)
+	assert.Equal(t, []string{"users"}, r.Tags)
+	assert.True(t, r.HasTag("users"))
+	assert.Equal(t, []string{"users.read"}, r.Permissions)
+	assert.Equal(t, true, r.GetMeta("deprecated"))
+
+	code, _ := request(http.MethodGet, "/users/1", m)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Same(t, r, route)
+
+	route = nil
+	code, _ = request(http.MethodGet, "/unknown", m)
+	assert.Equal(t, http.StatusNotFound, code)
+	assert.Nil(t, route)
+}
+
+func TestRouteMetadataGroup(t *testing.T) {
+	m := New()
+	g := m.Group("/admin").AddTags("admin").RequirePermissions("admin")
+	sg := g.Group("/users").AddTags("users")
+	h := func(c Context) error { return nil }
+
+	r := sg.GET("", h).RequirePermissions("users.read")
+	assert.Equal(t, []string{"admin", "users"}, r.Tags)
+	assert.Equal(t, []string{"admin", "users.read"}, r.Permissions)
+	assert.Equal(t, []string{"admin"}, g.GET("/stats", h).Tags)
+
+	rs := m.Match([]string{http.MethodGet, http.MethodPost}, "/items", h).SetName("items").AddTags("items")
+	for _, r := range rs {
+		assert.Equal(t, "items", r.Name)
+		assert.Equal(t, []string{"items"}, r.Tags)
+	}
+}
+
+func TestRouteHost(t *testing.T) {
+	m := New()
+	var route *Route
+	r := m.Host("api.example.com").GET("/", func(c Context) error {
+		route = c.Route()
+		return nil
+	})
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Host = "api.example.com"
+	m.ServeHTTP(httptest.NewRecorder(), req)
+	assert.Same(t, r, route)
+}
+
+func TestMuxReverse(t *testing.T) {
+	m := New()
+	h := func(Context) error { return nil }
+	m.GET("/static", h).SetName("static")
+	m.GET("/users/:id", h).SetName("user")
+	m.GET("/users/:uid/files/:fid<int>", h).SetName("file")
+	m.GET("/assets/*", h).SetName("assets")
+
+	assert.Equal(t, "/static", m.Reverse("static"))
+	assert.Equal(t, "/users/1", m.Reverse("user", 1))
+	assert.Equal(t, "/users/:id", m.Reverse("user"))
+	assert.Equal(t, "/users/a%2Fb%20c", m.Reverse("user", "a/b c"))
+	assert.Equal(t, "/users/1/files/2", m.Reverse("file", 1, 2))
+	assert.Equal(t, "/assets/css/a%20b.css", m.Reverse("assets", "css/a b.css"))
+	assert.Equal(t, "/users/1?q=a%26b&sort=name", m.Reverse("user", 1, url.Values{"sort": {"name"}, "q": {"a&b"}}))
+	assert.Equal(t, "/static?page=2", m.Reverse("static", url.Values{"page": {"2"}}))
+	assert.Equal(t, "", m.Reverse("unknown", url.Values{"page": {"2"}}))
+}
+
+func TestMuxRoutes(t *testing.T) {
+	m := New()
+	h := func(Context) error { return nil }
+	m.POST("/b", h)
+	m.GET("/b", h)
+	m.GET("a", h)
+
+	routes := m.Routes()
+	if assert.Len(t, routes, 3) {
+		assert.Equal(t, "/a", routes[0].Path)
+		assert.Equal(t, http.MethodGet, routes[1].Method)
+		assert.Equal(t, http.MethodPost, routes[2].Method)
+	}
+}
@@ -2,7 +2,11 @@ package routing
 
 import (
 	"net/http"
+	"regexp"
+	"strconv"
 	"strings"
+
+	"github.com/google/uuid"
 )
 
 var (
@@ -38,6 +42,13 @@ type (
 		ppath         string
 		pnames        []string
 		methodHandler *methodHandler
+		constraint    *paramConstraint
+	}
+	// paramConstraint restricts the values a param node matches, e.g. the
+	// "int" of "/page/:n<int>".
+	paramConstraint struct {
+		expr  string
+		match func(string) bool
 	}
 	kind          uint8
 	children      []*node
@@ -62,6 +73,35 @@ const (
 	akind
 )
 
+// paramConstraints are the predefined constraints, any other constraint is
+// compiled as regular expression which has to match the whole value.
+var paramConstraints = map[string]func(string) bool{
+	"int": func(s string) bool {
+		_, err := strconv.Atoi(s)
+		return err == nil
+	},
+	"uuid": func(s string) bool {
+		_, err := uuid.Parse(s)
+		return err == nil && len(s) == 36
+	},
+	"alpha": func(s string) bool {
+		for i := 0; i < len(s); i++ {
+			if c := s[i] | 0x20; c < 'a' || c > 'z' {
+				return false
+			}
+		}
+		return s != ""
+	},
+	"alnum": func(s string) bool {
+		for i := 0; i < len(s); i++ {
+			if c := s[i] | 0x20; (c < 'a' || c > 'z') && (s[i] < '0' || s[i] > '9') {
+				return false
+			}
+		}
+		return s != ""
+	},
+}
+
 // NewRouter returns a new Router instance.
 func NewRouter(m *Mux) *Router {
 	return &Router{
@@ -74,6 +114,13 @@ func NewRouter(m *Mux) *Router {
 }
 
 // Add registers a new route for method and path with matching handler.
+//
+// Params may be followed by a constraint in angle brackets, either one of
+// "int", "uuid", "alpha" and "alnum" or a regular expression, e.g.
+// "/users/:id<uuid>" or "/posts/:slug<[a-z0-9-]+>". Constrained params only
+// match values satisfying the constraint, so requests fall through to other
+// routes or are not found. Constrained params take precedence over the
+// unconstrained param at the same position.
 func (r *Router) Add(method, path string, h HandlerFunc) {
 	// Validate path
 	if path == "" {
@@ -82,37 +129,77 @@ func (r *Router) Add(method, path string, h HandlerFunc) {
 	if path[0] != '/' {
 		path = "/" + path
 	}
-	pnames := []string{} // Param names
-	ppath := path        // Pristine path
+	pnames := []string{}                 // Param names
+	pconstraints := []*paramConstraint{} // Param constraints
+	ppath := path                        // Pristine path
 
 	for i, l := 0, len(path); i < l; i++ {
 		if path[i] == ':' {
 			j := i + 1
 
-			r.insert(method, path[:i], nil, skind, "", nil)
-			for ; i < l && path[i] != '/'; i++ {
+			r.insert(method, path[:i], nil, skind, "", nil, pconstraints)
+			for ; i < l && path[i] != '/' && path[i] != '<'; i++ {
 			}
 
 			pnames = append(pnames, path[j:i])
+			var pc *paramConstraint
+			if i < l && path[i] == '<' {
+				k := constraintEnd(path, i)
+				pc = newParamConstraint(path[i+1 : k])
+				i = k + 1
+			}
+			pconstraints = append(pconstraints, pc)
 			path = path[:j] + path[i:]
 			i, l = j, len(path)
 
 			if i == l {
-				r.insert(method, path[:i], h, pkind, ppath, pnames)
+				r.insert(method, path[:i], h, pkind, ppath, pnames, pconstraints)
 			} else {
-				r.insert(method, path[:i], nil, pkind, "", nil)
+				r.insert(method, path[:i], nil, pkind, "", nil, pconstraints)
 			}
 		} else if path[i] == '*' {
-			r.insert(method, path[:i], nil, skind, "", nil)
+			r.insert(method, path[:i], nil, skind, "", nil, pconstraints)
 			pnames = append(pnames, "*")
-			r.insert(method, path[:i+1], h, akind, ppath, pnames)
+			r.insert(method, path[:i+1], h, akind, ppath, pnames, pconstraints)
 		}
 	}
 
-	r.insert(method, path, h, skind, ppath, pnames)
+	r.insert(method, path, h, skind, ppath, pnames, pconstraints)
+}
+
+// constraintEnd returns the index of the ">" closing the constraint starting
+// at i. Constraints end at the end of a path segment, so regular expressions
+// may contain ">" themselves.
+func constraintEnd(path string, i int) int {
+	for k := i + 1; k < len(path) && path[k] != '/'; k++ {
+		if path[k] == '>' && (k+1 == len(path) || path[k+1] == '/') {
+			return k
+		}
+	}
+	panic("routing: unterminated param constraint in " + path)
 }
 
-func (r *Router) insert(method, path string, h HandlerFunc, t kind, ppath string, pnames []string) {
+func newParamConstraint(expr string) *paramConstraint {
+	if match, ok := paramConstraints[expr]; ok {
+		return &paramConstraint{expr: expr, match: match}
+	}
+	re, err := regexp.Compile("^(?:" + expr + ")$")
+	if err != nil {
+		panic("routing: invalid param constraint " + expr + ": " + err.Error())
+	}
+	return &paramConstraint{expr: expr, match: re.MatchString}
+}
+
+// constraintAt returns the constraint of the param node starting at offset i
+// of path.
+func constraintAt(path string, i int, pconstraints []*paramConstraint) *paramConstraint {
+	if n := strings.Count(path[:i], ":"); n < len(pconstraints) {
+		return pconstraints[n]
+	}
+	return nil
+}
+
+func (r *Router) insert(method, path string, h HandlerFunc, t kind, ppath string, pnames []string, pconstraints []*paramConstraint) {
 	// Adjust max param
 	l := len(pnames)
 	if *r.mux.maxParam < l {
@@ -177,12 +264,20 @@ func (r *Router) insert(method, path string, h HandlerFunc, t kind, ppath string
 			} else {
 				// Create child node
 				n = newNode(t, search[l:], cn, nil, new(methodHandler), ppath, pnames)
+				if t == pkind {
+					n.constraint = constraintAt(path, len(path)-len(search)+l, pconstraints)
+				}
 				n.addHandler(method, h)
 				cn.addChild(n)
 			}
 		} else if l < sl {
 			search = search[l:]
-			c := cn.findChildWithLabel(search[0])
+			var c *node
+			if search[0] == ':' {
+				c = cn.findParamChild(constraintAt(path, len(path)-len(search), pconstraints))
+			} else {
+				c = cn.findChildWithLabel(search[0])
+			}
 			if c != nil {
 				// Go deeper
 				cn = c
@@ -190,6 +285,9 @@ func (r *Router) insert(method, path string, h HandlerFunc, t kind, ppath string
 			}
 			// Create child node
 			n := newNode(t, search, cn, nil, new(methodHandler), ppath, pnames)
+			if t == pkind {
+				n.constraint = constraintAt(path, len(path)-len(search), pconstraints)
+			}
 			n.addHandler(method, h)
 			cn.addChild(n)
 		} else {
@@ -250,6 +348,70 @@ func (n *node) findChildByKind(t kind) *node {
 	return nil
 }
 
+// findParamChild returns the param child with constraint pc.
+func (n *node) findParamChild(pc *paramConstraint) *node {
+	for _, c := range n.children {
+		if c.kind == pkind && (c.constraint == pc || c.constraint != nil && pc != nil && c.constraint.expr == pc.expr) {
+			return c
+		}
+	}
+	return nil
+}
+
+// matchParamChild returns the param child matching the param value at the
+// start of search which is not excluded, and the end of the value. Children
+// with a constraint satisfied by the value take precedence over the
+// unconstrained one. If other children match the value as well, the returned
+// child is stored in choice.
+func (n *node) matchParamChild(search string, excluded []*node, choice **node) (*node, int) {
+	var match, fallback *node
+	pe := -1 // Value end, found once needed
+	for _, c := range n.children {
+		if c.kind != pkind || len(excluded) > 0 && isExcluded(c, excluded) {
+			continue
+		}
+		if c.constraint == nil {
+			fallback = c
+			continue
+		}
+		if pe == -1 {
+			pe = paramEnd(search)
+		}
+		if c.constraint.match(search[:pe]) {
+			if match != nil {
+				*choice = match
+				return match, pe
+			}
+			match = c
+		}
+	}
+	if match == nil {
+		match = fallback
+	} else if fallback != nil {
+		*choice = match
+	}
+	if match != nil && pe == -1 {
+		pe = paramEnd(search)
+	}
+	return match, pe
+}
+
+func paramEnd(search string) int {
+	i := 0
+	for l := len(search); i < l && search[i] != '/'; i++ {
+	}
+	return i
+}
+
+func isExcluded(n *node, excluded []*node) bool {
+	for _, e := range excluded {
+		if e == n {
+			return true
+		}
+	}
+	return false
+}
+
 func (n *node) addHandler(method string, h HandlerFunc) {
 	switch method {
 	case http.MethodConnect:
@@ -307,12 +469,19 @@ func (n *node) findHandler(method string) HandlerFunc {
 }
 
 func (n *node) checkMethodNotAllowed() HandlerFunc {
+	if n.hasHandler() {
+		return MethodNotAllowedHandler
+	}
+	return NotFoundHandler
+}
+
+func (n *node) hasHandler() bool {
 	for _, m := range methods {
 		if h := n.findHandler(m); h != nil {
-			return MethodNotAllowedHandler
+			return true
 		}
 	}
-	return NotFoundHandler
+	return false
 }
 
 // Find lookup a handler registered for method and path. It also parses URL for path
@@ -325,6 +494,22 @@ func (n *node) checkMethodNotAllowed() HandlerFunc {
 // - Return it `Echo#ReleaseContext()`.
 func (r *Router) Find(method, path string, c Context) {
 	ctx := c.(*context)
+	var excluded []*node
+	for {
+		choice, found := r.find(method, path, ctx, excluded)
+		if found || choice == nil {
+			return
+		}
+		// Another constrained param matched the value as well, retry
+		// without the param node leading nowhere.
+		excluded = append(excluded, choice)
+	}
+}
+
+// find looks up the route for method and path skipping the excluded param
+// nodes. It returns the last param node chosen among several matching ones,
+// so Find can backtrack if no route is found.
+func (r *Router) find(method, path string, ctx *context, excluded []*node) (choice *node, found bool) {
 	ctx.path = path
 	cn := r.tree // Current node as root
 
@@ -335,6 +520,7 @@ func (r *Router) Find(method, path string, c Context) {
 		nk      kind          // Next kind
 		nn      *node         // Next node
 		ns      string        // Next search
+		pe      int           // Param value end
 		pvalues = ctx.pvalues // Use the internal slice so the interface can keep the illusion of a dynamic slice
 	)
 
@@ -376,7 +562,7 @@ func (r *Router) Find(method, path string, c Context) {
 				goto Any
 			}
 			if nn == nil { // Issue #1348
-				return // Not found
+				return choice, false // Not found
 			}
 			cn = nn
 			search = ns
@@ -401,7 +587,7 @@ func (r *Router) Find(method, path string, c Context) {
 
 	Param:
 		// Param node
-		if child = cn.findChildByKind(pkind); child != nil {
+		if child, pe = cn.matchParamChild(search, excluded, &choice); child != nil {
 			// Issue #378
 			if len(pvalues) == n {
 				continue
@@ -415,12 +601,9 @@ func (r *Router) Find(method, path string, c Context) {
 			}
 
 			cn = child
-			i, l := 0, len(search)
-			for ; i < l && search[i] != '/'; i++ {
-			}
-			pvalues[n] = search[:i]
+			pvalues[n] = search[:pe]
 			n++
-			search = search[i:]
+			search = search[pe:]
 			continue
 		}
 
@@ -440,7 +623,7 @@ func (r *Router) Find(method, path string, c Context) {
 			search = ns
 			np := nn.parent
 			// Consider param route one level up only
-			if cn = nn.findChildByKind(pkind); cn != nil {
+			if cn, _ = nn.matchParamChild(ns, excluded, &choice); cn != nil {
 				pos := strings.IndexByte(ns, '/')
 				if pos == -1 {
 					// If no slash is remaining in search string set param value
@@ -474,7 +657,7 @@ func (r *Router) Find(method, path string, c Context) {
 				break
 			}
 		}
-		return // Not found
+		return choice, false // Not found
 
 	}
 
@@ -485,11 +668,12 @@ func (r *Router) Find(method, path string, c Context) {
 	// NOTE: Slow zone...
 	if ctx.handler == nil {
 		ctx.handler = cn.checkMethodNotAllowed()
+		found = cn.hasHandler()
 
 		// Dig further for any, might have an empty value for *, e.g.
 		// serving a directory. Issue #207.
 		if cn = cn.findChildByKind(akind); cn == nil {
-			return
+			return choice, found
 		}
 		if h := cn.findHandler(method); h != nil {
 			ctx.handler = h
@@ -499,7 +683,8 @@ func (r *Router) Find(method, path string, c Context) {
 		ctx.path = cn.ppath
 		ctx.pnames = cn.pnames
 		pvalues[len(cn.pnames)-1] = ""
+		return choice, found || cn.hasHandler()
 	}
 
-	return
+	return choice, true
 }
@@ -18,6 +18,8 @@ import (
 	"path/filepath"
 	"reflect"
 	"runtime"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
@@ -52,6 +54,7 @@ type (
 		Renderer         Renderer
 		Logger           Logger
 		IPExtractor      IPExtractor
+		encoders         []encoderEntry
 	}
 
 	// MiddlewareFunc defines a function to process middleware.
@@ -107,27 +110,37 @@ const (
 	MIMETextPlainCharsetUTF8             = MIMETextPlain + "; " + charsetUTF8
 	MIMEMultipartForm                    = "multipart/form-data"
 	MIMEOctetStream                      = "application/octet-stream"
+	MIMETextEventStream                  = "text/event-stream"
 )
 
 // Headers
 const (
 	HeaderAccept              = "Accept"
+	HeaderAge                 = "Age"
 	HeaderAcceptEncoding      = "Accept-Encoding"
 	HeaderAllow               = "Allow"
 	HeaderAuthorization       = "Authorization"
 	HeaderContentDisposition  = "Content-Disposition"
 	HeaderContentEncoding     = "Content-Encoding"
 	HeaderContentLength       = "Content-Length"
+	HeaderContentRange        = "Content-Range"
 	HeaderContentType         = "Content-Type"
 	HeaderCookie              = "Cookie"
+	HeaderETag                = "ETag"
 	HeaderSetCookie           = "Set-Cookie"
 	HeaderIfModifiedSince     = "If-Modified-Since"
+	HeaderIfUnmodifiedSince   = "If-Unmodified-Since"
+	HeaderIfMatch             = "If-Match"
+	HeaderIfNoneMatch         = "If-None-Match"
+	HeaderCacheControl        = "Cache-Control"
 	HeaderLastModified        = "Last-Modified"
 	HeaderLocation            = "Location"
 	HeaderUpgrade             = "Upgrade"
+	HeaderConnection          = "Connection"
 	HeaderVary                = "Vary"
 	HeaderWWWAuthenticate     = "WWW-Authenticate"
 	HeaderXForwardedFor       = "X-Forwarded-For"
+	HeaderXForwardedHost      = "X-Forwarded-Host"
 	HeaderXForwardedProto     = "X-Forwarded-Proto"
 	HeaderXForwardedProtocol  = "X-Forwarded-Protocol"
 	HeaderXForwardedSsl       = "X-Forwarded-Ssl"
@@ -136,8 +149,16 @@ const (
 	HeaderXRealIP             = "X-Real-IP"
 	HeaderXRequestID          = "X-Request-ID"
 	HeaderXRequestedWith      = "X-Requested-With"
+	HeaderXCache              = "X-Cache"
 	HeaderServer              = "Server"
 	HeaderOrigin              = "Origin"
+	HeaderRetryAfter          = "Retry-After"
+	HeaderLastEventID         = "Last-Event-ID"
+
+	// Rate limiting
+	HeaderRateLimitLimit     = "RateLimit-Limit"
+	HeaderRateLimitRemaining = "RateLimit-Remaining"
+	HeaderRateLimitReset     = "RateLimit-Reset"
 
 	// Access control
 	HeaderAccessControlRequestMethod    = "Access-Control-Request-Method"
@@ -177,6 +198,7 @@ func New() (m *Mux) {
 	m.TLSServer.Handler = m
 	m.HTTPErrorHandler = m.DefaultHTTPErrorHandler
 	m.Binder = &DefaultBinder{}
+	m.registerDefaultEncoders()
 	m.pool.New = func() interface{} {
 		return m.NewContext(nil, nil)
 	}
@@ -199,6 +221,26 @@ func (m *Mux) NewContext(r *http.Request, w http.ResponseWriter) Context {
 	}
 }
 
+// CopyContext returns a copy of c with its own response writing to w. The
+// copy holds the request, route, path parameters and data of c and stays valid
+// after c has been released, e.g. for handlers running in another goroutine.
+func (m *Mux) CopyContext(c Context, w http.ResponseWriter) Context {
+	cc := m.NewContext(c.Request(), w).(*context)
+	cc.path = c.Path()
+	cc.pnames = append([]string(nil), c.ParamNames()...)
+	copy(cc.pvalues, c.ParamValues())
+	cc.handler = c.Handler()
+	if ctx, ok := c.(*context); ok {
+		ctx.lock.RLock()
+		for k, v := range ctx.store {
+			cc.store[k] = v
+		}
+		ctx.lock.RUnlock()
+		cc.logger = ctx.logger
+	}
+	return cc
+}
+
 // Router returns the default router.
 func (m *Mux) Router() *Router {
 	return m.router
@@ -275,8 +317,8 @@ func (m *Mux) TRACE(path string, h HandlerFunc, mf ...MiddlewareFunc) *Route {
 
 // Any registers a new route for all HTTP methods and path with matching handler
 // in the router with optional route-level middleware.
-func (m *Mux) Any(path string, handler HandlerFunc, middleware ...MiddlewareFunc) []*Route {
-	routes := make([]*Route, len(methods))
+func (m *Mux) Any(path string, handler HandlerFunc, middleware ...MiddlewareFunc) Routes {
+	routes := make(Routes, len(methods))
 	for i, met := range methods {
 		routes[i] = m.Add(met, path, handler, middleware...)
 	}
@@ -285,8 +327,8 @@ func (m *Mux) Any(path string, handler HandlerFunc, middleware ...MiddlewareFunc
 
 // Match registers a new route for multiple HTTP methods and path with matching
 // handler in the router with optional route-level middleware.
-func (m *Mux) Match(methods []string, path string, handler HandlerFunc, middleware ...MiddlewareFunc) []*Route {
-	routes := make([]*Route, len(methods))
+func (m *Mux) Match(methods []string, path string, handler HandlerFunc, middleware ...MiddlewareFunc) Routes {
+	routes := make(Routes, len(methods))
 	for i, met := range methods {
 		routes[i] = m.Add(met, path, handler, middleware...)
 	}
@@ -343,6 +385,9 @@ func (m *Mux) File(path, file string, mf ...MiddlewareFunc) *Route {
 }
 
 func (m *Mux) add(host, method, path string, handler HandlerFunc, middleware ...MiddlewareFunc) *Route {
+	if path == "" || path[0] != '/' {
+		path = "/" + path
+	}
 	name := handlerName(handler)
 	router := m.findRouter(host)
 	router.Add(method, path, func(c Context) error {
@@ -354,6 +399,7 @@ func (m *Mux) add(host, method, path string, handler HandlerFunc, middleware ...
 		Path:   path,
 		Name:   name,
 	}
+	router.routes[method+path] = r
 	m.router.routes[method+path] = r
 	return r
 }
@@ -390,8 +436,19 @@ func (m *Mux) URL(h HandlerFunc, params ...interface{}) string {
 	return m.URI(h, params...)
 }
 
-// Reverse generates an URL from route name and provided parameters.
+// Reverse generates an URL from route name and provided parameters. The
+// parameters replace the path params and the "*" wildcard of the route in
+// order and are escaped, the segments of the wildcard separately. A trailing
+// `url.Values` parameter is appended as query string.
 func (m *Mux) Reverse(name string, params ...interface{}) string {
+	var query url.Values
+	if ln := len(params); ln > 0 {
+		if q, ok := params[ln-1].(url.Values); ok {
+			query = q
+			params = params[:ln-1]
+		}
+	}
+
 	uri := new(bytes.Buffer)
 	ln := len(params)
 	n := 0
@@ -401,19 +458,46 @@ func (m *Mux) Reverse(name string, params ...interface{}) string {
 				if r.Path[i] == ':' && n < ln {
 					for ; i < l && r.Path[i] != '/'; i++ {
 					}
-					uri.WriteString(fmt.Sprintf("%v", params[n]))
+					uri.WriteString(url.PathEscape(fmt.Sprintf("%v", params[n])))
+					n++
+				} else if r.Path[i] == '*' && n < ln {
+					segments := strings.Split(fmt.Sprintf("%v", params[n]), "/")
+					for j, s := range segments {
+						segments[j] = url.PathEscape(s)
+					}
+					uri.WriteString(strings.Join(segments, "/"))
 					n++
+					continue
 				}
 				if i < l {
 					uri.WriteByte(r.Path[i])
 				}
 			}
+			if len(query) > 0 {
+				uri.WriteByte('?')
+				uri.WriteString(query.Encode())
+			}
 			break
 		}
 	}
 	return uri.String()
 }
 
+// Routes returns the registered routes ordered by path and method.
+func (m *Mux) Routes() []*Route {
+	routes := make([]*Route, 0, len(m.router.routes))
+	for _, r := range m.router.routes {
+		routes = append(routes, r)
+	}
+	sort.Slice(routes, func(i, j int) bool {
+		if routes[i].Path != routes[j].Path {
+			return routes[i].Path < routes[j].Path
+		}
+		return routes[i].Method < routes[j].Method
+	})
+	return routes
+}
+
 // ServeHTTP implements `http.Handler` interface, which serves HTTP requests.
 func (m *Mux) ServeHTTP(res http.ResponseWriter, req *http.Request) {
 	// Acquire context
@@ -427,6 +511,8 @@ func (m *Mux) ServeHTTP(res http.ResponseWriter, req *http.Request) {
 		h = applyMiddleware(h, m.middleware...)
 	} else {
 		h = func(c Context) error {
+			// Pre middleware may have changed or replaced the request.
+			req := c.Request()
 			m.findRouter(req.Host).Find(req.Method, req.URL.EscapedPath(), c)
 			h := c.Handler()
 			h = applyMiddleware(h, m.middleware...)
@@ -547,15 +633,23 @@ func WrapHandler(h http.Handler) HandlerFunc {
 }
 
 // WrapMiddleware wraps `func(http.Handler) http.Handler` into `echo.MiddlewareFunc`
+//
+// Errors of the chain are handled by the HTTPErrorHandler within the wrapped
+// middleware, so that error responses pass through it like all other
+// responses, e.g. through middleware buffering the response.
 func WrapMiddleware(m func(http.Handler) http.Handler) MiddlewareFunc {
 	return func(next HandlerFunc) HandlerFunc {
-		return func(c Context) (err error) {
+		return func(c Context) error {
+			res := c.Response()
 			m(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 				c.SetRequest(r)
 				c.SetResponse(NewResponse(w, c.Mux()))
-				err = next(c)
-			})).ServeHTTP(c.Response(), c.Request())
-			return
+				if err := next(c); err != nil {
+					c.Error(err)
+				}
+			})).ServeHTTP(res, c.Request())
+			c.SetResponse(res)
+			return nil
 		}
 	}
 }
@@ -1,6 +1,12 @@
 package routing
 
-import "net/http/httptest"
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
 
 type (
 	user struct {
@@ -35,3 +41,49 @@ func request(method, path string, m *Mux) (int, string) {
 	m.ServeHTTP(rec, req)
 	return rec.Code, rec.Body.String()
 }
+
+func TestWrapMiddlewareError(t *testing.T) {
+	// buffer holds back the response until the chain has returned
+	buffer := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rec := httptest.NewRecorder()
+			next.ServeHTTP(rec, r)
+			for k, v := range rec.Header() {
+				w.Header()[k] = v
+			}
+			w.WriteHeader(rec.Code)
+			w.Write(rec.Body.Bytes())
+		})
+	}
+
+	m := New()
+	m.Use(WrapMiddleware(buffer))
+	m.GET("/", func(c Context) error {
+		return ErrForbidden
+	})
+	code, body := request(http.MethodGet, "/", m)
+	assert.Equal(t, http.StatusForbidden, code)
+	assert.Equal(t, `{"message":"Forbidden"}`+"\n", body)
+}
+
+func TestMuxCopyContext(t *testing.T) {
+	m := New()
+	req := httptest.NewRequest(http.MethodGet, "/users/1", nil)
+	c := m.NewContext(req, httptest.NewRecorder())
+	c.SetPath("/users/:id")
+	c.SetParamNames("id")
+	c.SetParamValues("1")
+	c.Set("user", "jon")
+
+	rec := httptest.NewRecorder()
+	cc := m.CopyContext(c, rec)
+	c.Reset(httptest.NewRequest(http.MethodGet, "/", nil), httptest.NewRecorder())
+	assert.Equal(t, req, cc.Request())
+	assert.Equal(t, "/users/:id", cc.Path())
+	assert.Equal(t, "1", cc.Param("id"))
+	assert.Equal(t, "jon", cc.Get("user"))
+
+	assert.NoError(t, cc.String(http.StatusOK, "test"))
+	assert.Equal(t, "test", rec.Body.String())
+	assert.False(t, c.Response().Committed)
+}
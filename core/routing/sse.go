@@ -0,0 +1,280 @@
+package routing
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type (
+	// Event is a server-sent event, see `Context#SSE()`.
+	Event struct {
+		// ID is sent back by reconnecting clients in the Last-Event-ID
+		// header to receive the events they missed.
+		ID string
+
+		// Name is the type of the event, clients default to "message".
+		Name string
+
+		// Data is sent as it is if it's a string or []byte, other values are
+		// encoded as JSON.
+		Data interface{}
+
+		// Retry sets the time clients wait before reconnecting.
+		Retry time.Duration
+	}
+
+	// EventStream writes server-sent events. Events and comments may be sent
+	// concurrently.
+	EventStream struct {
+		mu        sync.Mutex
+		response  *Response
+		lastID    string
+		heartbeat *time.Ticker
+		closed    bool
+		done      chan struct{}
+	}
+
+	// EventBacklog keeps sent events to replay them to reconnecting clients,
+	// see `EventStream#Replay()`.
+	EventBacklog interface {
+		// Add stores e and assigns an ID if it has none.
+		Add(e *Event) error
+
+		// Since returns the events added after the event with id. If id is
+		// unknown, e.g. because the event was dropped, all retained events
+		// are returned.
+		Since(id string) ([]Event, error)
+	}
+
+	// MemoryEventBacklog keeps the most recent events in memory.
+	MemoryEventBacklog struct {
+		mu     sync.Mutex
+		events []Event
+		size   int
+		seq    uint64
+	}
+)
+
+// DefaultSSEHeartbeat is the interval of heartbeats of event streams, see
+// `EventStream#SetHeartbeat()`.
+var DefaultSSEHeartbeat = 15 * time.Second
+
+// SSE implements `Context#SSE()`.
+func (c *context) SSE(fn func(s *EventStream) error) error {
+	header := c.response.Header()
+	header.Set(HeaderContentType, MIMETextEventStream)
+	header.Set(HeaderCacheControl, "no-cache")
+	// Disables buffering of reverse proxies like nginx.
+	header.Set("X-Accel-Buffering", "no")
+	c.response.WriteHeader(http.StatusOK)
+	flush(c.response)
+
+	s := &EventStream{
+		response:  c.response,
+		lastID:    c.request.Header.Get(HeaderLastEventID),
+		heartbeat: time.NewTicker(DefaultSSEHeartbeat),
+		done:      make(chan struct{}),
+	}
+	ctx := c.request.Context()
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				s.Close()
+				return
+			case <-s.done:
+				return
+			case <-s.heartbeat.C:
+				s.Comment("")
+			}
+		}
+	}()
+	// The context and response are reused once the handler returns, so the
+	// heartbeats must stop with fn.
+	defer s.Close()
+	return fn(s)
+}
+
+// LastEventID returns the ID of the last event sent, initially the
+// Last-Event-ID header of the request.
+func (s *EventStream) LastEventID() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lastID
+}
+
+// Send writes e and flushes it to the client. It returns
+// ErrEventStreamClosed once the client disconnected.
+func (s *EventStream) Send(e Event) error {
+	if strings.ContainsAny(e.ID, "\r\n\x00") || strings.ContainsAny(e.Name, "\r\n") {
+		return errors.New("event id and name must not contain line breaks")
+	}
+	var data string
+	switch d := e.Data.(type) {
+	case nil:
+	case string:
+		data = d
+	case []byte:
+		data = string(d)
+	default:
+		b, err := json.Marshal(d)
+		if err != nil {
+			return err
+		}
+		data = string(b)
+	}
+
+	var b strings.Builder
+	if e.ID != "" {
+		b.WriteString("id: " + e.ID + "\n")
+	}
+	if e.Name != "" {
+		b.WriteString("event: " + e.Name + "\n")
+	}
+	if e.Retry > 0 {
+		b.WriteString("retry: " + strconv.FormatInt(int64(e.Retry/time.Millisecond), 10) + "\n")
+	}
+	if e.Data != nil {
+		data = strings.Replace(strings.Replace(data, "\r\n", "\n", -1), "\r", "\n", -1)
+		for _, line := range strings.Split(data, "\n") {
+			b.WriteString("data: " + line + "\n")
+		}
+	}
+	b.WriteString("\n")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.write(b.String()); err != nil {
+		return err
+	}
+	if e.ID != "" {
+		s.lastID = e.ID
+	}
+	return nil
+}
+
+// Comment writes a comment, which is ignored by clients but keeps the
+// connection alive.
+func (s *EventStream) Comment(text string) error {
+	var b strings.Builder
+	for _, line := range strings.Split(strings.Replace(text, "\r", "", -1), "\n") {
+		b.WriteString(":" + line + "\n")
+	}
+	b.WriteString("\n")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.write(b.String())
+}
+
+// Replay sends the events of backlog the client missed since the
+// Last-Event-ID of the request. Nothing is sent to clients connecting for
+// the first time.
+func (s *EventStream) Replay(backlog EventBacklog) error {
+	id := s.LastEventID()
+	if id == "" {
+		return nil
+	}
+	events, err := backlog.Since(id)
+	if err != nil {
+		return err
+	}
+	for _, e := range events {
+		if err := s.Send(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetHeartbeat changes the interval of heartbeat comments, zero disables
+// them.
+func (s *EventStream) SetHeartbeat(interval time.Duration) {
+	if interval <= 0 {
+		s.heartbeat.Stop()
+		return
+	}
+	s.heartbeat.Reset(interval)
+}
+
+// Done returns a channel which is closed once the client disconnected or
+// the stream is closed.
+func (s *EventStream) Done() <-chan struct{} {
+	return s.done
+}
+
+// Close stops the stream before fn passed to `Context#SSE()` returns, which
+// closes it anyway.
+func (s *EventStream) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+	s.closed = true
+	s.heartbeat.Stop()
+	close(s.done)
+}
+
+// write writes and flushes text, s.mu has to be held.
+func (s *EventStream) write(text string) error {
+	if s.closed {
+		return ErrEventStreamClosed
+	}
+	if _, err := s.response.Write([]byte(text)); err != nil {
+		s.closed = true
+		s.heartbeat.Stop()
+		close(s.done)
+		return err
+	}
+	flush(s.response)
+	return nil
+}
+
+// flush flushes r if the underlying writer supports it.
+func flush(r *Response) {
+	if _, ok := r.Writer.(http.Flusher); ok {
+		r.Flush()
+	}
+}
+
+// NewMemoryEventBacklog returns a backlog keeping the last size events.
+// Events without ID are numbered.
+func NewMemoryEventBacklog(size int) *MemoryEventBacklog {
+	if size <= 0 {
+		panic("routing: event backlog size must be positive")
+	}
+	return &MemoryEventBacklog{size: size}
+}
+
+// Add implements `EventBacklog#Add()`.
+func (b *MemoryEventBacklog) Add(e *Event) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.seq++
+	if e.ID == "" {
+		e.ID = strconv.FormatUint(b.seq, 10)
+	}
+	if len(b.events) == b.size {
+		b.events = b.events[1:]
+	}
+	b.events = append(b.events, *e)
+	return nil
+}
+
+// Since implements `EventBacklog#Since()`.
+func (b *MemoryEventBacklog) Since(id string) ([]Event, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for i := len(b.events) - 1; i >= 0; i-- {
+		if b.events[i].ID == id {
+			return append([]Event(nil), b.events[i+1:]...), nil
+		}
+	}
+	return append([]Event(nil), b.events...), nil
+}
@@ -0,0 +1,163 @@
+package routing
+
+import (
+	"bufio"
+	stdcontext "context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContextSSE(t *testing.T) {
+	assert := assert.New(t)
+	e := New()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	var s *EventStream
+	err := c.SSE(func(stream *EventStream) error {
+		s = stream
+		assert.NoError(s.Send(Event{ID: "1", Name: "progress", Data: map[string]int{"percent": 50}}))
+		assert.NoError(s.Send(Event{Data: "line 1\r\nline 2\rline 3", Retry: 3 * time.Second}))
+		assert.NoError(s.Send(Event{Data: []byte("bytes")}))
+		assert.NoError(s.Comment("ping"))
+		assert.Error(s.Send(Event{ID: "a\nb"}))
+		assert.Equal("1", s.LastEventID())
+		return ErrForbidden
+	})
+	assert.Equal(ErrForbidden, err)
+	// The stream is closed once fn returns.
+	assert.Equal(ErrEventStreamClosed, s.Send(Event{Data: "late"}))
+
+	assert.Equal(http.StatusOK, rec.Code)
+	assert.Equal(MIMETextEventStream, rec.Header().Get(HeaderContentType))
+	assert.Equal("no-cache", rec.Header().Get(HeaderCacheControl))
+	assert.True(rec.Flushed)
+	assert.Equal(`id: 1
+event: progress
+data: {"percent":50}
+
+retry: 3000
+data: line 1
+data: line 2
+data: line 3
+
+data: bytes
+
+:ping
+
+`, rec.Body.String())
+
+	select {
+	case <-s.Done():
+	default:
+		t.Error("stream is not done")
+	}
+}
+
+func TestContextSSEDisconnect(t *testing.T) {
+	e := New()
+	reqCtx, cancel := stdcontext.WithCancel(stdcontext.Background())
+	req := httptest.NewRequest(http.MethodGet, "/", nil).WithContext(reqCtx)
+	c := e.NewContext(req, httptest.NewRecorder())
+
+	c.SSE(func(s *EventStream) error {
+		cancel()
+		select {
+		case <-s.Done():
+		case <-time.After(time.Second):
+			t.Fatal("stream not done after disconnect")
+		}
+		assert.Equal(t, ErrEventStreamClosed, s.Send(Event{Data: "x"}))
+		return nil
+	})
+}
+
+func TestContextSSEStopsWithHandler(t *testing.T) {
+	defer func(d time.Duration) { DefaultSSEHeartbeat = d }(DefaultSSEHeartbeat)
+	DefaultSSEHeartbeat = time.Millisecond
+
+	e := New()
+	rec := httptest.NewRecorder()
+	c := e.NewContext(httptest.NewRequest(http.MethodGet, "/", nil), rec)
+	c.SSE(func(s *EventStream) error {
+		time.Sleep(10 * time.Millisecond)
+		return nil
+	})
+	// No heartbeats are written into the response after the handler
+	// returned, e.g. once it's reused for another request.
+	n := rec.Body.Len()
+	time.Sleep(10 * time.Millisecond)
+	assert.Equal(t, n, rec.Body.Len())
+}
+
+func TestContextSSEHeartbeatAndReplay(t *testing.T) {
+	backlog := NewMemoryEventBacklog(3)
+	for _, data := range []string{"a", "b", "c", "d"} {
+		assert.NoError(t, backlog.Add(&Event{Data: data}))
+	}
+
+	e := New()
+	e.GET("/events", func(c Context) error {
+		return c.SSE(func(s *EventStream) error {
+			s.SetHeartbeat(10 * time.Millisecond)
+			if err := s.Replay(backlog); err != nil {
+				return err
+			}
+			<-s.Done()
+			return nil
+		})
+	})
+	srv := httptest.NewServer(e)
+	defer srv.Close()
+
+	read := func(lastEventID string, lines int) []string {
+		req, _ := http.NewRequest(http.MethodGet, srv.URL+"/events", nil)
+		if lastEventID != "" {
+			req.Header.Set(HeaderLastEventID, lastEventID)
+		}
+		res, err := http.DefaultClient.Do(req)
+		if !assert.NoError(t, err) {
+			return nil
+		}
+		defer res.Body.Close()
+		sc := bufio.NewScanner(res.Body)
+		var got []string
+		for len(got) < lines && sc.Scan() {
+			if sc.Text() != "" {
+				got = append(got, sc.Text())
+			}
+		}
+		return got
+	}
+
+	// Missed events are replayed, followed by heartbeats.
+	assert.Equal(t, []string{"id: 3", "data: c", "id: 4", "data: d", ":"}, read("2", 5))
+	// Dropped events can't be replayed, all retained events are sent.
+	assert.Equal(t, []string{"id: 2", "data: b"}, read("1", 2))
+	// First connections receive heartbeats only.
+	assert.Equal(t, []string{":"}, read("", 1))
+}
+
+func TestMemoryEventBacklog(t *testing.T) {
+	b := NewMemoryEventBacklog(2)
+	e := &Event{ID: "custom", Data: "a"}
+	assert.NoError(t, b.Add(e))
+	assert.Equal(t, "custom", e.ID)
+	assert.NoError(t, b.Add(&Event{Data: "b"}))
+
+	events, err := b.Since("custom")
+	assert.NoError(t, err)
+	assert.Equal(t, []Event{{ID: "2", Data: "b"}}, events)
+
+	events, _ = b.Since("2")
+	assert.Empty(t, events)
+
+	assert.Panics(t, func() {
+		NewMemoryEventBacklog(0)
+	})
+}
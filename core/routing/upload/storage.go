@@ -0,0 +1,160 @@
+package upload
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+type (
+	// Storage stores the content of files by key.
+	Storage interface {
+		// Save stores the content read from r under key. Errors reading r
+		// are returned, the content may then be incomplete.
+		Save(key string, r io.Reader) error
+
+		// Open returns the content stored under key, or ErrNotFound.
+		Open(key string) (io.ReadCloser, error)
+
+		// Delete removes the content stored under key. Deleting keys which
+		// don't exist is not an error.
+		Delete(key string) error
+	}
+
+	// LocalStorage stores files in a directory of the local filesystem.
+	LocalStorage struct {
+		dir string
+	}
+
+	// MemoryStorage keeps files in memory, e.g. for tests.
+	MemoryStorage struct {
+		mu    sync.RWMutex
+		files map[string][]byte
+	}
+)
+
+var (
+	// ErrNotFound is returned by `Storage#Open()` for unknown keys.
+	ErrNotFound = errors.New("upload: file not found")
+
+	// ErrInvalidKey is returned for keys leaving the directory of a
+	// LocalStorage.
+	ErrInvalidKey = errors.New("upload: invalid key")
+)
+
+// NewLocalStorage returns a storage keeping files in dir. Keys may contain
+// slashes to store files in subdirectories.
+func NewLocalStorage(dir string) *LocalStorage {
+	return &LocalStorage{dir: dir}
+}
+
+// Save implements `Storage#Save()`. The content is written to a temporary
+// file which is renamed once complete, so incomplete files are never visible.
+func (s *LocalStorage) Save(key string, r io.Reader) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(tmp, r); err == nil {
+		err = tmp.Close()
+	} else {
+		tmp.Close()
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
+
+// Open implements `Storage#Open()`.
+func (s *LocalStorage) Open(key string) (io.ReadCloser, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+	return f, err
+}
+
+// Delete implements `Storage#Delete()`.
+func (s *LocalStorage) Delete(key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// path returns the path of the file of key, which has to stay inside the
+// directory.
+func (s *LocalStorage) path(key string) (string, error) {
+	clean := filepath.Clean("/" + filepath.FromSlash(key))
+	if key == "" || clean == string(filepath.Separator) || strings.Contains(key, "..") {
+		return "", ErrInvalidKey
+	}
+	return filepath.Join(s.dir, clean), nil
+}
+
+// NewMemoryStorage returns an empty storage keeping files in memory.
+func NewMemoryStorage() *MemoryStorage {
+	return &MemoryStorage{files: map[string][]byte{}}
+}
+
+// Save implements `Storage#Save()`. Nothing is stored if reading r fails.
+func (s *MemoryStorage) Save(key string, r io.Reader) error {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.files[key] = b
+	s.mu.Unlock()
+	return nil
+}
+
+// Open implements `Storage#Open()`.
+func (s *MemoryStorage) Open(key string) (io.ReadCloser, error) {
+	s.mu.RLock()
+	b, ok := s.files[key]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return ioutil.NopCloser(bytes.NewReader(b)), nil
+}
+
+// Delete implements `Storage#Delete()`.
+func (s *MemoryStorage) Delete(key string) error {
+	s.mu.Lock()
+	delete(s.files, key)
+	s.mu.Unlock()
+	return nil
+}
+
+// Len returns the number of stored files.
+func (s *MemoryStorage) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.files)
+}
@@ -0,0 +1,70 @@
+package upload
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) {
+	return 0, errors.New("read failed")
+}
+
+func testStorage(t *testing.T, s Storage) {
+	assert := assert.New(t)
+
+	if assert.NoError(s.Save("a/b.txt", strings.NewReader("content"))) {
+		r, err := s.Open("a/b.txt")
+		if assert.NoError(err) {
+			b, _ := ioutil.ReadAll(r)
+			r.Close()
+			assert.Equal("content", string(b))
+		}
+	}
+
+	assert.Error(s.Save("c.txt", failingReader{}))
+	_, err := s.Open("c.txt")
+	assert.Equal(ErrNotFound, err)
+
+	assert.NoError(s.Delete("a/b.txt"))
+	assert.NoError(s.Delete("a/b.txt"))
+	_, err = s.Open("a/b.txt")
+	assert.Equal(ErrNotFound, err)
+}
+
+func TestLocalStorage(t *testing.T) {
+	dir, err := ioutil.TempDir("", "upload")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	s := NewLocalStorage(dir)
+	testStorage(t, s)
+
+	for _, key := range []string{"", "/", "../x", "a/../../x"} {
+		assert.Equal(t, ErrInvalidKey, s.Save(key, strings.NewReader("x")), key)
+	}
+	// No temporary files are left behind.
+	var files []string
+	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if strings.HasPrefix(info.Name(), ".upload-") {
+			files = append(files, path)
+		}
+		return err
+	})
+	assert.Empty(t, files)
+}
+
+func TestMemoryStorage(t *testing.T) {
+	s := NewMemoryStorage()
+	testStorage(t, s)
+	assert.Equal(t, 0, s.Len())
+}
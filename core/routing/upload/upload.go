@@ -0,0 +1,393 @@
+// Package upload streams the files of multipart requests into a Storage. Parts
+// are read one by one and written to the storage as they arrive, nothing is
+// buffered in memory or on disk first. Size, count and detected media types
+// are limited per field, size and count also across the form, and the
+// metadata of the stored files is returned ready for persisting, e.g.
+// embedded in a model:
+//
+//	type Attachment struct {
+//		framework.Model
+//		upload.File
+//	}
+package upload
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"goplugins/core/routing"
+	"hash"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// sniffLen is the number of bytes used to detect the media type of files, see
+// http.DetectContentType.
+const sniffLen = 512
+
+type (
+	// Config defines the limits of uploads and where files are stored.
+	Config struct {
+		// Storage the files are saved in.
+		// Required.
+		Storage Storage
+
+		// MaxFileSize is the maximum size of a file in bytes.
+		// Optional. Default value 10MB.
+		MaxFileSize int64
+
+		// MaxFiles is the maximum number of files per field.
+		// Optional. Default value 10.
+		MaxFiles int
+
+		// MaxTotalFiles is the maximum number of files of all fields.
+		// Optional. Default value 20.
+		MaxTotalFiles int
+
+		// MaxTotalSize is the maximum size of the files of all fields in
+		// bytes.
+		// Optional. Default value 50MB.
+		MaxTotalSize int64
+
+		// AllowedTypes are the allowed detected media types of files, e.g.
+		// "image/png" or "image/*".
+		// Optional. Default value allows all types.
+		AllowedTypes []string
+
+		// Fields overrides the limits of single fields by name.
+		// Optional.
+		Fields map[string]FieldConfig
+
+		// MaxValueSize is the maximum size of a form value which isn't a file
+		// in bytes. Values are kept in memory.
+		// Optional. Default value 1MB.
+		MaxValueSize int64
+
+		// MaxValues is the maximum number of form values which aren't files.
+		// Optional. Default value 100.
+		MaxValues int
+
+		// Key returns the storage key of a file. Filename and detected
+		// ContentType are set.
+		// Optional. Default value is a random UUID with the extension of the
+		// filename.
+		Key func(f *File) string
+	}
+
+	// FieldConfig defines the limits of a field, zero values use the limits
+	// of the Config.
+	FieldConfig struct {
+		MaxFileSize  int64
+		MaxFiles     int
+		AllowedTypes []string
+	}
+
+	// Uploader parses multipart requests and saves their files.
+	Uploader struct {
+		config Config
+	}
+
+	// File is the metadata of a stored file.
+	File struct {
+		Field       string `json:"field"`
+		Filename    string `json:"filename"`
+		Key         string `json:"key" gorm:"index"`
+		Size        int64  `json:"size"`
+		SHA256      string `json:"sha256"`
+		ContentType string `json:"contentType"`
+	}
+
+	// Form is a parsed multipart form.
+	Form struct {
+		Values url.Values
+		Files  map[string][]*File
+	}
+
+	// limitedReader returns errTooLarge once more than n bytes are read.
+	limitedReader struct {
+		r io.Reader
+		n int64
+	}
+
+	// countingReader counts and hashes the bytes read and keeps read errors,
+	// so they can be told apart from errors of the storage.
+	countingReader struct {
+		r   io.Reader
+		h   hash.Hash
+		n   int64
+		err error
+	}
+)
+
+var (
+	// DefaultConfig is the default upload config.
+	DefaultConfig = Config{
+		MaxFileSize:   10 << 20,
+		MaxFiles:      10,
+		MaxTotalFiles: 20,
+		MaxTotalSize:  50 << 20,
+		MaxValueSize:  1 << 20,
+		MaxValues:     100,
+		Key:           RandomKey,
+	}
+
+	errTooLarge = errors.New("upload: too large")
+)
+
+// New returns an Uploader with config, limits which aren't set use the
+// DefaultConfig. It panics without storage.
+func New(config Config) *Uploader {
+	if config.Storage == nil {
+		panic("routing: upload requires storage")
+	}
+	if config.MaxFileSize == 0 {
+		config.MaxFileSize = DefaultConfig.MaxFileSize
+	}
+	if config.MaxFiles == 0 {
+		config.MaxFiles = DefaultConfig.MaxFiles
+	}
+	if config.MaxTotalFiles == 0 {
+		config.MaxTotalFiles = DefaultConfig.MaxTotalFiles
+	}
+	if config.MaxTotalSize == 0 {
+		config.MaxTotalSize = DefaultConfig.MaxTotalSize
+	}
+	if config.MaxValueSize == 0 {
+		config.MaxValueSize = DefaultConfig.MaxValueSize
+	}
+	if config.MaxValues == 0 {
+		config.MaxValues = DefaultConfig.MaxValues
+	}
+	if config.Key == nil {
+		config.Key = DefaultConfig.Key
+	}
+	return &Uploader{config: config}
+}
+
+// RandomKey returns a random UUID with the lower case extension of the
+// filename, e.g. "0b7c5b3e-....png".
+func RandomKey(f *File) string {
+	return uuid.New().String() + strings.ToLower(filepath.Ext(f.Filename))
+}
+
+// Parse reads the multipart body of the request and saves its files. Requests
+// which aren't multipart are rejected with routing.ErrUnsupportedMediaType,
+// files above the limits with 413 and files of types which aren't allowed
+// with 415. Files saved before an error are deleted again.
+func (u *Uploader) Parse(c routing.Context) (*Form, error) {
+	mr, err := c.Request().MultipartReader()
+	if err != nil {
+		return nil, routing.ErrUnsupportedMediaType
+	}
+
+	form := &Form{Values: url.Values{}, Files: map[string][]*File{}}
+	values, files, size := 0, 0, int64(0)
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			return form, nil
+		}
+		if err != nil {
+			u.Discard(form)
+			return nil, readError(err)
+		}
+
+		name := part.FormName()
+		if name == "" {
+			part.Close()
+			continue
+		}
+		if part.FileName() == "" {
+			values++
+			if values > u.config.MaxValues {
+				u.Discard(form)
+				return nil, routing.NewHTTPError(http.StatusRequestEntityTooLarge, "too many form values")
+			}
+			value, err := readValue(part, u.config.MaxValueSize)
+			part.Close()
+			if err == errTooLarge {
+				u.Discard(form)
+				return nil, routing.NewHTTPError(http.StatusRequestEntityTooLarge, fmt.Sprintf("form value %s exceeds %d bytes", name, u.config.MaxValueSize))
+			} else if err != nil {
+				u.Discard(form)
+				return nil, readError(err)
+			}
+			form.Values.Add(name, value)
+			continue
+		}
+
+		files++
+		if files > u.config.MaxTotalFiles {
+			part.Close()
+			u.Discard(form)
+			return nil, routing.NewHTTPError(http.StatusRequestEntityTooLarge, fmt.Sprintf("form exceeds %d files", u.config.MaxTotalFiles))
+		}
+		f, err := u.save(part, name, len(form.Files[name]), u.config.MaxTotalSize-size)
+		part.Close()
+		if err != nil {
+			u.Discard(form)
+			return nil, err
+		}
+		size += f.Size
+		form.Files[name] = append(form.Files[name], f)
+	}
+}
+
+// Discard deletes the files of form, e.g. if the model referencing them
+// can't be saved.
+func (u *Uploader) Discard(form *Form) error {
+	var first error
+	for _, files := range form.Files {
+		for _, f := range files {
+			if err := u.config.Storage.Delete(f.Key); err != nil && first == nil {
+				first = err
+			}
+		}
+	}
+	return first
+}
+
+// save streams the file of part into the storage, count is the number of
+// files of the field saved before and remaining the size left of the total.
+func (u *Uploader) save(part *multipart.Part, field string, count int, remaining int64) (*File, error) {
+	maxSize, maxFiles, allowed := u.limits(field)
+	if count >= maxFiles {
+		return nil, routing.NewHTTPError(http.StatusRequestEntityTooLarge, fmt.Sprintf("field %s exceeds %d files", field, maxFiles))
+	}
+	tooLarge := fmt.Sprintf("file of field %s exceeds %d bytes", field, maxSize)
+	if remaining < maxSize {
+		maxSize = remaining
+		tooLarge = fmt.Sprintf("files exceed %d bytes", u.config.MaxTotalSize)
+	}
+
+	head := make([]byte, sniffLen)
+	n, err := io.ReadFull(part, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return nil, readError(err)
+	}
+	head = head[:n]
+
+	f := &File{
+		Field:       field,
+		Filename:    filepath.Base(strings.Replace(part.FileName(), "\\", "/", -1)),
+		ContentType: http.DetectContentType(head),
+	}
+	if !typeAllowed(allowed, f.ContentType) {
+		return nil, routing.NewHTTPError(http.StatusUnsupportedMediaType, fmt.Sprintf("type %s of field %s is not allowed", f.ContentType, field))
+	}
+	f.Key = u.config.Key(f)
+
+	h := sha256.New()
+	r := &countingReader{
+		r: &limitedReader{r: io.MultiReader(bytes.NewReader(head), part), n: maxSize},
+		h: h,
+	}
+	if err := u.config.Storage.Save(f.Key, r); err != nil {
+		u.config.Storage.Delete(f.Key)
+		if r.err == errTooLarge {
+			return nil, routing.NewHTTPError(http.StatusRequestEntityTooLarge, tooLarge)
+		} else if r.err != nil {
+			return nil, readError(r.err)
+		}
+		return nil, routing.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
+	}
+	f.Size = r.n
+	f.SHA256 = hex.EncodeToString(h.Sum(nil))
+	return f, nil
+}
+
+// limits returns the limits of field.
+func (u *Uploader) limits(field string) (maxSize int64, maxFiles int, allowed []string) {
+	maxSize, maxFiles, allowed = u.config.MaxFileSize, u.config.MaxFiles, u.config.AllowedTypes
+	if fc, ok := u.config.Fields[field]; ok {
+		if fc.MaxFileSize > 0 {
+			maxSize = fc.MaxFileSize
+		}
+		if fc.MaxFiles > 0 {
+			maxFiles = fc.MaxFiles
+		}
+		if fc.AllowedTypes != nil {
+			allowed = fc.AllowedTypes
+		}
+	}
+	return
+}
+
+// File returns the first file of field, or nil.
+func (f *Form) File(field string) *File {
+	if files := f.Files[field]; len(files) > 0 {
+		return files[0]
+	}
+	return nil
+}
+
+func (r *limitedReader) Read(p []byte) (int, error) {
+	if r.n < 0 {
+		return 0, errTooLarge
+	}
+	// Read one byte more than allowed to detect larger files.
+	if int64(len(p)) > r.n+1 {
+		p = p[:r.n+1]
+	}
+	n, err := r.r.Read(p)
+	r.n -= int64(n)
+	if r.n < 0 {
+		return n, errTooLarge
+	}
+	return n, err
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.n += int64(n)
+	r.h.Write(p[:n])
+	if err != nil && err != io.EOF {
+		r.err = err
+	}
+	return n, err
+}
+
+func readValue(r io.Reader, max int64) (string, error) {
+	b := new(strings.Builder)
+	if _, err := io.Copy(b, &limitedReader{r: r, n: max}); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// readError passes errors raised while reading the body, e.g. by the
+// BodyLimit middleware, and rejects malformed bodies.
+func readError(err error) error {
+	if he, ok := err.(*routing.HTTPError); ok {
+		return he
+	}
+	return routing.NewHTTPError(http.StatusBadRequest, err.Error()).SetInternal(err)
+}
+
+// typeAllowed reports whether mediaType, which may have parameters like
+// "text/plain; charset=utf-8", matches one of allowed.
+func typeAllowed(allowed []string, mediaType string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	if i := strings.IndexByte(mediaType, ';'); i != -1 {
+		mediaType = mediaType[:i]
+	}
+	mediaType = strings.TrimSpace(mediaType)
+	for _, a := range allowed {
+		a = strings.ToLower(a)
+		if a == mediaType || a == "*/*" ||
+			strings.HasSuffix(a, "/*") && strings.HasPrefix(mediaType, a[:len(a)-1]) {
+			return true
+		}
+	}
+	return false
+}
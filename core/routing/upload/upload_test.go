@@ -0,0 +1,204 @@
+package upload
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"goplugins/core/routing"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var pngHeader = []byte("\x89PNG\r\n\x1a\n")
+
+type part struct {
+	field, filename string
+	content         []byte
+}
+
+func multipartRequest(parts ...part) *http.Request {
+	body := new(bytes.Buffer)
+	mw := multipart.NewWriter(body)
+	for _, p := range parts {
+		if p.filename == "" {
+			mw.WriteField(p.field, string(p.content))
+			continue
+		}
+		w, _ := mw.CreateFormFile(p.field, p.filename)
+		w.Write(p.content)
+	}
+	mw.Close()
+	req := httptest.NewRequest(http.MethodPost, "/", body)
+	req.Header.Set(routing.HeaderContentType, mw.FormDataContentType())
+	return req
+}
+
+func parse(u *Uploader, req *http.Request) (*Form, error) {
+	return u.Parse(routing.New().NewContext(req, httptest.NewRecorder()))
+}
+
+func TestUploaderParse(t *testing.T) {
+	assert := assert.New(t)
+	s := NewMemoryStorage()
+	u := New(Config{Storage: s})
+
+	image := append(pngHeader, bytes.Repeat([]byte{0}, 1000)...)
+	form, err := parse(u, multipartRequest(
+		part{field: "title", content: []byte("Holiday")},
+		part{field: "photos", filename: "Beach.PNG", content: image},
+		part{field: "photos", filename: `C:\Users\jon\notes.txt`, content: []byte("hello")},
+	))
+	if !assert.NoError(err) {
+		return
+	}
+	assert.Equal("Holiday", form.Values.Get("title"))
+	if assert.Len(form.Files["photos"], 2) {
+		f := form.File("photos")
+		sum := sha256.Sum256(image)
+		assert.Equal("photos", f.Field)
+		assert.Equal("Beach.PNG", f.Filename)
+		assert.Equal(int64(len(image)), f.Size)
+		assert.Equal(hex.EncodeToString(sum[:]), f.SHA256)
+		assert.Equal("image/png", f.ContentType)
+		assert.True(strings.HasSuffix(f.Key, ".png"))
+
+		r, err := s.Open(f.Key)
+		if assert.NoError(err) {
+			b, _ := ioutil.ReadAll(r)
+			assert.Equal(image, b)
+		}
+
+		f = form.Files["photos"][1]
+		assert.Equal("notes.txt", f.Filename)
+		assert.Equal("text/plain; charset=utf-8", f.ContentType)
+	}
+	assert.Nil(form.File("missing"))
+
+	assert.NoError(u.Discard(form))
+	assert.Equal(0, s.Len())
+}
+
+func TestUploaderLimits(t *testing.T) {
+	image := append(pngHeader, bytes.Repeat([]byte{0}, 100)...)
+	tests := []struct {
+		name   string
+		config Config
+		parts  []part
+		code   int
+	}{
+		{
+			name:   "file size",
+			config: Config{MaxFileSize: 50},
+			parts:  []part{{field: "a", filename: "a.png", content: image}},
+			code:   http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:   "field file size",
+			config: Config{Fields: map[string]FieldConfig{"a": {MaxFileSize: 1000}}, MaxFileSize: 10},
+			parts:  []part{{field: "a", filename: "a.png", content: image}, {field: "b", filename: "b.png", content: image}},
+			code:   http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:   "file count",
+			config: Config{Fields: map[string]FieldConfig{"a": {MaxFiles: 1}}},
+			parts:  []part{{field: "a", filename: "a.png", content: image}, {field: "a", filename: "b.png", content: image}},
+			code:   http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:   "total file count",
+			config: Config{MaxTotalFiles: 2},
+			parts:  []part{{field: "a", filename: "a.png", content: image}, {field: "b", filename: "b.png", content: image}, {field: "c", filename: "c.png", content: image}},
+			code:   http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:   "total size",
+			config: Config{MaxTotalSize: int64(2*len(image) - 1)},
+			parts:  []part{{field: "a", filename: "a.png", content: image}, {field: "b", filename: "b.png", content: image}},
+			code:   http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:   "type",
+			config: Config{AllowedTypes: []string{"image/*"}},
+			parts:  []part{{field: "a", filename: "a.png", content: image}, {field: "b", filename: "b.png", content: []byte("text")}},
+			code:   http.StatusUnsupportedMediaType,
+		},
+		{
+			name:   "field type",
+			config: Config{Fields: map[string]FieldConfig{"a": {AllowedTypes: []string{"application/pdf"}}}},
+			parts:  []part{{field: "a", filename: "a.png", content: image}},
+			code:   http.StatusUnsupportedMediaType,
+		},
+		{
+			name:   "value size",
+			config: Config{MaxValueSize: 3},
+			parts:  []part{{field: "a", filename: "a.png", content: image}, {field: "v", content: []byte("long")}},
+			code:   http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:   "value count",
+			config: Config{MaxValues: 1},
+			parts:  []part{{field: "v", content: []byte("1")}, {field: "v", content: []byte("2")}},
+			code:   http.StatusRequestEntityTooLarge,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewMemoryStorage()
+			tt.config.Storage = s
+			_, err := parse(New(tt.config), multipartRequest(tt.parts...))
+			if assert.IsType(t, new(routing.HTTPError), err) {
+				assert.Equal(t, tt.code, err.(*routing.HTTPError).Code)
+			}
+			// Files saved before are deleted.
+			assert.Equal(t, 0, s.Len())
+		})
+	}
+
+	// Files of exactly the maximum size are accepted.
+	form, err := parse(New(Config{Storage: NewMemoryStorage(), MaxFileSize: int64(len(image))}),
+		multipartRequest(part{field: "a", filename: "a.png", content: image}))
+	if assert.NoError(t, err) {
+		assert.Equal(t, int64(len(image)), form.File("a").Size)
+	}
+	form, err = parse(New(Config{Storage: NewMemoryStorage(), MaxTotalFiles: 2, MaxTotalSize: int64(2 * len(image))}),
+		multipartRequest(part{field: "a", filename: "a.png", content: image}, part{field: "b", filename: "b.png", content: image}))
+	if assert.NoError(t, err) {
+		assert.Len(t, form.Files, 2)
+	}
+}
+
+func TestUploaderParseErrors(t *testing.T) {
+	u := New(Config{Storage: NewMemoryStorage()})
+
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("{}"))
+	req.Header.Set(routing.HeaderContentType, routing.MIMEApplicationJSON)
+	_, err := parse(u, req)
+	assert.Equal(t, routing.ErrUnsupportedMediaType, err)
+
+	req = httptest.NewRequest(http.MethodPost, "/", strings.NewReader("--x\r\nContent-Disposition: form-data; name=\"a\"; filename=\"a.txt\"\r\n\r\nabc"))
+	req.Header.Set(routing.HeaderContentType, "multipart/form-data; boundary=x")
+	_, err = parse(u, req)
+	if assert.IsType(t, new(routing.HTTPError), err) {
+		assert.Equal(t, http.StatusBadRequest, err.(*routing.HTTPError).Code)
+	}
+
+	assert.Panics(t, func() {
+		New(Config{})
+	})
+}
+
+func TestTypeAllowed(t *testing.T) {
+	assert.True(t, typeAllowed(nil, "image/png"))
+	assert.True(t, typeAllowed([]string{"image/png"}, "image/png"))
+	assert.True(t, typeAllowed([]string{"Image/*"}, "image/gif"))
+	assert.True(t, typeAllowed([]string{"text/plain"}, "text/plain; charset=utf-8"))
+	assert.True(t, typeAllowed([]string{"*/*"}, "application/pdf"))
+	assert.False(t, typeAllowed([]string{"image/*"}, "application/pdf"))
+	assert.False(t, typeAllowed([]string{"image/png"}, "image/pngx"))
+}
@@ -0,0 +1,439 @@
+package websocket
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// MessageType is the type of a data message.
+type MessageType int
+
+// Message types.
+const (
+	TextMessage   MessageType = 1
+	BinaryMessage MessageType = 2
+)
+
+// Close codes, see RFC 6455 section 7.4.1.
+const (
+	CloseNormalClosure           = 1000
+	CloseGoingAway               = 1001
+	CloseProtocolError           = 1002
+	CloseUnsupportedData         = 1003
+	CloseNoStatusReceived        = 1005
+	CloseInvalidFramePayloadData = 1007
+	ClosePolicyViolation         = 1008
+	CloseMessageTooBig           = 1009
+	CloseInternalServerErr       = 1011
+)
+
+// Opcodes and bits of frame headers.
+const (
+	opContinuation = 0x0
+	opText         = 0x1
+	opBinary       = 0x2
+	opClose        = 0x8
+	opPing         = 0x9
+	opPong         = 0xa
+
+	finBit  = 0x80
+	rsvBits = 0x70
+	maskBit = 0x80
+
+	maxControlPayload = 125
+)
+
+type (
+	// Conn is a WebSocket connection. Messages are read by one goroutine
+	// at a time, writes may be called concurrently. ReadMessage has to be
+	// called continuously, as control frames like pongs and close frames are
+	// processed while reading.
+	Conn struct {
+		conn           net.Conn
+		br             *bufio.Reader
+		subprotocol    string
+		maxMessageSize int64
+		readTimeout    time.Duration
+		writeTimeout   time.Duration
+
+		// wmu guards writes and closeSent.
+		wmu       sync.Mutex
+		closeSent bool
+
+		closeOnce sync.Once
+		closed    chan struct{}
+
+		mu       sync.Mutex
+		onCloses []func()
+	}
+
+	// CloseError is returned once the connection is closed by a close frame,
+	// either received from the peer or sent because of a protocol error.
+	CloseError struct {
+		Code int
+		Text string
+	}
+)
+
+// ErrClosed is returned for writes to closed connections.
+var ErrClosed = errors.New("websocket: connection closed")
+
+func newConn(nc net.Conn, br *bufio.Reader, subprotocol string, config Config) *Conn {
+	return &Conn{
+		conn:           nc,
+		br:             br,
+		subprotocol:    subprotocol,
+		maxMessageSize: config.MaxMessageSize,
+		readTimeout:    config.ReadTimeout,
+		writeTimeout:   config.WriteTimeout,
+		closed:         make(chan struct{}),
+	}
+}
+
+// Error implements the error interface.
+func (e *CloseError) Error() string {
+	if e.Text == "" {
+		return fmt.Sprintf("websocket: close %d", e.Code)
+	}
+	return fmt.Sprintf("websocket: close %d: %s", e.Code, e.Text)
+}
+
+// IsClosed reports whether err results from a closed connection, i.e. it is
+// a CloseError, ErrClosed, or the connection was closed without close frame
+// or failed, e.g. timed out.
+func IsClosed(err error) bool {
+	var (
+		ce *CloseError
+		ne net.Error
+	)
+	return errors.As(err, &ce) || errors.As(err, &ne) || errors.Is(err, ErrClosed) ||
+		errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// Subprotocol returns the negotiated subprotocol, or "".
+func (c *Conn) Subprotocol() string {
+	return c.subprotocol
+}
+
+// RemoteAddr returns the network address of the peer.
+func (c *Conn) RemoteAddr() net.Addr {
+	return c.conn.RemoteAddr()
+}
+
+// Closed returns a channel which is closed once the connection is closed.
+func (c *Conn) Closed() <-chan struct{} {
+	return c.closed
+}
+
+// ReadMessage returns the next data message. Fragmented messages are
+// reassembled, pings are answered. Errors close the connection.
+func (c *Conn) ReadMessage() (MessageType, []byte, error) {
+	var (
+		typ     MessageType
+		msg     []byte
+		started bool
+	)
+	for {
+		fin, opcode, payload, err := c.readFrame(int64(len(msg)))
+		if err != nil {
+			return 0, nil, c.fail(err)
+		}
+
+		switch opcode {
+		case opPing:
+			if err := c.writeFrame(opPong, payload); err != nil {
+				return 0, nil, c.fail(err)
+			}
+			continue
+		case opPong:
+			continue
+		case opClose:
+			return 0, nil, c.fail(parseClose(payload))
+		case opContinuation:
+			if !started {
+				return 0, nil, c.fail(&CloseError{Code: CloseProtocolError, Text: "unexpected continuation frame"})
+			}
+		default:
+			if started {
+				return 0, nil, c.fail(&CloseError{Code: CloseProtocolError, Text: "expected continuation frame"})
+			}
+			typ, started = MessageType(opcode), true
+		}
+
+		msg = append(msg, payload...)
+		if !fin {
+			continue
+		}
+		if typ == TextMessage && !utf8.Valid(msg) {
+			return 0, nil, c.fail(&CloseError{Code: CloseInvalidFramePayloadData, Text: "invalid utf-8"})
+		}
+		if msg == nil {
+			msg = []byte{}
+		}
+		return typ, msg, nil
+	}
+}
+
+// ReadJSON reads the next message and decodes it as JSON into v.
+func (c *Conn) ReadJSON(v interface{}) error {
+	_, msg, err := c.ReadMessage()
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(msg, v)
+}
+
+// WriteMessage sends a data message.
+func (c *Conn) WriteMessage(typ MessageType, data []byte) error {
+	if typ != TextMessage && typ != BinaryMessage {
+		return fmt.Errorf("websocket: invalid message type %d", typ)
+	}
+	return c.writeFrame(byte(typ), data)
+}
+
+// WriteJSON sends v encoded as JSON in a text message.
+func (c *Conn) WriteJSON(v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return c.WriteMessage(TextMessage, data)
+}
+
+// Ping sends a ping, the pong is processed by ReadMessage.
+func (c *Conn) Ping() error {
+	return c.writeFrame(opPing, nil)
+}
+
+// Close sends a normal close frame and closes the connection.
+func (c *Conn) Close() error {
+	return c.CloseWithReason(CloseNormalClosure, "")
+}
+
+// CloseWithReason sends a close frame with code and reason and closes the
+// connection.
+func (c *Conn) CloseWithReason(code int, reason string) error {
+	c.writeClose(code, reason)
+	return c.close()
+}
+
+// onClose registers f to run once the connection is closed.
+func (c *Conn) onClose(f func()) {
+	c.mu.Lock()
+	select {
+	case <-c.closed:
+		c.mu.Unlock()
+		f()
+		return
+	default:
+	}
+	c.onCloses = append(c.onCloses, f)
+	c.mu.Unlock()
+}
+
+func (c *Conn) close() error {
+	err := ErrClosed
+	c.closeOnce.Do(func() {
+		err = c.conn.Close()
+		c.mu.Lock()
+		close(c.closed)
+		fs := c.onCloses
+		c.onCloses = nil
+		c.mu.Unlock()
+		for _, f := range fs {
+			f()
+		}
+	})
+	return err
+}
+
+// fail closes the connection because of err. Close errors are answered with
+// a close frame with their code.
+func (c *Conn) fail(err error) error {
+	if ce, ok := err.(*CloseError); ok {
+		code := ce.Code
+		if code == CloseNoStatusReceived {
+			code = CloseNormalClosure
+		}
+		c.writeClose(code, "")
+	}
+	c.close()
+	return err
+}
+
+// keepAlive sends pings until the connection is closed.
+func (c *Conn) keepAlive(interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-c.closed:
+			return
+		case <-t.C:
+			if err := c.Ping(); err != nil {
+				c.close()
+				return
+			}
+		}
+	}
+}
+
+// readFrame reads the next frame, read is the size of the message read
+// before.
+func (c *Conn) readFrame(read int64) (fin bool, opcode byte, payload []byte, err error) {
+	if c.readTimeout > 0 {
+		c.conn.SetReadDeadline(time.Now().Add(c.readTimeout))
+	}
+	var b [8]byte
+	if _, err = io.ReadFull(c.br, b[:2]); err != nil {
+		return
+	}
+	fin, opcode = b[0]&finBit != 0, b[0]&0x0f
+	if b[0]&rsvBits != 0 {
+		return fin, opcode, nil, &CloseError{Code: CloseProtocolError, Text: "reserved bits set"}
+	}
+	// Frames sent by clients have to be masked.
+	if b[1]&maskBit == 0 {
+		return fin, opcode, nil, &CloseError{Code: CloseProtocolError, Text: "unmasked frame"}
+	}
+
+	length := int64(b[1] &^ maskBit)
+	switch length {
+	case 126:
+		if _, err = io.ReadFull(c.br, b[:2]); err != nil {
+			return
+		}
+		length = int64(binary.BigEndian.Uint16(b[:2]))
+	case 127:
+		if _, err = io.ReadFull(c.br, b[:8]); err != nil {
+			return
+		}
+		v := binary.BigEndian.Uint64(b[:8])
+		if v>>63 != 0 {
+			return fin, opcode, nil, &CloseError{Code: CloseProtocolError, Text: "invalid length"}
+		}
+		length = int64(v)
+	}
+
+	switch opcode {
+	case opClose, opPing, opPong:
+		if !fin || length > maxControlPayload {
+			return fin, opcode, nil, &CloseError{Code: CloseProtocolError, Text: "invalid control frame"}
+		}
+	case opContinuation, opText, opBinary:
+		if c.maxMessageSize > 0 && read+length > c.maxMessageSize {
+			return fin, opcode, nil, &CloseError{Code: CloseMessageTooBig, Text: "message too big"}
+		}
+	default:
+		return fin, opcode, nil, &CloseError{Code: CloseProtocolError, Text: "unknown opcode"}
+	}
+
+	var mask [4]byte
+	if _, err = io.ReadFull(c.br, mask[:]); err != nil {
+		return
+	}
+	payload = make([]byte, length)
+	if _, err = io.ReadFull(c.br, payload); err != nil {
+		return
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return fin, opcode, payload, nil
+}
+
+// parseClose returns the CloseError of the payload of a close frame.
+func parseClose(payload []byte) error {
+	switch {
+	case len(payload) == 0:
+		return &CloseError{Code: CloseNoStatusReceived}
+	case len(payload) == 1:
+		return &CloseError{Code: CloseProtocolError, Text: "invalid close payload"}
+	}
+	code := int(binary.BigEndian.Uint16(payload))
+	if !validCloseCode(code) {
+		return &CloseError{Code: CloseProtocolError, Text: "invalid close code"}
+	}
+	if !utf8.Valid(payload[2:]) {
+		return &CloseError{Code: CloseInvalidFramePayloadData, Text: "invalid utf-8"}
+	}
+	return &CloseError{Code: code, Text: string(payload[2:])}
+}
+
+// validCloseCode reports whether code may be sent in a close frame.
+func validCloseCode(code int) bool {
+	switch {
+	case code >= 1000 && code <= 1003, code >= 1007 && code <= 1011:
+		return true
+	case code >= 3000 && code <= 4999:
+		return true
+	}
+	return false
+}
+
+func (c *Conn) writeClose(code int, reason string) {
+	payload := make([]byte, 2, 2+len(reason))
+	binary.BigEndian.PutUint16(payload, uint16(code))
+	payload = append(payload, reason...)
+	if len(payload) > maxControlPayload {
+		payload = payload[:maxControlPayload]
+	}
+
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	if c.closeSent {
+		return
+	}
+	c.closeSent = true
+	c.write(frameHeader(opClose, len(payload)), payload)
+}
+
+func (c *Conn) writeFrame(opcode byte, payload []byte) error {
+	return c.writeRaw(frameHeader(opcode, len(payload)), payload)
+}
+
+// writeRaw writes a frame, e.g. prepared once for all connections of a
+// broadcast.
+func (c *Conn) writeRaw(header, payload []byte) error {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	if c.closeSent {
+		return ErrClosed
+	}
+	return c.write(header, payload)
+}
+
+func (c *Conn) write(header, payload []byte) error {
+	if c.writeTimeout > 0 {
+		c.conn.SetWriteDeadline(time.Now().Add(c.writeTimeout))
+	}
+	bufs := net.Buffers{header, payload}
+	_, err := bufs.WriteTo(c.conn)
+	return err
+}
+
+// frameHeader returns the header of an unmasked final frame, as sent by
+// servers.
+func frameHeader(opcode byte, length int) []byte {
+	switch {
+	case length <= maxControlPayload:
+		return []byte{finBit | opcode, byte(length)}
+	case length <= 0xffff:
+		h := []byte{finBit | opcode, 126, 0, 0}
+		binary.BigEndian.PutUint16(h[2:], uint16(length))
+		return h
+	default:
+		h := make([]byte, 10)
+		h[0], h[1] = finBit|opcode, 127
+		binary.BigEndian.PutUint64(h[2:], uint64(length))
+		return h
+	}
+}
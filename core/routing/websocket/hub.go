@@ -0,0 +1,164 @@
+package websocket
+
+import (
+	"encoding/json"
+	"sync"
+)
+
+type (
+	// Hub keeps connections in groups, e.g. all sessions of a user or all
+	// viewers of a document, to broadcast messages to them. Connections
+	// leave their groups once they are closed. It is safe for concurrent
+	// use.
+	Hub struct {
+		mu     sync.RWMutex
+		groups map[string]map[*Conn]struct{}
+		conns  map[*Conn]*hubClient
+	}
+
+	// hubClient queues the broadcasts to a connection, so slow connections
+	// don't hold up the others.
+	hubClient struct {
+		groups map[string]struct{}
+		queue  chan hubMessage
+		done   chan struct{}
+	}
+
+	hubMessage struct {
+		header, data []byte
+	}
+)
+
+// HubQueueSize is the number of broadcast messages queued per connection.
+// Connections falling further behind are closed.
+var HubQueueSize = 64
+
+// NewHub returns an empty hub.
+func NewHub() *Hub {
+	return &Hub{
+		groups: map[string]map[*Conn]struct{}{},
+		conns:  map[*Conn]*hubClient{},
+	}
+}
+
+// Join adds conn to group.
+func (h *Hub) Join(group string, conn *Conn) {
+	h.mu.Lock()
+	if h.groups[group] == nil {
+		h.groups[group] = map[*Conn]struct{}{}
+	}
+	h.groups[group][conn] = struct{}{}
+	client, known := h.conns[conn]
+	if !known {
+		client = &hubClient{
+			groups: map[string]struct{}{},
+			queue:  make(chan hubMessage, HubQueueSize),
+			done:   make(chan struct{}),
+		}
+		h.conns[conn] = client
+	}
+	client.groups[group] = struct{}{}
+	h.mu.Unlock()
+
+	if !known {
+		go client.send(conn)
+		conn.onClose(func() {
+			h.Remove(conn)
+		})
+	}
+}
+
+// Leave removes conn from group.
+func (h *Hub) Leave(group string, conn *Conn) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.leave(group, conn)
+	if client, ok := h.conns[conn]; ok {
+		delete(client.groups, group)
+	}
+}
+
+// Remove removes conn from all groups.
+func (h *Hub) Remove(conn *Conn) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	client, ok := h.conns[conn]
+	if !ok {
+		return
+	}
+	for group := range client.groups {
+		h.leave(group, conn)
+	}
+	delete(h.conns, conn)
+	close(client.done)
+}
+
+func (h *Hub) leave(group string, conn *Conn) {
+	conns := h.groups[group]
+	delete(conns, conn)
+	if len(conns) == 0 {
+		delete(h.groups, group)
+	}
+}
+
+// Count returns the number of connections in group.
+func (h *Hub) Count(group string) int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.groups[group])
+}
+
+// Broadcast queues a message for all connections of group and returns the
+// number of connections it was queued for. It doesn't wait for the messages
+// to be written; connections failing to receive them or having more than
+// HubQueueSize messages queued are closed. The frame is encoded once for all
+// connections, data must not be modified afterwards.
+func (h *Hub) Broadcast(group string, typ MessageType, data []byte) int {
+	m := hubMessage{header: frameHeader(byte(typ), len(data)), data: data}
+	var slow []*Conn
+	sent := 0
+	h.mu.RLock()
+	for conn := range h.groups[group] {
+		select {
+		case h.conns[conn].queue <- m:
+			sent++
+		default:
+			slow = append(slow, conn)
+		}
+	}
+	h.mu.RUnlock()
+
+	// Closing removes the connections from the hub, which requires the lock.
+	for _, conn := range slow {
+		conn.close()
+	}
+	return sent
+}
+
+// BroadcastJSON sends v encoded as JSON in a text message to all
+// connections of group.
+func (h *Hub) BroadcastJSON(group string, v interface{}) (int, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return 0, err
+	}
+	return h.Broadcast(group, TextMessage, data), nil
+}
+
+// send writes the queued messages to conn until it is closed or removed from
+// the hub.
+func (c *hubClient) send(conn *Conn) {
+	for {
+		select {
+		case m := <-c.queue:
+			if err := conn.writeRaw(m.header, m.data); err != nil {
+				conn.close()
+				return
+			}
+		case <-c.done:
+			return
+		case <-conn.closed:
+			return
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package websocket
+
+import (
+	"bytes"
+	"goplugins/core/routing"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHub(t *testing.T) {
+	hub := NewHub()
+	joined := make(chan *Conn, 3)
+	m := routing.New()
+	m.GET("/ws", New(Config{}).Handler(func(c routing.Context, conn *Conn) error {
+		hub.Join(c.QueryParam("user"), conn)
+		hub.Join("all", conn)
+		joined <- conn
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return err
+			}
+		}
+	}))
+	s := httptest.NewServer(m)
+	defer s.Close()
+
+	var clients []*client
+	for _, user := range []string{"jon", "jon", "arya"} {
+		c := dial(t, s.URL, "/ws?user="+user, nil)
+		c.res.Body.Close()
+		defer c.conn.Close()
+		clients = append(clients, c)
+		<-joined
+	}
+	assert.Equal(t, 2, hub.Count("jon"))
+	assert.Equal(t, 3, hub.Count("all"))
+
+	n, err := hub.BroadcastJSON("jon", map[string]int{"unread": 3})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+	for _, c := range clients[:2] {
+		op, payload := c.readFrame(t)
+		assert.Equal(t, byte(opText), op)
+		assert.JSONEq(t, `{"unread":3}`, string(payload))
+	}
+
+	assert.Equal(t, 3, hub.Broadcast("all", BinaryMessage, []byte{1}))
+	for _, c := range clients {
+		op, payload := c.readFrame(t)
+		assert.Equal(t, byte(opBinary), op)
+		assert.Equal(t, []byte{1}, payload)
+	}
+
+	// Closed connections leave their groups.
+	clients[0].writeFrame(true, opClose, closePayload(CloseNormalClosure, ""))
+	clients[0].expectClose(t, CloseNormalClosure)
+	assert.Eventually(t, func() bool {
+		return hub.Count("jon") == 1 && hub.Count("all") == 2
+	}, time.Second, 5*time.Millisecond)
+
+	assert.Equal(t, 0, hub.Broadcast("nobody", TextMessage, []byte("x")))
+}
+
+func TestHubSlowConnection(t *testing.T) {
+	defer func(size int) { HubQueueSize = size }(HubQueueSize)
+	HubQueueSize = 4
+
+	hub := NewHub()
+	joined := make(chan *Conn, 2)
+	m := routing.New()
+	m.GET("/ws", New(Config{WriteTimeout: time.Minute}).Handler(func(c routing.Context, conn *Conn) error {
+		hub.Join("all", conn)
+		joined <- conn
+		<-conn.Closed()
+		return nil
+	}))
+	s := httptest.NewServer(m)
+	defer s.Close()
+
+	// The first client never reads, the second one reads every message.
+	slow := dial(t, s.URL, "/ws", nil)
+	defer slow.conn.Close()
+	<-joined
+	fast := dial(t, s.URL, "/ws", nil)
+	defer fast.conn.Close()
+	<-joined
+
+	msg := bytes.Repeat([]byte{1}, 1<<20)
+	for i := 0; hub.Count("all") == 2; i++ {
+		if i == 100 {
+			t.Fatal("slow connection was not closed")
+		}
+		start := time.Now()
+		hub.Broadcast("all", BinaryMessage, msg)
+		assert.True(t, time.Since(start) < time.Second, "broadcast blocked")
+		_, payload := fast.readFrame(t)
+		assert.Equal(t, len(msg), len(payload))
+	}
+	assert.Equal(t, 1, hub.Count("all"))
+	assert.Equal(t, 1, hub.Broadcast("all", TextMessage, []byte("still there")))
+	_, payload := fast.readFrame(t)
+	assert.Equal(t, "still there", string(payload))
+}
+
+func TestHubLeave(t *testing.T) {
+	hub := NewHub()
+	conn := &Conn{closed: make(chan struct{})}
+	hub.Join("a", conn)
+	hub.Join("b", conn)
+	hub.Leave("a", conn)
+	assert.Equal(t, 0, hub.Count("a"))
+	assert.Equal(t, 1, hub.Count("b"))
+	hub.Remove(conn)
+	assert.Equal(t, 0, hub.Count("b"))
+	assert.Empty(t, hub.groups)
+	assert.Empty(t, hub.conns)
+}
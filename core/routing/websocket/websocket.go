@@ -0,0 +1,263 @@
+// Package websocket implements the WebSocket protocol (RFC 6455) for handlers
+// of a routing.Mux. Requests are upgraded by an Upgrader, which checks the
+// origin and limits the size of messages; connections are kept alive with
+// pings and can be grouped in a Hub to broadcast messages, e.g. to all
+// sessions of a user:
+//
+//	hub := websocket.NewHub()
+//	upgrader := websocket.New(websocket.Config{})
+//	mux.GET("/live", upgrader.Handler(func(c routing.Context, conn *websocket.Conn) error {
+//		hub.Join(userID(c), conn)
+//		for {
+//			if _, _, err := conn.ReadMessage(); err != nil {
+//				return err
+//			}
+//		}
+//	}))
+//	hub.BroadcastJSON(userID, event)
+package websocket
+
+import (
+	"crypto/sha1"
+	"encoding/base64"
+	"errors"
+	"goplugins/core/routing"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// acceptGUID is appended to the key of the client to compute the accept key.
+const acceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// Header names of the handshake.
+const (
+	HeaderSecWebSocketKey      = "Sec-WebSocket-Key"
+	HeaderSecWebSocketAccept   = "Sec-WebSocket-Accept"
+	HeaderSecWebSocketVersion  = "Sec-WebSocket-Version"
+	HeaderSecWebSocketProtocol = "Sec-WebSocket-Protocol"
+)
+
+type (
+	// Config defines the config of an Upgrader.
+	Config struct {
+		// AllowOrigins are the origins allowed to connect in addition to the
+		// host of the request, e.g. "https://app.example.com", "*" allows all
+		// origins. Requests without Origin header, i.e. not sent by browsers,
+		// are always allowed.
+		// Optional.
+		AllowOrigins []string
+
+		// CheckOrigin replaces the origin check.
+		// Optional.
+		CheckOrigin func(c routing.Context) bool
+
+		// Subprotocols are the supported subprotocols in order of
+		// preference.
+		// Optional.
+		Subprotocols []string
+
+		// MaxMessageSize is the maximum size of a received message in bytes,
+		// larger messages close the connection with CloseMessageTooBig.
+		// Optional. Default value 1MB.
+		MaxMessageSize int64
+
+		// PingInterval is the interval of pings sent to keep the connection
+		// alive, a negative value disables pings.
+		// Optional. Default value 30s.
+		PingInterval time.Duration
+
+		// ReadTimeout closes connections which didn't send a frame, e.g. a
+		// pong, for the duration. Should be longer than the PingInterval, a
+		// negative value disables the timeout.
+		// Optional. Default value 60s.
+		ReadTimeout time.Duration
+
+		// WriteTimeout is the maximum duration of writing a message.
+		// Optional. Default value 10s.
+		WriteTimeout time.Duration
+	}
+
+	// Upgrader upgrades HTTP requests to WebSocket connections.
+	Upgrader struct {
+		config Config
+	}
+)
+
+var (
+	// DefaultConfig is the default Upgrader config.
+	DefaultConfig = Config{
+		MaxMessageSize: 1 << 20,
+		PingInterval:   30 * time.Second,
+		ReadTimeout:    60 * time.Second,
+		WriteTimeout:   10 * time.Second,
+	}
+
+	// ErrBadHandshake is returned for requests which aren't WebSocket
+	// handshakes.
+	ErrBadHandshake = routing.NewHTTPError(http.StatusBadRequest, "websocket: bad handshake")
+
+	// ErrOriginNotAllowed is returned for requests from origins which are
+	// not allowed.
+	ErrOriginNotAllowed = routing.NewHTTPError(http.StatusForbidden, "websocket: origin not allowed")
+
+	// ErrHijackNotSupported is returned if the connection can't be taken
+	// over, e.g. because the response is buffered by middleware.
+	ErrHijackNotSupported = errors.New("websocket: response does not support hijacking")
+)
+
+// New returns an Upgrader with config, values which aren't set use the
+// DefaultConfig.
+func New(config Config) *Upgrader {
+	if config.MaxMessageSize == 0 {
+		config.MaxMessageSize = DefaultConfig.MaxMessageSize
+	}
+	if config.PingInterval == 0 {
+		config.PingInterval = DefaultConfig.PingInterval
+	}
+	if config.ReadTimeout == 0 {
+		config.ReadTimeout = DefaultConfig.ReadTimeout
+	}
+	if config.WriteTimeout == 0 {
+		config.WriteTimeout = DefaultConfig.WriteTimeout
+	}
+	return &Upgrader{config: config}
+}
+
+// Upgrade completes the handshake and takes over the connection of the
+// request. Errors are returned before anything is written, so they can be
+// handled like other errors of handlers; invalid handshakes are rejected with
+// 400, unsupported versions with 426 and origins which aren't allowed with
+// 403.
+func (u *Upgrader) Upgrade(c routing.Context) (*Conn, error) {
+	req := c.Request()
+	if req.Method != http.MethodGet ||
+		!headerContains(req.Header, routing.HeaderConnection, "upgrade") ||
+		!headerContains(req.Header, routing.HeaderUpgrade, "websocket") {
+		return nil, ErrBadHandshake
+	}
+	if req.Header.Get(HeaderSecWebSocketVersion) != "13" {
+		c.Response().Header().Set(HeaderSecWebSocketVersion, "13")
+		return nil, routing.NewHTTPError(http.StatusUpgradeRequired, "websocket: unsupported version")
+	}
+	key := req.Header.Get(HeaderSecWebSocketKey)
+	if k, err := base64.StdEncoding.DecodeString(key); err != nil || len(k) != 16 {
+		return nil, ErrBadHandshake
+	}
+	if !u.checkOrigin(c) {
+		return nil, ErrOriginNotAllowed
+	}
+
+	hj, ok := c.Response().Writer.(http.Hijacker)
+	if !ok {
+		return nil, routing.NewHTTPError(http.StatusInternalServerError).SetInternal(ErrHijackNotSupported)
+	}
+	nc, brw, err := hj.Hijack()
+	if err != nil {
+		return nil, routing.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
+	}
+
+	protocol := u.subprotocol(req)
+	var b strings.Builder
+	b.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n")
+	b.WriteString(HeaderSecWebSocketAccept + ": " + acceptKey(key) + "\r\n")
+	if protocol != "" {
+		b.WriteString(HeaderSecWebSocketProtocol + ": " + protocol + "\r\n")
+	}
+	b.WriteString("\r\n")
+	nc.SetWriteDeadline(time.Now().Add(u.config.WriteTimeout))
+	if _, err := nc.Write([]byte(b.String())); err != nil {
+		nc.Close()
+		return nil, err
+	}
+	nc.SetWriteDeadline(time.Time{})
+
+	// The connection is taken over, so the response is only updated for the
+	// access log.
+	res := c.Response()
+	res.Status = http.StatusSwitchingProtocols
+	res.Committed = true
+
+	conn := newConn(nc, brw.Reader, protocol, u.config)
+	if u.config.PingInterval > 0 {
+		go conn.keepAlive(u.config.PingInterval)
+	}
+	return conn, nil
+}
+
+// Handler returns a handler upgrading requests and passing the connection
+// to handle. The connection is closed once handle returns; errors other than
+// closed connections are logged and close the connection with
+// CloseInternalServerErr.
+func (u *Upgrader) Handler(handle func(c routing.Context, conn *Conn) error) routing.HandlerFunc {
+	return func(c routing.Context) error {
+		conn, err := u.Upgrade(c)
+		if err != nil {
+			return err
+		}
+		if err := handle(c, conn); err != nil && !IsClosed(err) {
+			c.Logger().Error(err)
+			conn.CloseWithReason(CloseInternalServerErr, "")
+			return nil
+		}
+		conn.Close()
+		return nil
+	}
+}
+
+// checkOrigin reports whether the origin of the request is allowed.
+func (u *Upgrader) checkOrigin(c routing.Context) bool {
+	if u.config.CheckOrigin != nil {
+		return u.config.CheckOrigin(c)
+	}
+	origin := c.Request().Header.Get(routing.HeaderOrigin)
+	if origin == "" {
+		return true
+	}
+	for _, o := range u.config.AllowOrigins {
+		if o == "*" || strings.EqualFold(o, origin) {
+			return true
+		}
+	}
+	o, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(o.Host, c.Request().Host)
+}
+
+// subprotocol returns the first supported subprotocol requested by the
+// client.
+func (u *Upgrader) subprotocol(req *http.Request) string {
+	var requested []string
+	for _, h := range req.Header.Values(HeaderSecWebSocketProtocol) {
+		for _, p := range strings.Split(h, ",") {
+			requested = append(requested, strings.TrimSpace(p))
+		}
+	}
+	for _, s := range u.config.Subprotocols {
+		for _, p := range requested {
+			if p == s {
+				return s
+			}
+		}
+	}
+	return ""
+}
+
+// acceptKey returns the Sec-WebSocket-Accept value for key.
+func acceptKey(key string) string {
+	h := sha1.New()
+	h.Write([]byte(key + acceptGUID))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// headerContains reports whether the comma separated header contains token.
+func headerContains(header http.Header, name, token string) bool {
+	for _, h := range header.Values(name) {
+		for _, t := range strings.Split(h, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,281 @@
+package websocket
+
+import (
+	"bufio"
+	"encoding/binary"
+	"goplugins/core/routing"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testKey = "dGhlIHNhbXBsZSBub25jZQ=="
+
+// client is a minimal WebSocket client sending masked frames.
+type client struct {
+	conn net.Conn
+	br   *bufio.Reader
+	res  *http.Response
+}
+
+func dial(t *testing.T, url, path string, header http.Header) *client {
+	conn, err := net.Dial("tcp", strings.TrimPrefix(url, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, _ := http.NewRequest(http.MethodGet, url+path, nil)
+	req.Header.Set(routing.HeaderConnection, "keep-alive, Upgrade")
+	req.Header.Set(routing.HeaderUpgrade, "websocket")
+	req.Header.Set(HeaderSecWebSocketVersion, "13")
+	req.Header.Set(HeaderSecWebSocketKey, testKey)
+	for k, v := range header {
+		req.Header[http.CanonicalHeaderKey(k)] = v
+	}
+	req.Write(conn)
+	br := bufio.NewReader(conn)
+	res, err := http.ReadResponse(br, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	return &client{conn: conn, br: br, res: res}
+}
+
+func (c *client) writeFrame(fin bool, opcode byte, payload []byte) {
+	b := []byte{opcode, maskBit}
+	if fin {
+		b[0] |= finBit
+	}
+	switch {
+	case len(payload) <= 125:
+		b[1] |= byte(len(payload))
+	case len(payload) <= 0xffff:
+		b[1] |= 126
+		b = append(b, 0, 0)
+		binary.BigEndian.PutUint16(b[2:], uint16(len(payload)))
+	default:
+		b[1] |= 127
+		b = append(b, make([]byte, 8)...)
+		binary.BigEndian.PutUint64(b[2:], uint64(len(payload)))
+	}
+	mask := []byte{1, 2, 3, 4}
+	b = append(b, mask...)
+	for i, p := range payload {
+		b = append(b, p^mask[i%4])
+	}
+	c.conn.Write(b)
+}
+
+func (c *client) readFrame(t *testing.T) (byte, []byte) {
+	var h [2]byte
+	if _, err := io.ReadFull(c.br, h[:]); err != nil {
+		t.Fatal(err)
+	}
+	assert.Zero(t, h[1]&maskBit, "server frames are not masked")
+	length := int(h[1] & 0x7f)
+	switch length {
+	case 126:
+		var l [2]byte
+		io.ReadFull(c.br, l[:])
+		length = int(binary.BigEndian.Uint16(l[:]))
+	case 127:
+		var l [8]byte
+		io.ReadFull(c.br, l[:])
+		length = int(binary.BigEndian.Uint64(l[:]))
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(c.br, payload); err != nil {
+		t.Fatal(err)
+	}
+	return h[0] & 0x0f, payload
+}
+
+func (c *client) expectClose(t *testing.T, code int) {
+	op, payload := c.readFrame(t)
+	if assert.Equal(t, byte(opClose), op) && assert.True(t, len(payload) >= 2) {
+		assert.Equal(t, code, int(binary.BigEndian.Uint16(payload)))
+	}
+}
+
+func closePayload(code int, reason string) []byte {
+	b := make([]byte, 2)
+	binary.BigEndian.PutUint16(b, uint16(code))
+	return append(b, reason...)
+}
+
+// echoServer echoes messages and sends the error ending the connection to
+// errs.
+func echoServer(config Config, errs chan error) *httptest.Server {
+	m := routing.New()
+	m.GET("/ws", New(config).Handler(func(c routing.Context, conn *Conn) error {
+		for {
+			typ, msg, err := conn.ReadMessage()
+			if err != nil {
+				if errs != nil {
+					errs <- err
+				}
+				return err
+			}
+			if err := conn.WriteMessage(typ, msg); err != nil {
+				return err
+			}
+		}
+	}))
+	return httptest.NewServer(m)
+}
+
+func TestUpgrade(t *testing.T) {
+	errs := make(chan error, 1)
+	s := echoServer(Config{Subprotocols: []string{"chat.v2", "chat"}}, errs)
+	defer s.Close()
+
+	c := dial(t, s.URL, "/ws", http.Header{HeaderSecWebSocketProtocol: {"chat, chat.v2"}})
+	defer c.conn.Close()
+	assert.Equal(t, http.StatusSwitchingProtocols, c.res.StatusCode)
+	assert.Equal(t, "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=", c.res.Header.Get(HeaderSecWebSocketAccept))
+	assert.Equal(t, "chat.v2", c.res.Header.Get(HeaderSecWebSocketProtocol))
+
+	// Echo of a text message.
+	c.writeFrame(true, opText, []byte("hello"))
+	op, payload := c.readFrame(t)
+	assert.Equal(t, byte(opText), op)
+	assert.Equal(t, "hello", string(payload))
+
+	// Fragmented binary message with a ping in between.
+	c.writeFrame(false, opBinary, []byte{1, 2})
+	c.writeFrame(true, opPing, []byte("p"))
+	c.writeFrame(true, opContinuation, []byte{3})
+	op, payload = c.readFrame(t)
+	assert.Equal(t, byte(opPong), op)
+	assert.Equal(t, "p", string(payload))
+	op, payload = c.readFrame(t)
+	assert.Equal(t, byte(opBinary), op)
+	assert.Equal(t, []byte{1, 2, 3}, payload)
+
+	// Extended lengths.
+	long := strings.Repeat("x", 70000)
+	c.writeFrame(true, opText, []byte(long))
+	_, payload = c.readFrame(t)
+	assert.Equal(t, long, string(payload))
+
+	// Close handshake.
+	c.writeFrame(true, opClose, closePayload(CloseGoingAway, "bye"))
+	c.expectClose(t, CloseGoingAway)
+	err := <-errs
+	assert.Equal(t, &CloseError{Code: CloseGoingAway, Text: "bye"}, err)
+	assert.True(t, IsClosed(err))
+}
+
+func TestUpgradeHandshakeErrors(t *testing.T) {
+	s := echoServer(Config{AllowOrigins: []string{"https://app.example.com"}}, nil)
+	defer s.Close()
+
+	tests := []struct {
+		name   string
+		header http.Header
+		code   int
+	}{
+		{"no upgrade", http.Header{routing.HeaderUpgrade: {"h2c"}}, http.StatusBadRequest},
+		{"bad key", http.Header{HeaderSecWebSocketKey: {"short"}}, http.StatusBadRequest},
+		{"version", http.Header{HeaderSecWebSocketVersion: {"8"}}, http.StatusUpgradeRequired},
+		{"origin", http.Header{routing.HeaderOrigin: {"https://evil.example.com"}}, http.StatusForbidden},
+		{"allowed origin", http.Header{routing.HeaderOrigin: {"https://app.example.com"}}, http.StatusSwitchingProtocols},
+		{"same origin", http.Header{routing.HeaderOrigin: {s.URL}}, http.StatusSwitchingProtocols},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := dial(t, s.URL, "/ws", tt.header)
+			defer c.conn.Close()
+			assert.Equal(t, tt.code, c.res.StatusCode)
+			if tt.code == http.StatusUpgradeRequired {
+				assert.Equal(t, "13", c.res.Header.Get(HeaderSecWebSocketVersion))
+			}
+		})
+	}
+}
+
+func TestConnProtocolErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		write func(c *client)
+		code  int
+	}{
+		{"unmasked", func(c *client) { c.conn.Write([]byte{finBit | opText, 1, 'a'}) }, CloseProtocolError},
+		{"reserved bits", func(c *client) { c.writeFrame(true, opText|0x40, []byte("a")) }, CloseProtocolError},
+		{"unknown opcode", func(c *client) { c.writeFrame(true, 0x3, nil) }, CloseProtocolError},
+		{"continuation", func(c *client) { c.writeFrame(true, opContinuation, []byte("a")) }, CloseProtocolError},
+		{"interleaved", func(c *client) {
+			c.writeFrame(false, opText, []byte("a"))
+			c.writeFrame(true, opText, []byte("b"))
+		}, CloseProtocolError},
+		{"fragmented control", func(c *client) { c.writeFrame(false, opPing, nil) }, CloseProtocolError},
+		{"invalid utf-8", func(c *client) { c.writeFrame(true, opText, []byte{0xff}) }, CloseInvalidFramePayloadData},
+		{"too big", func(c *client) { c.writeFrame(true, opBinary, make([]byte, 20)) }, CloseMessageTooBig},
+		{"too big fragments", func(c *client) {
+			c.writeFrame(false, opBinary, make([]byte, 10))
+			c.writeFrame(true, opContinuation, make([]byte, 10))
+		}, CloseMessageTooBig},
+		{"invalid close code", func(c *client) { c.writeFrame(true, opClose, closePayload(1005, "")) }, CloseProtocolError},
+		{"empty close", func(c *client) { c.writeFrame(true, opClose, nil) }, CloseNormalClosure},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := make(chan error, 1)
+			s := echoServer(Config{MaxMessageSize: 16}, errs)
+			defer s.Close()
+			c := dial(t, s.URL, "/ws", nil)
+			defer c.conn.Close()
+
+			tt.write(c)
+			c.expectClose(t, tt.code)
+			assert.True(t, IsClosed(<-errs))
+			// The connection is closed after the close frame.
+			_, err := c.br.ReadByte()
+			assert.Equal(t, io.EOF, err)
+		})
+	}
+}
+
+func TestConnKeepAlive(t *testing.T) {
+	errs := make(chan error, 1)
+	s := echoServer(Config{PingInterval: 20 * time.Millisecond, ReadTimeout: 100 * time.Millisecond}, errs)
+	defer s.Close()
+	c := dial(t, s.URL, "/ws", nil)
+	defer c.conn.Close()
+
+	op, _ := c.readFrame(t)
+	assert.Equal(t, byte(opPing), op)
+	c.writeFrame(true, opPong, nil)
+
+	// Without pongs the connection times out.
+	select {
+	case err := <-errs:
+		if ne, ok := err.(net.Error); assert.True(t, ok, "%v", err) {
+			assert.True(t, ne.Timeout())
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("connection did not time out")
+	}
+}
+
+func TestHandlerClosesConnection(t *testing.T) {
+	m := routing.New()
+	m.GET("/ws", New(Config{}).Handler(func(c routing.Context, conn *Conn) error {
+		return conn.WriteJSON(map[string]string{"hello": "world"})
+	}))
+	s := httptest.NewServer(m)
+	defer s.Close()
+
+	c := dial(t, s.URL, "/ws", nil)
+	defer c.conn.Close()
+	op, payload := c.readFrame(t)
+	assert.Equal(t, byte(opText), op)
+	assert.JSONEq(t, `{"hello":"world"}`, string(payload))
+	c.expectClose(t, CloseNormalClosure)
+}
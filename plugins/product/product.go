@@ -3,6 +3,8 @@ package main
 
 import (
 	"fmt"
+	"goplugins/core/framework"
+	"goplugins/core/framework/database"
 	"goplugins/core/routing"
 	"net/http"
 )
@@ -11,9 +13,34 @@ var Plugin UserPlugin
 
 type UserPlugin string
 
+// Product is a demo product
+type Product struct {
+	framework.Model
+	Name  string `json:"name"`
+	SKU   string `json:"sku" gorm:"uniqueIndex"`
+	Price int64  `json:"price"` // in cents
+}
+
+const demoProducts = `
+- model: product
+  fields: {name: Coffee Mug, sku: MUG-001, price: 1299}
+- model: product
+  fields: {name: T-Shirt, sku: TSH-001, price: 1999}
+- model: product
+  fields: {name: Sticker Pack, sku: STK-001, price: 499}
+`
+
 func (p *UserPlugin) Install() {
 	// install database tables blabla
 	fmt.Println("Install Hook")
+	framework.RegisterSeeder("product.demo", func(db *database.DB) error {
+		if err := db.AutoMigrate(&Product{}); err != nil {
+			return err
+		}
+		return framework.NewFixtures(db).
+			Register("product", &Product{}).
+			Load([]byte(demoProducts))
+	})
 }
 func (p *UserPlugin) PostInstall() {
 	fmt.Println("PostInstall Hook")